@@ -0,0 +1,258 @@
+// Command eval runs answer quality evaluations from the terminal, with the same datasets,
+// runs and knowledge base configuration as the /evaluations admin routes.
+//
+//	go run ./cmd/eval datasets
+//	go run ./cmd/eval import -file dataset.json
+//	go run ./cmd/eval run -dataset 1 [-grader llm_judge] [-model id] [-prompt-file prompt.txt] [-results 5]
+//	go run ./cmd/eval runs -dataset 1
+//	go run ./cmd/eval compare -base 1 -candidate 2
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Abraxas-365/opd/internal/evaluation"
+	"github.com/Abraxas-365/opd/internal/evaluation/evaluationinfra"
+	"github.com/Abraxas-365/opd/internal/evaluation/evaluationsrv"
+	"github.com/Abraxas-365/opd/internal/kb/kbasesrv"
+	"github.com/Abraxas-365/opd/internal/kb/kbinfra"
+	"github.com/Abraxas-365/opd/internal/usage/usageinfra"
+	"github.com/Abraxas-365/opd/internal/usage/usagesrv"
+	"github.com/Abraxas-365/opd/pkg/conf"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/jmoiron/sqlx"
+)
+
+const usage = `usage: eval <command> [flags]
+
+commands:
+  datasets   list the datasets
+  import     create a dataset from a JSON file with name, description and cases
+  run        run a dataset and print its results
+  runs       list the runs of a dataset
+  compare    compare a candidate run with a base run`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	db, err := sqlx.Connect("postgres", conf.LoadDatabaseURL())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(),
+		config.WithRegion("us-east-1"),
+	)
+	if err != nil {
+		log.Fatal("unable to load SDK config: " + err.Error())
+	}
+
+	// Budget alerts raised by evaluations are logged and listed, emails are only sent by the server
+	usageConf := conf.LoadUsageConf()
+	usageService := usagesrv.New(usageinfra.NewUsageStore(db), usageConf.UsageMonthlyBudget, usageConf.UsageBudgetAlertPercents, usageConf.UsageBudgetAlertEmails, conf.LoadTimeZone())
+	evaluator := kbsrv.NewEvaluator(bedrockagentruntime.NewFromConfig(cfg), bedrockruntime.NewFromConfig(cfg), kbinfra.NewStore(db), usageService)
+	service := evaluationsrv.New(evaluationinfra.NewEvaluationStore(db), evaluator, evaluator)
+
+	ctx := context.Background()
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "datasets":
+		err = listDatasets(ctx, service)
+	case "import":
+		err = importDataset(ctx, service, args)
+	case "run":
+		err = run(ctx, service, args)
+	case "runs":
+		err = listRuns(ctx, service, args)
+	case "compare":
+		err = compare(ctx, service, args)
+	default:
+		log.Fatal(usage)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func listDatasets(ctx context.Context, service *evaluationsrv.Service) error {
+	datasets, err := service.GetDatasets(ctx, 1, 1000)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tDESCRIPTION")
+	for _, d := range datasets.Data {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", d.ID, d.Name, d.Description)
+	}
+	return w.Flush()
+}
+
+func importDataset(ctx context.Context, service *evaluationsrv.Service, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	file := flags.String("file", "", "JSON file with name, description and cases")
+	flags.Parse(args)
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+
+	raw, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	var d evaluation.Dataset
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return fmt.Errorf("invalid dataset file: %v", err)
+	}
+
+	created, err := service.CreateDataset(ctx, d)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created dataset %d with %d cases\n", created.ID, len(created.Cases))
+	return nil
+}
+
+func run(ctx context.Context, service *evaluationsrv.Service, args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	datasetID := flags.Int("dataset", 0, "dataset id")
+	grader := flags.String("grader", evaluation.GraderSimilarity, "similarity or llm_judge")
+	model := flags.String("model", "", "model id, defaults to KB_MODEL_ID")
+	promptFile := flags.String("prompt-file", "", "file with the generation prompt, defaults to KB_MODEL_PROMPT")
+	results := flags.Int("results", 0, "number of results to retrieve, defaults to KB_NUMBER_OF_RESULTS")
+	flags.Parse(args)
+	if *datasetID == 0 {
+		return fmt.Errorf("-dataset is required")
+	}
+
+	target := evaluation.Target{ModelID: *model, NumberOfResults: *results}
+	if *promptFile != "" {
+		prompt, err := os.ReadFile(*promptFile)
+		if err != nil {
+			return err
+		}
+		target.Prompt = string(prompt)
+	}
+
+	r, err := service.Run(ctx, *datasetID, target, *grader, nil)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "QUESTION\tRETRIEVAL HIT\tSCORE\tERROR")
+	for _, result := range r.Results {
+		errText := ""
+		if result.Error != nil {
+			errText = *result.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%s\n", truncate(result.Question, 60), formatHit(result.RetrievalHit), result.Score, errText)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nrun %d: %d cases, retrieval hit rate %s, answer score %s\n",
+		r.ID, r.CaseCount, formatRate(r.RetrievalHitRate), formatRate(r.AnswerScore))
+	return nil
+}
+
+func listRuns(ctx context.Context, service *evaluationsrv.Service, args []string) error {
+	flags := flag.NewFlagSet("runs", flag.ExitOnError)
+	datasetID := flags.Int("dataset", 0, "dataset id")
+	flags.Parse(args)
+	if *datasetID == 0 {
+		return fmt.Errorf("-dataset is required")
+	}
+
+	runs, err := service.GetRuns(ctx, *datasetID, 1, 100)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tGRADER\tMODEL\tRESULTS\tHIT RATE\tSCORE\tSTARTED")
+	for _, r := range runs.Data {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			r.ID, r.Status, r.Grader, r.Target.ModelID, r.Target.NumberOfResults,
+			formatRate(r.RetrievalHitRate), formatRate(r.AnswerScore), r.StartedAt.Format("2006-01-02 15:04"))
+	}
+	return w.Flush()
+}
+
+func compare(ctx context.Context, service *evaluationsrv.Service, args []string) error {
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	base := flags.Int("base", 0, "base run id")
+	candidate := flags.Int("candidate", 0, "candidate run id")
+	flags.Parse(args)
+	if *base == 0 || *candidate == 0 {
+		return fmt.Errorf("-base and -candidate are required")
+	}
+
+	c, err := service.Compare(ctx, *base, *candidate)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "QUESTION\tBASE\tCANDIDATE\tDELTA\tBASE HIT\tCANDIDATE HIT")
+	for _, cc := range c.Cases {
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%+.2f\t%s\t%s\n",
+			truncate(cc.Question, 60), cc.BaseScore, cc.CandidateScore, cc.Delta,
+			formatHit(cc.BaseRetrievalHit), formatHit(cc.CandidateRetrievalHit))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nretrieval hit rate %s -> %s (%s), answer score %s -> %s (%s)\n",
+		formatRate(c.Base.RetrievalHitRate), formatRate(c.Candidate.RetrievalHitRate), formatDelta(c.RetrievalHitRateDelta),
+		formatRate(c.Base.AnswerScore), formatRate(c.Candidate.AnswerScore), formatDelta(c.AnswerScoreDelta))
+	fmt.Printf("%d improved, %d regressed of %d compared cases\n", c.Improved, c.Regressed, len(c.Cases))
+	return nil
+}
+
+func formatRate(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", *v)
+}
+
+func formatDelta(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%+.2f", *v)
+}
+
+func formatHit(hit *bool) string {
+	switch {
+	case hit == nil:
+		return "-"
+	case *hit:
+		return "yes"
+	default:
+		return "no"
+	}
+}
+
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-3]) + "..."
+}
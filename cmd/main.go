@@ -3,22 +3,65 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
+	// Embeds the IANA time zone database, analytics accept any zone even where the system has none
+	_ "time/tzdata"
 
 	"github.com/Abraxas-365/opd/internal/analitics/analiticsapi"
 	analyticsinfra "github.com/Abraxas-365/opd/internal/analitics/analiticsinfra"
 	"github.com/Abraxas-365/opd/internal/analitics/analiticssrv"
+	"github.com/Abraxas-365/opd/internal/answercache/answercacheapi"
+	"github.com/Abraxas-365/opd/internal/answercache/answercacheinfra"
+	"github.com/Abraxas-365/opd/internal/answercache/answercachesrv"
+	"github.com/Abraxas-365/opd/internal/chatuser"
 	"github.com/Abraxas-365/opd/internal/chatuser/chatuserapi"
 	"github.com/Abraxas-365/opd/internal/chatuser/chatuserinfra"
 	"github.com/Abraxas-365/opd/internal/chatuser/chatusersrv"
+	"github.com/Abraxas-365/opd/internal/email/emailapi"
+	"github.com/Abraxas-365/opd/internal/email/emailinfra"
+	"github.com/Abraxas-365/opd/internal/email/emailsrv"
+	"github.com/Abraxas-365/opd/internal/evaluation/evaluationapi"
+	"github.com/Abraxas-365/opd/internal/evaluation/evaluationinfra"
+	"github.com/Abraxas-365/opd/internal/evaluation/evaluationsrv"
+	"github.com/Abraxas-365/opd/internal/experiment/experimentapi"
+	"github.com/Abraxas-365/opd/internal/experiment/experimentinfra"
+	"github.com/Abraxas-365/opd/internal/experiment/experimentsrv"
+	"github.com/Abraxas-365/opd/internal/faq/faqapi"
+	"github.com/Abraxas-365/opd/internal/faq/faqinfra"
+	"github.com/Abraxas-365/opd/internal/faq/faqsrv"
+	"github.com/Abraxas-365/opd/internal/guardrail"
+	"github.com/Abraxas-365/opd/internal/guardrail/guardrailapi"
+	"github.com/Abraxas-365/opd/internal/guardrail/guardrailinfra"
+	"github.com/Abraxas-365/opd/internal/guardrail/guardrailsrv"
+	"github.com/Abraxas-365/opd/internal/handoff/handoffapi"
+	"github.com/Abraxas-365/opd/internal/handoff/handoffinfra"
+	"github.com/Abraxas-365/opd/internal/handoff/handoffsrv"
+	"github.com/Abraxas-365/opd/internal/interaction/interactionapi"
 	"github.com/Abraxas-365/opd/internal/interaction/interactioninfra"
 	"github.com/Abraxas-365/opd/internal/interaction/interactionsrv"
 	"github.com/Abraxas-365/opd/internal/kb/kbapi"
 	"github.com/Abraxas-365/opd/internal/kb/kbasesrv"
 	"github.com/Abraxas-365/opd/internal/kb/kbinfra"
+	"github.com/Abraxas-365/opd/internal/pii"
+	"github.com/Abraxas-365/opd/internal/pii/piiapi"
+	"github.com/Abraxas-365/opd/internal/pii/piiinfra"
+	"github.com/Abraxas-365/opd/internal/pii/piisrv"
+	"github.com/Abraxas-365/opd/internal/slack/slackapi"
+	"github.com/Abraxas-365/opd/internal/slack/slackinfra"
+	"github.com/Abraxas-365/opd/internal/slack/slacksrv"
+	"github.com/Abraxas-365/opd/internal/telegram/telegramapi"
+	"github.com/Abraxas-365/opd/internal/telegram/telegraminfra"
+	"github.com/Abraxas-365/opd/internal/telegram/telegramsrv"
+	"github.com/Abraxas-365/opd/internal/usage/usageapi"
+	"github.com/Abraxas-365/opd/internal/usage/usageinfra"
+	"github.com/Abraxas-365/opd/internal/usage/usagesrv"
 	"github.com/Abraxas-365/opd/internal/user"
 	"github.com/Abraxas-365/opd/internal/user/userapi"
 	"github.com/Abraxas-365/opd/internal/user/userinfra"
 	"github.com/Abraxas-365/opd/internal/user/usersrv"
+	"github.com/Abraxas-365/opd/internal/whatsapp/whatsappapi"
+	"github.com/Abraxas-365/opd/internal/whatsapp/whatsappinfra"
+	"github.com/Abraxas-365/opd/internal/whatsapp/whatsappsrv"
 	"github.com/Abraxas-365/opd/pkg/conf"
 	"github.com/Abraxas-365/toolkit/pkg/errors"
 	"github.com/Abraxas-365/toolkit/pkg/lucia"
@@ -26,6 +69,8 @@ import (
 	"github.com/Abraxas-365/toolkit/pkg/s3client"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/bedrockagent"
@@ -45,6 +90,16 @@ func main() {
 
 	userRepo := userinfra.NewUserStore(db)
 	userSrv := usersrv.NewService(userRepo)
+
+	piiDetectors, err := pii.NewDetectors(conf.PIIDetectors, conf.PIICustomPatterns)
+	if err != nil {
+		panic(err)
+	}
+	piiRepo := piiinfra.NewPIIStore(db)
+	piiSrv, err := piisrv.New(piiRepo, userSrv, piiDetectors, conf.PIIVaultKey)
+	if err != nil {
+		panic(err)
+	}
 	analrepo := analyticsinfra.NewAnalyticsStore(db)
 	sessionStore := luciastore.NewStoreFromConnection(db)
 	authSrv := lucia.NewAuthService[*user.User](userSrv, sessionStore)
@@ -55,11 +110,30 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	analSrv := analiticssrv.NewService(analrepo, s3client)
 
 	interactionRepo := interactioninfra.NewInteractionStore(db)
 	interactionSrv := interactionsrv.New(interactionRepo)
 
+	handoffRepo := handoffinfra.NewHandoffStore(db)
+	handoffSrv := handoffsrv.New(handoffRepo, chatUserSrv, piiSrv, conf.HandoffTriggers)
+
+	faqRepo := faqinfra.NewFAQStore(db)
+	faqSrv := faqsrv.New(faqRepo, conf.FAQMatchThreshold)
+
+	denyPatterns, err := guardrail.NewDenyPatterns(conf.GuardrailDenyPatterns)
+	if err != nil {
+		panic(err)
+	}
+	blockedTopics := guardrail.NewBlockedTopics(conf.GuardrailBlockedTopics)
+	guardrailRepo := guardrailinfra.NewGuardrailStore(db)
+	guardrailSrv := guardrailsrv.New(guardrailRepo,
+		[]guardrail.Rule{guardrail.MaxLength(conf.GuardrailMaxInputLength), guardrail.PromptInjection{}, blockedTopics, denyPatterns},
+		[]guardrail.Rule{blockedTopics, denyPatterns},
+	)
+
+	answerCacheRepo := answercacheinfra.NewAnswerCacheStore(db)
+	answerCacheSrv := answercachesrv.New(answerCacheRepo, conf.AnswerCacheTTL, conf.AnswerCacheThreshold)
+
 	// Initialize Google OAuth provider
 	googleProvider := lucia.NewGoogleProvider(
 		conf.GoogleClientID,
@@ -79,16 +153,42 @@ func main() {
 		panic("unable to load SDK config: " + err.Error())
 	}
 
+	exportUploader := analyticsinfra.NewS3Uploader(s3.NewFromConfig(cfg), "vendy")
+	analSrv := analiticssrv.NewService(analrepo, s3client, exportUploader, piiSrv, userSrv, conf.AnalyticsMinGroupSize, conf.AnalyticsAgeBuckets, conf.AnalyticsTimeZone)
+	go func() {
+		if err := analSrv.RollUp(context.Background(), conf.AnalyticsRollupInterval); err != nil {
+			log.Printf("analytics rollup stopped: %v", err)
+		}
+	}()
+
 	client := bedrockagentruntime.NewFromConfig(cfg)
+	modelClient := bedrockruntime.NewFromConfig(cfg)
 
 	repo := kbinfra.NewStore(db)
 
+	experimentRepo := experimentinfra.NewExperimentStore(db)
+	experimentSrv := experimentsrv.New(experimentRepo, userSrv)
+
 	brClient := bedrockagent.New(session.Must(session.NewSession(&aws.Config{
 		Region: aws.String("us-east-1"),
 	})))
 
 	// Then modify the kbService initialization to include the brClient:
-	kbSerive := kbsrv.New(client, brClient, repo, s3client, *userSrv, *chatUserSrv, *interactionSrv)
+	usageRepo := usageinfra.NewUsageStore(db)
+	usageSrv := usagesrv.New(usageRepo, conf.UsageMonthlyBudget, conf.UsageBudgetAlertPercents, conf.UsageBudgetAlertEmails, conf.AnalyticsTimeZone)
+
+	kbSerive := kbsrv.New(client, brClient, modelClient, repo, s3client, *userSrv, *chatUserSrv, *interactionSrv, handoffSrv, faqSrv, guardrailSrv, piiSrv, answerCacheSrv, experimentSrv, usageSrv)
+	if answerCacheSrv.Enabled() {
+		go func() {
+			if err := kbSerive.WatchIngestions(context.Background(), conf.AnswerCacheIngestionPoll); err != nil {
+				log.Printf("ingestion watcher stopped: %v", err)
+			}
+		}()
+	}
+
+	evaluator := kbsrv.NewEvaluator(client, modelClient, repo, usageSrv)
+	evaluationRepo := evaluationinfra.NewEvaluationStore(db)
+	evaluationSrv := evaluationsrv.New(evaluationRepo, evaluator, evaluator)
 
 	app := fiber.New()
 	authMiddleware := lucia.NewAuthMiddleware(authSrv)
@@ -106,6 +206,64 @@ func main() {
 	userapi.SetupRoutes(app, userSrv, authMiddleware)
 	analiticsapi.SetupRoutes(app, analSrv, authMiddleware)
 	chatuserapi.SetupRoutes(app, chatUserSrv, authMiddleware)
+	handoffapi.SetupRoutes(app, handoffSrv, authMiddleware)
+	faqapi.SetupRoutes(app, faqSrv, authMiddleware)
+	guardrailapi.SetupRoutes(app, guardrailSrv, authMiddleware)
+	piiapi.SetupRoutes(app, piiSrv, authMiddleware)
+	answercacheapi.SetupRoutes(app, answerCacheSrv, authMiddleware)
+	evaluationapi.SetupRoutes(app, evaluationSrv, authMiddleware)
+	experimentapi.SetupRoutes(app, experimentSrv, authMiddleware)
+	usageapi.SetupRoutes(app, usageSrv, authMiddleware)
+	interactionapi.SetupRoutes(app, interactionSrv)
+
+	if conf.WhatsAppEnabled() {
+		whatsAppClient := whatsappinfra.NewGraphClient(conf.WhatsAppAPIURL, conf.WhatsAppPhoneNumberID, conf.WhatsAppToken)
+		whatsAppRepo := whatsappinfra.NewWhatsAppStore(db)
+		whatsAppSrv := whatsappsrv.New(whatsAppClient, whatsAppRepo, kbSerive, chatUserSrv, conf.WhatsAppVerifyToken, conf.WhatsAppAppSecret)
+		whatsappapi.SetupRoutes(app, whatsAppSrv)
+		handoffSrv.RegisterChannel(chatuser.ChannelWhatsApp, whatsAppSrv)
+	}
+
+	if conf.SlackEnabled() {
+		slackClient := slackinfra.NewWebClient(conf.SlackAPIURL, conf.SlackBotToken)
+		slackSrv := slacksrv.New(slackClient, kbSerive, chatUserSrv, conf.SlackSigningSecret)
+		slackapi.SetupRoutes(app, slackSrv)
+		handoffSrv.RegisterChannel(chatuser.ChannelSlack, slackSrv)
+	}
+
+	if conf.TelegramEnabled() {
+		telegramClient := telegraminfra.NewBotClient(conf.TelegramAPIURL, conf.TelegramBotToken)
+		telegramSrv := telegramsrv.New(telegramClient, kbSerive, chatUserSrv, conf.TelegramSecretToken)
+		handoffSrv.RegisterChannel(chatuser.ChannelTelegram, telegramSrv)
+		if conf.TelegramPolling() {
+			go func() {
+				if err := telegramSrv.Poll(context.Background()); err != nil {
+					log.Printf("telegram polling stopped: %v", err)
+				}
+			}()
+		} else {
+			telegramapi.SetupRoutes(app, telegramSrv)
+		}
+	}
+
+	if conf.EmailEnabled() {
+		smtpSender := emailinfra.NewSMTPSender(conf.SMTPHost, conf.SMTPPort, conf.SMTPUsername, conf.SMTPPassword, conf.EmailFrom)
+		emailSrv := emailsrv.New(smtpSender, kbSerive, chatUserSrv, conf.EmailFrom, conf.EmailWebhookToken)
+		handoffSrv.RegisterChannel(chatuser.ChannelEmail, emailSrv)
+		analSrv.RegisterNotifier(emailSrv)
+		usageSrv.RegisterNotifier(emailSrv)
+		if conf.EmailWebhookToken != "" {
+			emailapi.SetupRoutes(app, emailSrv)
+		}
+		if conf.IMAPAddr != "" {
+			mailbox := emailinfra.NewIMAPMailbox(conf.IMAPAddr, conf.IMAPUsername, conf.IMAPPassword, conf.IMAPFolder)
+			go func() {
+				if err := emailSrv.Poll(context.Background(), mailbox, conf.IMAPPollInterval); err != nil {
+					log.Printf("email polling stopped: %v", err)
+				}
+			}()
+		}
+	}
 
 	// Google OAuth routes
 	app.Get("/login/google", func(c *fiber.Ctx) error {
@@ -11,6 +11,12 @@ type Statistic struct {
 	Statistic   string `json:"statistic"`
 	Prefix      string `json:"prefix"`
 	Suffix      string `json:"suffix"`
+	// Previous is the statistic of the period of the same length just before. Change and ChangePercent
+	// are how much a numeric statistic moved since then, ChangePercent is nil when Previous is 0.
+	Previous      string           `json:"previous"`
+	Change        *float64         `json:"change"`
+	ChangePercent *float64         `json:"change_percent"`
+	Sparkline     []DailyStatistic `json:"sparkline"`
 }
 
 func NewTotalMonthlyInteracions(ammount string) *Statistic {
@@ -24,16 +30,6 @@ func NewTotalMonthlyInteracions(ammount string) *Statistic {
 
 }
 
-func NewMonthlyMostConsultedData(file string) *Statistic {
-	return &Statistic{
-		Title:       "Monthly Most Consulted Data",
-		Description: "Most consulted data in the last month",
-		Statistic:   file,
-		Prefix:      "",
-		Suffix:      "",
-	}
-}
-
 func NewTotalUsers(ammount string) *Statistic {
 	return &Statistic{
 		Title:       "Total Users",
@@ -74,7 +70,298 @@ func NewMostConsultedDataBetweenDates(file string, startDate string, endDate str
 	}
 }
 
+// Granularities of the analytics time series, named like the Postgres date_trunc fields
+const (
+	GranularityHour  = "hour"
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// DailyStatistic is the count of a bucket of a time series, Date is the start of the bucket
+// in the time zone of the report
 type DailyStatistic struct {
 	Date  time.Time `json:"date" db:"date"`
 	Count int       `json:"count" db:"count"`
 }
+
+// HandoffStatistics counts the handoff events of a period
+type HandoffStatistics struct {
+	Escalated          int `json:"escalated" db:"escalated"`
+	RequestedByUser    int `json:"requested_by_user" db:"requested_by_user"`
+	NotFoundEscalation int `json:"not_found_escalation" db:"not_found_escalation"`
+	Claimed            int `json:"claimed" db:"claimed"`
+	Replied            int `json:"replied" db:"replied"`
+	Closed             int `json:"closed" db:"closed"`
+}
+
+// FAQStatistics shows how many interactions were answered by a curated FAQ in a period
+type FAQStatistics struct {
+	Interactions int       `json:"interactions" db:"interactions"`
+	Matched      int       `json:"matched" db:"matched"`
+	Unmatched    int       `json:"unmatched" db:"unmatched"`
+	MatchRate    float64   `json:"match_rate" db:"match_rate"`
+	TopFAQs      []FAQHits `json:"top_faqs"`
+}
+
+// FAQHits is how many interactions a FAQ answered
+type FAQHits struct {
+	FAQID    int    `json:"faq_id" db:"faq_id"`
+	Question string `json:"question" db:"question"`
+	Hits     int    `json:"hits" db:"hits"`
+}
+
+// VariantStatistics compares how an experiment variant did. FeedbackRate is the share of
+// interactions rated by the chat user and HelpfulRate the share of those rated helpful.
+type VariantStatistics struct {
+	VariantID    int     `json:"variant_id" db:"variant_id"`
+	Name         string  `json:"name" db:"name"`
+	ChatUsers    int     `json:"chat_users" db:"chat_users"`
+	Interactions int     `json:"interactions" db:"interactions"`
+	Feedback     int     `json:"feedback" db:"feedback"`
+	FeedbackRate float64 `json:"feedback_rate" db:"feedback_rate"`
+	HelpfulRate  float64 `json:"helpful_rate" db:"helpful_rate"`
+	NotFound     int     `json:"not_found" db:"not_found"`
+	NotFoundRate float64 `json:"not_found_rate" db:"not_found_rate"`
+	AvgLatencyMs float64 `json:"avg_latency_ms" db:"avg_latency_ms"`
+	P95LatencyMs float64 `json:"p95_latency_ms" db:"p95_latency_ms"`
+}
+
+// SuggestionStatistics shows how many questions came from the suggested follow-ups in a period
+type SuggestionStatistics struct {
+	Interactions     int     `json:"interactions" db:"interactions"`
+	SuggestionClicks int     `json:"suggestion_clicks" db:"suggestion_clicks"`
+	ClickShare       float64 `json:"click_share" db:"click_share"`
+}
+
+// AnswerStatusCounts counts interactions by how well they were answered
+type AnswerStatusCounts struct {
+	Answered int `json:"answered" db:"answered"`
+	Partial  int `json:"partial" db:"partial"`
+	NotFound int `json:"not_found" db:"not_found"`
+}
+
+// UnansweredQuestion is a question answered partially or not at all
+type UnansweredQuestion struct {
+	Question     string    `json:"question" db:"question"`
+	AnswerStatus string    `json:"answer_status" db:"answer_status"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// ContentGap is a group of similar questions the knowledge base could not fully answer
+type ContentGap struct {
+	Question    string    `json:"question"`
+	Count       int       `json:"count"`
+	NotFound    int       `json:"not_found"`
+	Partial     int       `json:"partial"`
+	Examples    []string  `json:"examples"`
+	LastAskedAt time.Time `json:"last_asked_at"`
+}
+
+// ContentGapReport is how well questions were answered in a period and the gaps found
+type ContentGapReport struct {
+	AnswerStatusCounts
+	Gaps []ContentGap `json:"gaps"`
+}
+
+// Demographic dimensions interactions and chat users can be broken down by
+const (
+	DimensionAge        = "age"
+	DimensionGender     = "gender"
+	DimensionOccupation = "occupation"
+	DimensionLocation   = "location"
+)
+
+// DemographicCount counts the interactions of a demographic value and the distinct chat users behind them.
+// Value is the age, or the trimmed lower case gender, occupation or location, empty when unknown.
+type DemographicCount struct {
+	Value        string `json:"value" db:"value"`
+	Interactions int    `json:"interactions" db:"interactions"`
+	ChatUsers    int    `json:"chat_users" db:"chat_users"`
+}
+
+// DemographicGroup is a group of a demographic breakdown, an age bucket like "25-34" or a value like "female"
+type DemographicGroup struct {
+	Group        string `json:"group"`
+	Interactions int    `json:"interactions"`
+	ChatUsers    int    `json:"chat_users"`
+}
+
+// DemographicBreakdown groups the interactions of a period and their chat users by a dimension. Groups with
+// fewer chat users than MinGroupSize are merged into Suppressed, together with the smallest other groups
+// until Suppressed reaches MinGroupSize, so no group can be worked out from the totals.
+type DemographicBreakdown struct {
+	Dimension         string             `json:"dimension"`
+	MinGroupSize      int                `json:"min_group_size"`
+	Groups            []DemographicGroup `json:"groups"`
+	Suppressed        *DemographicGroup  `json:"suppressed"`
+	SuppressedGroups  int                `json:"suppressed_groups"`
+	TotalInteractions int                `json:"total_interactions"`
+	TotalChatUsers    int                `json:"total_chat_users"`
+}
+
+// FileCitations is how often the answers of a period cited a file. Citations counts every reference,
+// an answer grounded on several chunks of a file cites it several times, Interactions the answers citing it.
+// FileID, UserID and UserEmail are nil when the cited file is not in the files table.
+type FileCitations struct {
+	S3Key        string  `json:"s3_key" db:"s3_key"`
+	FileID       *int    `json:"file_id" db:"file_id"`
+	Filename     string  `json:"filename" db:"filename"`
+	UserID       *string `json:"user_id" db:"user_id"`
+	UserEmail    *string `json:"user_email" db:"user_email"`
+	Citations    int     `json:"citations" db:"citations"`
+	Interactions int     `json:"interactions" db:"interactions"`
+}
+
+// FileCitationCount is how many times a file was cited on a day
+type FileCitationCount struct {
+	S3Key string    `json:"s3_key" db:"s3_key"`
+	Date  time.Time `json:"date" db:"date"`
+	Count int       `json:"count" db:"count"`
+}
+
+// FileCitationTrend is the citations of a file per day, days without citations included
+type FileCitationTrend struct {
+	FileCitations
+	Series []DailyStatistic `json:"series"`
+}
+
+// UncitedFile is an uploaded file no answer cited in a period, a candidate for cleanup.
+// LastCitedAt is the last time any answer cited it, nil when it was never cited.
+type UncitedFile struct {
+	ID          int        `json:"id" db:"id"`
+	Filename    string     `json:"filename" db:"filename"`
+	S3Key       string     `json:"s3_key" db:"s3_key"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	UserEmail   string     `json:"user_email" db:"user_email"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastCitedAt *time.Time `json:"last_cited_at" db:"last_cited_at"`
+}
+
+// ActiveUsers counts the distinct chat users with an interaction on a day (DAU) and in the 7 (WAU)
+// and 30 (MAU) days ending on it. Stickiness is DAU / MAU, how many of the monthly users come back daily.
+type ActiveUsers struct {
+	Date       time.Time `json:"date" db:"date"`
+	DAU        int       `json:"dau" db:"dau"`
+	WAU        int       `json:"wau" db:"wau"`
+	MAU        int       `json:"mau" db:"mau"`
+	Stickiness float64   `json:"stickiness" db:"-"`
+}
+
+// ActiveUsersReport is the rolling active users of every day of a period.
+// Stickiness is the average DAU over the average MAU of the period.
+type ActiveUsersReport struct {
+	Series     []ActiveUsers `json:"series"`
+	AverageDAU float64       `json:"average_dau"`
+	AverageWAU float64       `json:"average_wau"`
+	AverageMAU float64       `json:"average_mau"`
+	Stickiness float64       `json:"stickiness"`
+}
+
+// CohortActivity is how many chat users of a cohort had an interaction Week weeks after the cohort week
+type CohortActivity struct {
+	Cohort time.Time `json:"cohort" db:"cohort"`
+	Week   int       `json:"week" db:"week"`
+	Users  int       `json:"users" db:"users"`
+}
+
+// RetentionWeek is the share of a cohort still active a number of weeks after its first interaction
+type RetentionWeek struct {
+	Week  int     `json:"week"`
+	Users int     `json:"users"`
+	Rate  float64 `json:"rate"`
+}
+
+// RetentionCohort is the chat users whose first interaction was in the week starting on Cohort, a Monday,
+// and how many of them came back every week since. Week 0 is the cohort week, so its rate is 1.
+type RetentionCohort struct {
+	Cohort    time.Time       `json:"cohort"`
+	Users     int             `json:"users"`
+	Retention []RetentionWeek `json:"retention"`
+}
+
+// Export job statuses
+const (
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+)
+
+// Export formats. CSV, NDJSON and Parquet exports are a ZIP with a file per entity,
+// XLSX exports are a workbook with a sheet per entity.
+const (
+	ExportCSV     = "csv"
+	ExportNDJSON  = "ndjson"
+	ExportXLSX    = "xlsx"
+	ExportParquet = "parquet"
+)
+
+// ExportJob is a database export built in the background. Progress goes from 0 to 1 as
+// ExportedRows reaches TotalRows and DownloadURL is set once the export is completed.
+type ExportJob struct {
+	ID           int        `json:"id" db:"id"`
+	Status       string     `json:"status" db:"status"`
+	Format       string     `json:"format" db:"format"`
+	TimeZone     string     `json:"time_zone" db:"time_zone"`
+	RequestedBy  *string    `json:"requested_by" db:"requested_by"`
+	StartDate    *time.Time `json:"start_date" db:"start_date"`
+	EndDate      *time.Time `json:"end_date" db:"end_date"`
+	TotalRows    int        `json:"total_rows" db:"total_rows"`
+	ExportedRows int        `json:"exported_rows" db:"exported_rows"`
+	Progress     float64    `json:"progress" db:"-"`
+	S3Key        *string    `json:"-" db:"s3_key"`
+	Error        *string    `json:"error" db:"error"`
+	DownloadURL  string     `json:"download_url,omitempty" db:"-"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at" db:"completed_at"`
+}
+
+// ExportChatUser is a row of the chat users table in the database export
+type ExportChatUser struct {
+	ID         string    `json:"id" db:"id" parquet:"id"`
+	Age        *int      `json:"age" db:"age" parquet:"age,optional"`
+	Gender     string    `json:"gender" db:"gender" parquet:"gender"`
+	Occupation string    `json:"occupation" db:"occupation" parquet:"occupation"`
+	Location   string    `json:"location" db:"location" parquet:"location"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at" parquet:"created_at,timestamp"`
+}
+
+// ExportInteraction is a row of the interactions table in the database export. Citations are the
+// S3 URIs the answer was grounded on and CitedFiles their file names, in the same order.
+type ExportInteraction struct {
+	ID           int       `json:"id" db:"id" parquet:"id"`
+	UserChatID   string    `json:"user_chat_id" db:"user_chat_id" parquet:"user_chat_id"`
+	Question     string    `json:"question" db:"question" parquet:"question"`
+	AnswerStatus string    `json:"answer_status" db:"answer_status" parquet:"answer_status"`
+	Citations    []string  `json:"citations" db:"citations" parquet:"citations,list"`
+	CitedFiles   []string  `json:"cited_files" db:"cited_files" parquet:"cited_files,list"`
+	FAQID        *int      `json:"faq_id" db:"faq_id" parquet:"faq_id,optional"`
+	Cached       bool      `json:"cached" db:"cached" parquet:"cached"`
+	Helpful      *bool     `json:"helpful" db:"helpful" parquet:"helpful,optional"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at" parquet:"created_at,timestamp"`
+}
+
+// ExportFile is a row of the files table in the database export
+type ExportFile struct {
+	ID        int       `json:"id" db:"id" parquet:"id"`
+	Filename  string    `json:"filename" db:"filename" parquet:"filename"`
+	S3Key     string    `json:"s3_key" db:"s3_key" parquet:"s3_key"`
+	UserID    string    `json:"user_id" db:"user_id" parquet:"user_id"`
+	UserEmail string    `json:"user_email" db:"user_email" parquet:"user_email"`
+	CreatedAt time.Time `json:"created_at" db:"created_at" parquet:"created_at,timestamp"`
+}
+
+// ExportManifest describes a database export, StartDate and EndDate are nil for the complete database
+type ExportManifest struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	StartDate   *time.Time         `json:"start_date"`
+	EndDate     *time.Time         `json:"end_date"`
+	TimeZone    string             `json:"time_zone"`
+	Files       []ExportedFileInfo `json:"files"`
+}
+
+// ExportedFileInfo is a file inside the export archive, or a sheet of the workbook, and how many rows it has
+type ExportedFileInfo struct {
+	Name string `json:"name"`
+	Rows int    `json:"rows"`
+}
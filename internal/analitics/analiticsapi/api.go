@@ -1,8 +1,11 @@
 package analiticsapi
 
 import (
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Abraxas-365/opd/internal/analitics"
 	"github.com/Abraxas-365/opd/internal/analitics/analiticssrv"
 	"github.com/Abraxas-365/opd/internal/user"
 	"github.com/Abraxas-365/toolkit/pkg/errors"
@@ -19,6 +22,60 @@ func SetupRoutes(
 	app.Get("/analytics/daily/users", authMiddleware.RequireAuth(), getDailyUsers(service))
 	app.Get("/analytics/daily/interactions", authMiddleware.RequireAuth(), getDailyInteractions(service))
 	app.Get("/analytics/export", authMiddleware.RequireAuth(), exportDatabase(service))
+	app.Get("/analytics/exports/:id", authMiddleware.RequireAuth(), getExport(service))
+	app.Get("/analytics/handoffs", authMiddleware.RequireAuth(), getHandoffStatistics(service))
+	app.Get("/analytics/faqs", authMiddleware.RequireAuth(), getFAQStatistics(service))
+	app.Get("/analytics/suggestions", authMiddleware.RequireAuth(), getSuggestionStatistics(service))
+	app.Get("/analytics/content-gaps", authMiddleware.RequireAuth(), getContentGaps(service))
+	app.Get("/analytics/experiments/:id", authMiddleware.RequireAuth(), getExperimentStatistics(service))
+	app.Get("/analytics/demographics/:dimension", authMiddleware.RequireAuth(), getDemographicBreakdown(service))
+	app.Get("/analytics/active-users", authMiddleware.RequireAuth(), getActiveUsers(service))
+	app.Get("/analytics/retention", authMiddleware.RequireAuth(), getRetentionCohorts(service))
+	app.Get("/analytics/files/top", authMiddleware.RequireAuth(), getTopCitedFiles(service))
+	app.Get("/analytics/files/trends", authMiddleware.RequireAuth(), getFileCitationTrends(service))
+	app.Get("/analytics/files/uncited", authMiddleware.RequireAuth(), getUncitedFiles(service))
+}
+
+// parseOptionalDateRange reads the optional start_date and end_date query parameters
+func parseOptionalDateRange(c *fiber.Ctx) (*time.Time, *time.Time, error) {
+	var startDate, endDate *time.Time
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		parsedDate, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			return nil, nil, errors.ErrBadRequest("Invalid start_date format. Use YYYY-MM-DD")
+		}
+		startDate = &parsedDate
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		parsedDate, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			return nil, nil, errors.ErrBadRequest("Invalid end_date format. Use YYYY-MM-DD")
+		}
+		endDate = &parsedDate
+	}
+
+	if startDate != nil && endDate != nil && endDate.Before(*startDate) {
+		return nil, nil, errors.ErrBadRequest("end_date cannot be before start_date")
+	}
+
+	return startDate, endDate, nil
+}
+
+// parseLocation reads the optional tz query parameter, an IANA time zone like America/Lima that dates
+// and buckets are read in. Without it the service uses the configured time zone.
+func parseLocation(c *fiber.Ctx) (*time.Location, error) {
+	tz := c.Query("tz")
+	if tz == "" {
+		return nil, nil
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" {
+		return nil, errors.ErrBadRequest("Invalid tz, use an IANA time zone like America/Lima")
+	}
+	return loc, nil
 }
 
 func getAnalytics(service *analiticssrv.Service) fiber.Handler {
@@ -58,8 +115,15 @@ func getAnalytics(service *analiticssrv.Service) fiber.Handler {
 			})
 		}
 
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
 		// Get analytics
-		analytics, err := service.GetAllAnalitics(c.Context(), startDate, endDate)
+		analytics, err := service.GetAllAnalitics(c.Context(), startDate, endDate, loc)
 		if err != nil {
 			switch {
 			case errors.IsNotFound(err):
@@ -114,9 +178,20 @@ func getDailyUsers(service *analiticssrv.Service) fiber.Handler {
 			})
 		}
 
-		dailyStats, err := service.GetDailyUsersInRange(c.Context(), startDate, endDate)
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		dailyStats, err := service.GetDailyUsersInRange(c.Context(), startDate, endDate, loc, c.Query("granularity", analitics.GranularityDay))
 		if err != nil {
 			switch {
+			case errors.IsBadRequest(err):
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
 			case errors.IsNotFound(err):
 				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 					"error": err.Error(),
@@ -169,9 +244,20 @@ func getDailyInteractions(service *analiticssrv.Service) fiber.Handler {
 			})
 		}
 
-		dailyStats, err := service.GetDailyInteractionsInRange(c.Context(), startDate, endDate)
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		dailyStats, err := service.GetDailyInteractionsInRange(c.Context(), startDate, endDate, loc, c.Query("granularity", analitics.GranularityDay))
 		if err != nil {
 			switch {
+			case errors.IsBadRequest(err):
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
 			case errors.IsNotFound(err):
 				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 					"error": err.Error(),
@@ -230,8 +316,57 @@ func exportDatabase(service *analiticssrv.Service) fiber.Handler {
 			})
 		}
 
-		// Export database to CSV
-		presignedURL, err := service.ExportDatabaseToCSV(c.Context(), startDate, endDate)
+		userID, err := lucia.GetSession(c).UserIDToString()
+		if err != nil {
+			return err
+		}
+
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		// Build the export in the background, poll /analytics/exports/:id for the download link
+		job, err := service.StartExport(c.Context(), startDate, endDate, loc, c.Query("format", analitics.ExportCSV), userID)
+		if err != nil {
+			switch {
+			case errors.IsBadRequest(err):
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			case errors.IsServiceUnavailable(err):
+				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			case errors.IsDatabaseError(err):
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Database error occurred",
+				})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to export database",
+				})
+			}
+		}
+
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"data": job,
+		})
+	}
+}
+
+func getExport(service *analiticssrv.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Export id must be a number",
+			})
+		}
+
+		job, err := service.GetExport(c.Context(), id)
 		if err != nil {
 			switch {
 			case errors.IsNotFound(err):
@@ -248,15 +383,470 @@ func exportDatabase(service *analiticssrv.Service) fiber.Handler {
 				})
 			default:
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": "Failed to export database",
+					"error": "Failed to fetch export",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"data": job,
+		})
+	}
+}
+
+func getHandoffStatistics(service *analiticssrv.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		startDate, endDate, err := parseOptionalDateRange(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		stats, err := service.GetHandoffStatistics(c.Context(), startDate, endDate, loc)
+		if err != nil {
+			switch {
+			case errors.IsDatabaseError(err):
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Database error occurred",
+				})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch handoff statistics",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"data": stats,
+		})
+	}
+}
+
+func getFAQStatistics(service *analiticssrv.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		startDate, endDate, err := parseOptionalDateRange(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		stats, err := service.GetFAQStatistics(c.Context(), startDate, endDate, loc)
+		if err != nil {
+			switch {
+			case errors.IsDatabaseError(err):
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Database error occurred",
+				})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch FAQ statistics",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"data": stats,
+		})
+	}
+}
+
+func getSuggestionStatistics(service *analiticssrv.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		startDate, endDate, err := parseOptionalDateRange(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		stats, err := service.GetSuggestionStatistics(c.Context(), startDate, endDate, loc)
+		if err != nil {
+			switch {
+			case errors.IsDatabaseError(err):
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Database error occurred",
+				})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch suggestion statistics",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"data": stats,
+		})
+	}
+}
+
+func getExperimentStatistics(service *analiticssrv.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Experiment id must be a number",
+			})
+		}
+
+		stats, err := service.GetExperimentStatistics(c.Context(), id)
+		if err != nil {
+			switch {
+			case errors.IsDatabaseError(err):
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Database error occurred",
+				})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch experiment statistics",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"data": stats,
+		})
+	}
+}
+
+func getContentGaps(service *analiticssrv.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		startDate, endDate, err := parseOptionalDateRange(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		limit, err := strconv.Atoi(c.Query("limit", "20"))
+		if err != nil || limit < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid limit",
+			})
+		}
+
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		report, err := service.GetContentGaps(c.Context(), startDate, endDate, loc, limit)
+		if err != nil {
+			switch {
+			case errors.IsDatabaseError(err):
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Database error occurred",
+				})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch content gaps",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"data": report,
+		})
+	}
+}
+
+func getDemographicBreakdown(service *analiticssrv.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		startDate, endDate, err := parseOptionalDateRange(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		// Comma separated bucket boundaries, 18,30,50 groups ages as under 18, 18-29, 30-49 and 50+
+		var ageBuckets []int
+		if buckets := c.Query("age_buckets"); buckets != "" {
+			for _, b := range strings.Split(buckets, ",") {
+				age, err := strconv.Atoi(strings.TrimSpace(b))
+				if err != nil {
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+						"error": "Invalid age_buckets, use comma separated ages like 18,30,50",
+					})
+				}
+				ageBuckets = append(ageBuckets, age)
+			}
+		}
+
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		breakdown, err := service.GetDemographicBreakdown(c.Context(), c.Params("dimension"), startDate, endDate, loc, ageBuckets)
+		if err != nil {
+			switch {
+			case errors.IsBadRequest(err):
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			case errors.IsDatabaseError(err):
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Database error occurred",
+				})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch demographic breakdown",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"data": breakdown,
+		})
+	}
+}
+
+func getTopCitedFiles(service *analiticssrv.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		startDate, endDate, err := parseOptionalDateRange(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(analiticssrv.DefaultTopFiles)))
+		if err != nil || limit < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid limit",
+			})
+		}
+
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		files, err := service.GetTopCitedFiles(c.Context(), startDate, endDate, loc, limit)
+		if err != nil {
+			switch {
+			case errors.IsBadRequest(err):
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			case errors.IsDatabaseError(err):
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Database error occurred",
+				})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch top cited files",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"data": files,
+		})
+	}
+}
+
+func getFileCitationTrends(service *analiticssrv.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		startDate, endDate, err := parseOptionalDateRange(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if startDate == nil || endDate == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Both start_date and end_date are required",
+			})
+		}
+
+		limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(analiticssrv.DefaultTopFiles)))
+		if err != nil || limit < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid limit",
+			})
+		}
+
+		// s3_key can be repeated to follow specific files instead of the most cited ones
+		var s3Keys []string
+		for _, key := range c.Context().QueryArgs().PeekMulti("s3_key") {
+			s3Keys = append(s3Keys, string(key))
+		}
+
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		trends, err := service.GetFileCitationTrends(c.Context(), *startDate, *endDate, loc, c.Query("granularity", analitics.GranularityDay), s3Keys, limit)
+		if err != nil {
+			switch {
+			case errors.IsBadRequest(err):
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			case errors.IsDatabaseError(err):
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Database error occurred",
+				})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch file citation trends",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"data": trends,
+		})
+	}
+}
+
+func getUncitedFiles(service *analiticssrv.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		startDate, endDate, err := parseOptionalDateRange(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		files, err := service.GetUncitedFiles(c.Context(), startDate, endDate, loc)
+		if err != nil {
+			switch {
+			case errors.IsDatabaseError(err):
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Database error occurred",
+				})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch uncited files",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"data": files,
+		})
+	}
+}
+
+func getActiveUsers(service *analiticssrv.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		startDate, endDate, err := parseOptionalDateRange(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if startDate == nil || endDate == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Both start_date and end_date are required",
+			})
+		}
+
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		stats, err := service.GetActiveUsers(c.Context(), *startDate, *endDate, loc)
+		if err != nil {
+			switch {
+			case errors.IsDatabaseError(err):
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Database error occurred",
+				})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch active users",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"data": stats,
+		})
+	}
+}
+
+func getRetentionCohorts(service *analiticssrv.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		startDate, endDate, err := parseOptionalDateRange(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if startDate == nil || endDate == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Both start_date and end_date are required",
+			})
+		}
+
+		loc, err := parseLocation(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		stats, err := service.GetRetentionCohorts(c.Context(), *startDate, *endDate, loc)
+		if err != nil {
+			switch {
+			case errors.IsDatabaseError(err):
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Database error occurred",
+				})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch retention cohorts",
 				})
 			}
 		}
 
 		return c.JSON(fiber.Map{
-			"data": fiber.Map{
-				"download_url": presignedURL,
-			},
+			"data": stats,
 		})
 	}
 }
@@ -7,9 +7,6 @@ import (
 	"time"
 
 	"github.com/Abraxas-365/opd/internal/analitics"
-	"github.com/Abraxas-365/opd/internal/chatuser"
-	"github.com/Abraxas-365/opd/internal/interaction"
-	"github.com/Abraxas-365/opd/internal/kb"
 	"github.com/Abraxas-365/toolkit/pkg/errors"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
@@ -24,16 +21,12 @@ func NewAnalyticsStore(db *sqlx.DB) *PostgresStore {
 }
 
 func (s *PostgresStore) GetInteractions(ctx context.Context, startDate *time.Time, endDate *time.Time) (*analitics.Statistic, error) {
-	query := `SELECT COUNT(*) FROM interactions`
-	args := []interface{}{}
-
-	if startDate != nil && endDate != nil {
-		query = `SELECT COUNT(*) FROM interactions WHERE created_at BETWEEN $1 AND $2`
-		args = append(args, startDate, endDate)
-	}
+	query := `
+		WITH source AS (` + interactionsSource(1) + `)
+		SELECT COALESCE(SUM(interactions), 0) FROM source`
 
 	var count int
-	err := s.db.GetContext(ctx, &count, query, args...)
+	err := s.db.GetContext(ctx, &count, query, rollupArgs(startDate, endDate, "")...)
 	if err != nil {
 		return nil, errors.ErrDatabase("failed to get interactions count: " + err.Error())
 	}
@@ -49,50 +42,6 @@ func (s *PostgresStore) GetInteractions(ctx context.Context, startDate *time.Tim
 	return analitics.NewTotalMonthlyInteracions(strconv.Itoa(count)), nil
 }
 
-func (s *PostgresStore) GetMostConsultedData(ctx context.Context, startDate *time.Time, endDate *time.Time) (*analitics.Statistic, error) {
-	query := `
-        WITH file_counts AS (
-            SELECT unnest(context_interaction) as file_path, COUNT(*) as access_count 
-            FROM interactions
-    `
-	args := []interface{}{}
-
-	if startDate != nil && endDate != nil {
-		query += ` WHERE created_at BETWEEN $1 AND $2`
-		args = append(args, startDate, endDate)
-	}
-
-	query += `
-            GROUP BY file_path
-        )
-        SELECT file_path as filename
-        FROM file_counts 
-        WHERE file_path IS NOT NULL
-        ORDER BY access_count DESC 
-        LIMIT 1
-    `
-
-	var filename string
-	err := s.db.GetContext(ctx, &filename, query, args...)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			filename = "No data available"
-		} else {
-			return nil, errors.ErrDatabase("failed to get most consulted data: " + err.Error())
-		}
-	}
-
-	if startDate != nil && endDate != nil {
-		return analitics.NewMostConsultedDataBetweenDates(
-			filename,
-			startDate.Format("2006-01-02"),
-			endDate.Format("2006-01-02"),
-		), nil
-	}
-
-	return analitics.NewMonthlyMostConsultedData(filename), nil
-}
-
 func (s *PostgresStore) GetTotalUsers(ctx context.Context, startDate *time.Time, endDate *time.Time) (*analitics.Statistic, error) {
 	query := `SELECT COUNT(*) FROM "user"`
 	args := []interface{}{}
@@ -119,19 +68,19 @@ func (s *PostgresStore) GetTotalUsers(ctx context.Context, startDate *time.Time,
 	return analitics.NewTotalUsers(strconv.Itoa(count)), nil
 }
 
-func (s *PostgresStore) GetDailyUsers(ctx context.Context, startDate, endDate time.Time) ([]analitics.DailyStatistic, error) {
+func (s *PostgresStore) GetDailyUsers(ctx context.Context, startDate, endDate time.Time, granularity string, timeZone string) ([]analitics.DailyStatistic, error) {
 	query := `
 		SELECT 
-			DATE(created_at) as date,
+			date_trunc($3, created_at, $4) as date,
 			COUNT(*) as count
 		FROM "user"
 		WHERE created_at BETWEEN $1 AND $2
-		GROUP BY DATE(created_at)
+		GROUP BY 1
 		ORDER BY date
 	`
 
 	var stats []analitics.DailyStatistic
-	err := s.db.SelectContext(ctx, &stats, query, startDate, endDate)
+	err := s.db.SelectContext(ctx, &stats, query, startDate, endDate, granularity, timeZone)
 	if err != nil {
 		return nil, errors.ErrDatabase("failed to get daily users: " + err.Error())
 	}
@@ -143,52 +92,89 @@ func (s *PostgresStore) GetDailyUsers(ctx context.Context, startDate, endDate ti
 	return stats, nil
 }
 
-func (s *PostgresStore) GetDailyActiveUsers(ctx context.Context, startDate, endDate time.Time, activeDays int) ([]analitics.DailyStatistic, error) {
-	if activeDays < 1 {
-		return nil, errors.ErrBadRequest("active days must be greater than 0")
+func (s *PostgresStore) GetActiveUsers(ctx context.Context, startDate, endDate time.Time, timeZone string) ([]analitics.ActiveUsers, error) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, errors.ErrBadRequest("unknown time zone " + timeZone)
 	}
+	// The MAU of the first day counts the activity of the 29 days before it
+	windowStart := startDate.In(loc).AddDate(0, 0, -29)
 
+	// Every day is joined with the activity of the 30 days ending on it, the MAU window holds the WAU and DAU ones
 	query := `
-		WITH daily_active AS (
-			SELECT 
-				DATE(created_at) as date,
-				COUNT(DISTINCT user_chat_id) as count
-			FROM interactions
-			WHERE created_at BETWEEN $1 AND $2
-			GROUP BY DATE(created_at)
-			HAVING COUNT(DISTINCT user_chat_id) >= $3
+		WITH source AS (` + chatUserSource(1) + `
+		), days AS (
+			SELECT generate_series(DATE($4::timestamptz AT TIME ZONE $6::text), DATE($5::timestamptz AT TIME ZONE $6), interval '1 day')::date AS date
+		), activity AS (
+			SELECT DISTINCT user_chat_id, DATE(bucket AT TIME ZONE $6) AS date
+			FROM source
 		)
-		SELECT date, count
-		FROM daily_active
-		ORDER BY date
+		SELECT
+			d.date,
+			COUNT(DISTINCT a.user_chat_id) FILTER (WHERE a.date = d.date) AS dau,
+			COUNT(DISTINCT a.user_chat_id) FILTER (WHERE a.date > d.date - 7) AS wau,
+			COUNT(DISTINCT a.user_chat_id) AS mau
+		FROM days d
+		LEFT JOIN activity a ON a.date > d.date - 30 AND a.date <= d.date
+		GROUP BY d.date
+		ORDER BY d.date
 	`
 
-	var stats []analitics.DailyStatistic
-	err := s.db.SelectContext(ctx, &stats, query, startDate, endDate, activeDays)
+	stats := []analitics.ActiveUsers{}
+	args := append(rollupArgs(&windowStart, &endDate, timeZone), startDate, endDate, timeZone)
+	err = s.db.SelectContext(ctx, &stats, query, args...)
 	if err != nil {
-		return nil, errors.ErrDatabase("failed to get daily active users: " + err.Error())
+		return nil, errors.ErrDatabase("failed to get active users: " + err.Error())
 	}
 
-	if len(stats) == 0 {
-		return nil, errors.ErrNotFound("no daily active user statistics found for the specified criteria")
+	return stats, nil
+}
+
+func (s *PostgresStore) GetCohortActivity(ctx context.Context, startDate, endDate time.Time, timeZone string) ([]analitics.CohortActivity, error) {
+	// The source covers all the history, a cohort is keyed by the first interaction ever
+	query := `
+		WITH source AS (` + chatUserSource(1) + `
+		), cohorts AS (
+			SELECT user_chat_id, DATE(date_trunc('week', MIN(bucket) AT TIME ZONE $6::text)) AS cohort
+			FROM source
+			GROUP BY user_chat_id
+			HAVING MIN(bucket) BETWEEN $4 AND $5
+		), activity AS (
+			SELECT DISTINCT user_chat_id, DATE(date_trunc('week', bucket AT TIME ZONE $6)) AS week
+			FROM source
+			WHERE bucket >= $4
+		)
+		SELECT c.cohort, (a.week - c.cohort) / 7 AS week, COUNT(*) AS users
+		FROM cohorts c
+		JOIN activity a ON a.user_chat_id = c.user_chat_id
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`
+
+	stats := []analitics.CohortActivity{}
+	args := append(historyArgs(&startDate, &endDate, timeZone), startDate, endDate, timeZone)
+	err := s.db.SelectContext(ctx, &stats, query, args...)
+	if err != nil {
+		return nil, errors.ErrDatabase("failed to get cohort activity: " + err.Error())
 	}
 
 	return stats, nil
 }
 
-func (s *PostgresStore) GetDailyInteractions(ctx context.Context, startDate, endDate time.Time) ([]analitics.DailyStatistic, error) {
+func (s *PostgresStore) GetDailyInteractions(ctx context.Context, startDate, endDate time.Time, granularity string, timeZone string) ([]analitics.DailyStatistic, error) {
 	query := `
+		WITH source AS (` + interactionsSource(1) + `)
 		SELECT 
-			DATE(created_at) as date,
-			COUNT(*) as count
-		FROM interactions
-		WHERE created_at BETWEEN $1 AND $2
-		GROUP BY DATE(created_at)
+			date_trunc($4, bucket, $5) as date,
+			SUM(interactions) as count
+		FROM source
+		GROUP BY 1
 		ORDER BY date
 	`
 
 	var stats []analitics.DailyStatistic
-	err := s.db.SelectContext(ctx, &stats, query, startDate, endDate)
+	args := append(rollupArgs(&startDate, &endDate, timeZone), granularity, timeZone)
+	err := s.db.SelectContext(ctx, &stats, query, args...)
 	if err != nil {
 		return nil, errors.ErrDatabase("failed to get daily interactions: " + err.Error())
 	}
@@ -200,92 +186,399 @@ func (s *PostgresStore) GetDailyInteractions(ctx context.Context, startDate, end
 	return stats, nil
 }
 
-func (r *PostgresStore) GetAllChatUsers(ctx context.Context, startDate, endDate *time.Time) ([]chatuser.ChatUser, error) {
-	query := `SELECT id, age, gender, occupation, location 
-              FROM chatUser`
+func (s *PostgresStore) GetHandoffStatistics(ctx context.Context, startDate, endDate *time.Time) (*analitics.HandoffStatistics, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE event_type = 'escalated') as escalated,
+			COUNT(*) FILTER (WHERE event_type = 'escalated' AND reason = 'requested') as requested_by_user,
+			COUNT(*) FILTER (WHERE event_type = 'escalated' AND reason = 'not_found') as not_found_escalation,
+			COUNT(*) FILTER (WHERE event_type = 'claimed') as claimed,
+			COUNT(*) FILTER (WHERE event_type = 'replied') as replied,
+			COUNT(*) FILTER (WHERE event_type = 'closed') as closed
+		FROM handoff_events`
+	args := []interface{}{}
 
-	var args []interface{}
 	if startDate != nil && endDate != nil {
 		query += ` WHERE created_at BETWEEN $1 AND $2`
 		args = append(args, startDate, endDate)
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, errors.ErrDatabase("failed to query chat users: " + err.Error())
+	var stats analitics.HandoffStatistics
+	if err := s.db.GetContext(ctx, &stats, query, args...); err != nil {
+		return nil, errors.ErrDatabase("failed to get handoff statistics: " + err.Error())
 	}
-	defer rows.Close()
 
-	var chatUsers []chatuser.ChatUser
-	for rows.Next() {
-		var chatUser chatuser.ChatUser
-		err := rows.Scan(
-			&chatUser.ID,
-			&chatUser.Age,
-			&chatUser.Gender,
-			&chatUser.Ocupation,
-			&chatUser.Location,
-		)
-		if err != nil {
-			return nil, errors.ErrDatabase("failed to scan chat user data: " + err.Error())
-		}
-		chatUsers = append(chatUsers, chatUser)
+	return &stats, nil
+}
+
+func (s *PostgresStore) GetFAQStatistics(ctx context.Context, startDate, endDate *time.Time) (*analitics.FAQStatistics, error) {
+	where := ``
+	args := []interface{}{}
+
+	if startDate != nil && endDate != nil {
+		where = ` WHERE i.created_at BETWEEN $1 AND $2`
+		args = append(args, startDate, endDate)
+	}
+
+	query := `
+		WITH source AS (` + interactionsSource(1) + `)
+		SELECT
+			COALESCE(SUM(interactions), 0) as interactions,
+			COALESCE(SUM(faq_matched), 0) as matched,
+			COALESCE(SUM(interactions - faq_matched), 0) as unmatched,
+			COALESCE(SUM(faq_matched)::float / NULLIF(SUM(interactions), 0), 0) as match_rate
+		FROM source`
+
+	var stats analitics.FAQStatistics
+	if err := s.db.GetContext(ctx, &stats, query, rollupArgs(startDate, endDate, "")...); err != nil {
+		return nil, errors.ErrDatabase("failed to get faq statistics: " + err.Error())
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, errors.ErrDatabase("error iterating chat users: " + err.Error())
+	topQuery := `
+		SELECT f.id as faq_id, f.question, COUNT(*) as hits
+		FROM interactions i
+		JOIN faqs f ON f.id = i.faq_id` + where + `
+		GROUP BY f.id, f.question
+		ORDER BY hits DESC
+		LIMIT 10`
+
+	stats.TopFAQs = []analitics.FAQHits{}
+	if err := s.db.SelectContext(ctx, &stats.TopFAQs, topQuery, args...); err != nil {
+		return nil, errors.ErrDatabase("failed to get most matched faqs: " + err.Error())
 	}
 
-	if len(chatUsers) == 0 {
-		return nil, errors.ErrNotFound("no chat users found for the specified criteria")
+	return &stats, nil
+}
+
+func (s *PostgresStore) GetSuggestionStatistics(ctx context.Context, startDate, endDate *time.Time) (*analitics.SuggestionStatistics, error) {
+	query := `
+		WITH source AS (` + interactionsSource(1) + `)
+		SELECT
+			COALESCE(SUM(interactions), 0) as interactions,
+			COALESCE(SUM(suggestion_clicks), 0) as suggestion_clicks,
+			COALESCE(SUM(suggestion_clicks)::float / NULLIF(SUM(interactions), 0), 0) as click_share
+		FROM source`
+
+	var stats analitics.SuggestionStatistics
+	if err := s.db.GetContext(ctx, &stats, query, rollupArgs(startDate, endDate, "")...); err != nil {
+		return nil, errors.ErrDatabase("failed to get suggestion statistics: " + err.Error())
 	}
 
-	return chatUsers, nil
+	return &stats, nil
 }
 
-func (r *PostgresStore) GetAllInteractionsData(ctx context.Context, startDate, endDate *time.Time) ([]interaction.Interaction, error) {
-	query := `SELECT id, user_chat_id, COALESCE(context_interaction, '{}') AS context_interaction 
-              FROM interactions`
+func (s *PostgresStore) GetExperimentStatistics(ctx context.Context, experimentID int) ([]analitics.VariantStatistics, error) {
+	query := `
+		SELECT
+			v.id as variant_id,
+			v.name,
+			COUNT(DISTINCT i.user_chat_id) as chat_users,
+			COUNT(i.id) as interactions,
+			COUNT(i.helpful) as feedback,
+			COALESCE(COUNT(i.helpful)::float / NULLIF(COUNT(i.id), 0), 0) as feedback_rate,
+			COALESCE((COUNT(*) FILTER (WHERE i.helpful))::float / NULLIF(COUNT(i.helpful), 0), 0) as helpful_rate,
+			COUNT(*) FILTER (WHERE i.answer_status = 'not_found') as not_found,
+			COALESCE((COUNT(*) FILTER (WHERE i.answer_status = 'not_found'))::float / NULLIF(COUNT(i.id), 0), 0) as not_found_rate,
+			COALESCE(AVG(i.latency_ms), 0) as avg_latency_ms,
+			COALESCE(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY i.latency_ms), 0) as p95_latency_ms
+		FROM experiment_variants v
+		LEFT JOIN interactions i ON i.variant_id = v.id
+		WHERE v.experiment_id = $1
+		GROUP BY v.id, v.name
+		ORDER BY v.id`
+
+	stats := []analitics.VariantStatistics{}
+	if err := s.db.SelectContext(ctx, &stats, query, experimentID); err != nil {
+		return nil, errors.ErrDatabase("failed to get experiment statistics: " + err.Error())
+	}
+
+	return stats, nil
+}
+
+func (s *PostgresStore) GetAnswerStatusCounts(ctx context.Context, startDate, endDate *time.Time) (*analitics.AnswerStatusCounts, error) {
+	query := `
+		WITH source AS (` + interactionsSource(1) + `)
+		SELECT
+			COALESCE(SUM(answered), 0) as answered,
+			COALESCE(SUM(partial), 0) as partial,
+			COALESCE(SUM(not_found), 0) as not_found
+		FROM source`
+
+	var counts analitics.AnswerStatusCounts
+	if err := s.db.GetContext(ctx, &counts, query, rollupArgs(startDate, endDate, "")...); err != nil {
+		return nil, errors.ErrDatabase("failed to get answer status counts: " + err.Error())
+	}
+
+	return &counts, nil
+}
+
+// unansweredQuestionsLimit bounds how many recent questions are clustered in one report
+const unansweredQuestionsLimit = 5000
+
+func (s *PostgresStore) GetUnansweredQuestions(ctx context.Context, startDate, endDate *time.Time) ([]analitics.UnansweredQuestion, error) {
+	query := `
+		SELECT question, answer_status, created_at
+		FROM interactions
+		WHERE answer_status IN ('partial', 'not_found') AND question IS NOT NULL`
+	args := []interface{}{}
 
-	var args []interface{}
 	if startDate != nil && endDate != nil {
-		query += ` WHERE created_at BETWEEN $1 AND $2`
+		query += ` AND created_at BETWEEN $1 AND $2`
 		args = append(args, startDate, endDate)
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, errors.ErrDatabase("failed to query interactions: " + err.Error())
+	query += ` ORDER BY created_at DESC LIMIT ` + strconv.Itoa(unansweredQuestionsLimit)
+
+	var questions []analitics.UnansweredQuestion
+	if err := s.db.SelectContext(ctx, &questions, query, args...); err != nil {
+		return nil, errors.ErrDatabase("failed to get unanswered questions: " + err.Error())
 	}
-	defer rows.Close()
 
-	var interactions []interaction.Interaction
-	for rows.Next() {
-		var interaction interaction.Interaction
-		err := rows.Scan(
-			&interaction.ID,
-			&interaction.UserChatID,
-			pq.Array(&interaction.ContextInteraction), // Manejo de arreglos con pq.Array
+	return questions, nil
+}
+
+// demographicColumns maps the demographic dimensions to the chatUser expression they group by
+var demographicColumns = map[string]string{
+	analitics.DimensionAge:        `COALESCE(c.age::text, '')`,
+	analitics.DimensionGender:     `COALESCE(LOWER(TRIM(c.gender)), '')`,
+	analitics.DimensionOccupation: `COALESCE(LOWER(TRIM(c.occupation)), '')`,
+	analitics.DimensionLocation:   `COALESCE(LOWER(TRIM(c.location)), '')`,
+}
+
+func (s *PostgresStore) GetDemographicCounts(ctx context.Context, dimension string, startDate, endDate *time.Time) ([]analitics.DemographicCount, error) {
+	column, ok := demographicColumns[dimension]
+	if !ok {
+		return nil, errors.ErrBadRequest("unknown demographic dimension " + dimension)
+	}
+
+	// The values are read from chatUser, so a chat user who updates their profile moves to the new group
+	query := `
+		WITH source AS (` + chatUserSource(1) + `)
+		SELECT ` + column + ` AS value,
+		       SUM(s.interactions) AS interactions,
+		       COUNT(DISTINCT s.user_chat_id) AS chat_users
+		FROM source s
+		JOIN chatUser c ON c.id = s.user_chat_id
+		GROUP BY 1`
+
+	counts := []analitics.DemographicCount{}
+	if err := s.db.SelectContext(ctx, &counts, query, rollupArgs(startDate, endDate, "")...); err != nil {
+		return nil, errors.ErrDatabase("failed to get demographic counts: " + err.Error())
+	}
+
+	return counts, nil
+}
+
+// citedKey turns an s3://bucket/key citation into the S3 key the files table stores
+const citedKey = `regexp_replace(c.uri, '^s3://[^/]+/', '')`
+
+func (s *PostgresStore) GetTopCitedFiles(ctx context.Context, startDate, endDate *time.Time, s3Keys []string, limit int) ([]analitics.FileCitations, error) {
+	args := rollupArgs(startDate, endDate, "")
+	where := ``
+	if len(s3Keys) > 0 {
+		args = append(args, pq.Array(s3Keys))
+		where = ` WHERE s3_key = ANY($` + strconv.Itoa(len(args)) + `)`
+	}
+	args = append(args, limit)
+
+	query := `
+		WITH source AS (` + fileSource(1) + `
+		), ranked AS (
+			SELECT s3_key, SUM(citations) AS citations, SUM(interactions) AS interactions
+			FROM source` + where + `
+			GROUP BY s3_key
+			ORDER BY citations DESC, s3_key
+			LIMIT $` + strconv.Itoa(len(args)) + `
+		)
+		SELECT r.s3_key, f.id AS file_id, COALESCE(f.filename, regexp_replace(r.s3_key, '^.*/', '')) AS filename,
+		       f.user_id, f.user_email, r.citations, r.interactions
+		FROM ranked r
+		LEFT JOIN LATERAL (
+			SELECT id, filename, user_id, user_email
+			FROM files
+			WHERE s3_key = r.s3_key
+			ORDER BY id DESC
+			LIMIT 1
+		) f ON TRUE
+		ORDER BY r.citations DESC, r.s3_key`
+
+	files := []analitics.FileCitations{}
+	if err := s.db.SelectContext(ctx, &files, query, args...); err != nil {
+		return nil, errors.ErrDatabase("failed to get top cited files: " + err.Error())
+	}
+
+	return files, nil
+}
+
+func (s *PostgresStore) GetFileCitationCounts(ctx context.Context, s3Keys []string, startDate, endDate time.Time, granularity string, timeZone string) ([]analitics.FileCitationCount, error) {
+	query := `
+		WITH source AS (` + fileSource(1) + `)
+		SELECT s3_key,
+		       date_trunc($5, bucket, $6) AS date,
+		       SUM(citations) AS count
+		FROM source
+		WHERE s3_key = ANY($4)
+		GROUP BY 1, 2
+		ORDER BY 2`
+
+	counts := []analitics.FileCitationCount{}
+	args := append(rollupArgs(&startDate, &endDate, timeZone), pq.Array(s3Keys), granularity, timeZone)
+	if err := s.db.SelectContext(ctx, &counts, query, args...); err != nil {
+		return nil, errors.ErrDatabase("failed to get file citation counts: " + err.Error())
+	}
+
+	return counts, nil
+}
+
+func (s *PostgresStore) GetUncitedFiles(ctx context.Context, startDate, endDate *time.Time) ([]analitics.UncitedFile, error) {
+	// The source covers all the history for the last citation, citations in the range are filtered from it
+	query := `
+		WITH source AS (` + fileSource(1) + `
+		), cited AS (
+			SELECT s3_key,
+			       MAX(bucket) AS cited_at,
+			       COUNT(*) FILTER (WHERE bucket BETWEEN COALESCE($4::timestamptz, '-infinity') AND COALESCE($5::timestamptz, 'infinity')) AS in_range
+			FROM source
+			GROUP BY s3_key
 		)
+		SELECT f.id, f.filename, f.s3_key, f.user_id, f.user_email, f.created_at, c.cited_at AS last_cited_at
+		FROM files f
+		LEFT JOIN cited c ON c.s3_key = f.s3_key
+		WHERE COALESCE(c.in_range, 0) = 0
+		  AND f.created_at <= COALESCE($5::timestamptz, 'infinity')
+		ORDER BY f.created_at, f.id`
+
+	args := append(historyArgs(startDate, endDate, ""), startDate, endDate)
+
+	files := []analitics.UncitedFile{}
+	if err := s.db.SelectContext(ctx, &files, query, args...); err != nil {
+		return nil, errors.ErrDatabase("failed to get uncited files: " + err.Error())
+	}
+
+	return files, nil
+}
+
+// exportBatchSize is how many rows every FETCH of an export cursor reads
+const exportBatchSize = 1000
+
+// streamRows runs query through a server side cursor in a read only transaction and calls scan for
+// every row, fetching exportBatchSize rows at a time so an export never holds a whole table in memory
+func (r *PostgresStore) streamRows(ctx context.Context, cursor string, query string, args []interface{}, scan func(*sql.Rows) error) error {
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return errors.ErrDatabase("failed to start transaction: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DECLARE `+cursor+` NO SCROLL CURSOR FOR `+query, args...); err != nil {
+		return errors.ErrDatabase("failed to declare " + cursor + ": " + err.Error())
+	}
+
+	fetch := `FETCH FORWARD ` + strconv.Itoa(exportBatchSize) + ` FROM ` + cursor
+	for {
+		rows, err := tx.QueryContext(ctx, fetch)
 		if err != nil {
-			return nil, errors.ErrDatabase("failed to scan interaction data: " + err.Error())
+			return errors.ErrDatabase("failed to fetch from " + cursor + ": " + err.Error())
+		}
+
+		fetched := 0
+		for rows.Next() {
+			fetched++
+			if err := scan(rows); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return errors.ErrDatabase("error iterating " + cursor + ": " + err.Error())
+		}
+		rows.Close()
+
+		if fetched < exportBatchSize {
+			return nil
 		}
-		interactions = append(interactions, interaction)
 	}
+}
+
+func (r *PostgresStore) GetAllChatUsers(ctx context.Context, startDate, endDate *time.Time, fn func(analitics.ExportChatUser) error) error {
+	query := `SELECT id, age, COALESCE(gender, '') AS gender, COALESCE(occupation, '') AS occupation,
+                     COALESCE(location, '') AS location, created_at
+              FROM chatUser`
 
-	if err = rows.Err(); err != nil {
-		return nil, errors.ErrDatabase("error iterating interactions: " + err.Error())
+	var args []interface{}
+	if startDate != nil && endDate != nil {
+		query += ` WHERE created_at BETWEEN $1 AND $2`
+		args = append(args, startDate, endDate)
 	}
+	query += ` ORDER BY created_at, id`
 
-	if len(interactions) == 0 {
-		return nil, errors.ErrNotFound("no interactions found for the specified criteria")
+	return r.streamRows(ctx, "export_chat_users", query, args, func(rows *sql.Rows) error {
+		var chatUser analitics.ExportChatUser
+		err := rows.Scan(
+			&chatUser.ID,
+			&chatUser.Age,
+			&chatUser.Gender,
+			&chatUser.Occupation,
+			&chatUser.Location,
+			&chatUser.CreatedAt,
+		)
+		if err != nil {
+			return errors.ErrDatabase("failed to scan chat user data: " + err.Error())
+		}
+		return fn(chatUser)
+	})
+}
+
+// GetAllInteractionsData joins every cited S3 URI with the uploaded file it points to,
+// citations of files no longer in the files table keep the last segment of their key as name
+func (r *PostgresStore) GetAllInteractionsData(ctx context.Context, startDate, endDate *time.Time, fn func(analitics.ExportInteraction) error) error {
+	query := `SELECT i.id, i.user_chat_id, COALESCE(i.question, '') AS question,
+                     COALESCE(i.answer_status, '') AS answer_status,
+                     COALESCE(i.context_interaction, '{}') AS citations,
+                     COALESCE((
+                         SELECT array_agg(COALESCE(f.filename, regexp_replace(c.uri, '^.*/', '')) ORDER BY c.ord)
+                         FROM unnest(i.context_interaction) WITH ORDINALITY AS c(uri, ord)
+                         LEFT JOIN LATERAL (
+                             SELECT filename FROM files
+                             WHERE s3_key = regexp_replace(c.uri, '^s3://[^/]+/', '')
+                             ORDER BY id DESC
+                             LIMIT 1
+                         ) f ON TRUE
+                     ), '{}') AS cited_files,
+                     i.faq_id, i.cached, i.helpful, i.created_at
+              FROM interactions i`
+
+	var args []interface{}
+	if startDate != nil && endDate != nil {
+		query += ` WHERE i.created_at BETWEEN $1 AND $2`
+		args = append(args, startDate, endDate)
 	}
+	query += ` ORDER BY i.id`
 
-	return interactions, nil
+	return r.streamRows(ctx, "export_interactions", query, args, func(rows *sql.Rows) error {
+		var interaction analitics.ExportInteraction
+		err := rows.Scan(
+			&interaction.ID,
+			&interaction.UserChatID,
+			&interaction.Question,
+			&interaction.AnswerStatus,
+			pq.Array(&interaction.Citations),
+			pq.Array(&interaction.CitedFiles),
+			&interaction.FAQID,
+			&interaction.Cached,
+			&interaction.Helpful,
+			&interaction.CreatedAt,
+		)
+		if err != nil {
+			return errors.ErrDatabase("failed to scan interaction data: " + err.Error())
+		}
+		return fn(interaction)
+	})
 }
 
-func (r *PostgresStore) GetAllFiles(ctx context.Context, startDate, endDate *time.Time) ([]kb.DataFile, error) {
-	query := `SELECT id, filename, s3_key, user_id, user_email 
+func (r *PostgresStore) GetAllFiles(ctx context.Context, startDate, endDate *time.Time, fn func(analitics.ExportFile) error) error {
+	query := `SELECT id, filename, s3_key, user_id, user_email, created_at
               FROM files`
 
 	var args []interface{}
@@ -293,36 +586,94 @@ func (r *PostgresStore) GetAllFiles(ctx context.Context, startDate, endDate *tim
 		query += ` WHERE created_at BETWEEN $1 AND $2`
 		args = append(args, startDate, endDate)
 	}
+	query += ` ORDER BY id`
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, errors.ErrDatabase("failed to query files: " + err.Error())
-	}
-	defer rows.Close()
-
-	var files []kb.DataFile
-	for rows.Next() {
-		var file kb.DataFile
+	return r.streamRows(ctx, "export_files", query, args, func(rows *sql.Rows) error {
+		var file analitics.ExportFile
 		err := rows.Scan(
 			&file.ID,
 			&file.Filename,
 			&file.S3Key,
 			&file.UserID,
 			&file.UserEmail,
+			&file.CreatedAt,
 		)
 		if err != nil {
-			return nil, errors.ErrDatabase("failed to scan file data: " + err.Error())
+			return errors.ErrDatabase("failed to scan file data: " + err.Error())
 		}
-		files = append(files, file)
+		return fn(file)
+	})
+}
+
+func (r *PostgresStore) CountExportRows(ctx context.Context, startDate, endDate *time.Time) (int, error) {
+	query := `SELECT (SELECT COUNT(*) FROM chatUser WHERE $1::timestamptz IS NULL OR created_at BETWEEN $1 AND $2)
+                   + (SELECT COUNT(*) FROM interactions WHERE $1::timestamptz IS NULL OR created_at BETWEEN $1 AND $2)
+                   + (SELECT COUNT(*) FROM files WHERE $1::timestamptz IS NULL OR created_at BETWEEN $1 AND $2)`
+
+	var total int
+	if err := r.db.GetContext(ctx, &total, query, startDate, endDate); err != nil {
+		return 0, errors.ErrDatabase("failed to count export rows: " + err.Error())
 	}
+	return total, nil
+}
+
+const exportJobColumns = `id, status, format, time_zone, requested_by, start_date, end_date, total_rows, exported_rows, s3_key, error, created_at, completed_at`
+
+func (r *PostgresStore) CreateExportJob(ctx context.Context, job analitics.ExportJob) (*analitics.ExportJob, error) {
+	query := `
+        INSERT INTO export_jobs (status, format, time_zone, requested_by, start_date, end_date, total_rows)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING ` + exportJobColumns
 
-	if err = rows.Err(); err != nil {
-		return nil, errors.ErrDatabase("error iterating files: " + err.Error())
+	var created analitics.ExportJob
+	err := r.db.GetContext(ctx, &created, query, analitics.ExportRunning, job.Format, job.TimeZone, job.RequestedBy, job.StartDate, job.EndDate, job.TotalRows)
+	if err != nil {
+		return nil, errors.ErrDatabase("failed to create export job: " + err.Error())
 	}
+	return &created, nil
+}
 
-	if len(files) == 0 {
-		return nil, errors.ErrNotFound("no files found for the specified criteria")
+func (r *PostgresStore) GetExportJob(ctx context.Context, id int) (*analitics.ExportJob, error) {
+	var job analitics.ExportJob
+	err := r.db.GetContext(ctx, &job, `SELECT `+exportJobColumns+` FROM export_jobs WHERE id = $1`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("export job not found")
+		}
+		return nil, errors.ErrDatabase("failed to get export job: " + err.Error())
 	}
+	return &job, nil
+}
 
-	return files, nil
+func (r *PostgresStore) UpdateExportProgress(ctx context.Context, id int, exportedRows int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE export_jobs SET exported_rows = $2 WHERE id = $1`, id, exportedRows)
+	if err != nil {
+		return errors.ErrDatabase("failed to update export progress: " + err.Error())
+	}
+	return nil
+}
+
+func (r *PostgresStore) CompleteExportJob(ctx context.Context, id int, s3Key string, exportedRows int) (*analitics.ExportJob, error) {
+	query := `
+        UPDATE export_jobs
+        SET status = $2, s3_key = $3, exported_rows = $4, completed_at = CURRENT_TIMESTAMP
+        WHERE id = $1
+        RETURNING ` + exportJobColumns
+
+	var job analitics.ExportJob
+	if err := r.db.GetContext(ctx, &job, query, id, analitics.ExportCompleted, s3Key, exportedRows); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("export job not found")
+		}
+		return nil, errors.ErrDatabase("failed to complete export job: " + err.Error())
+	}
+	return &job, nil
+}
+
+func (r *PostgresStore) FailExportJob(ctx context.Context, id int, reason string) error {
+	query := `UPDATE export_jobs SET status = $2, error = $3, completed_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, id, analitics.ExportFailed, reason); err != nil {
+		return errors.ErrDatabase("failed to fail export job: " + err.Error())
+	}
+	return nil
 }
@@ -0,0 +1,191 @@
+package analyticsinfra
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// The rollup sources are a UNION of the rolled up hours before the cutoff and the live interactions
+// after it, grouped by minute so buckets of any time zone can be built from them. A source takes three
+// placeholders starting at p: whether the rollups can be read, and the optional start and end of the range.
+
+// rollupCutoff is where the rollups end, or -infinity when the range or its time zone is not on whole
+// hours and everything is read live
+func rollupCutoff(p int) string {
+	return `(SELECT CASE WHEN $` + strconv.Itoa(p) + `::boolean THEN rolled_up_to ELSE '-infinity'::timestamptz END FROM analytics_rollup_state)`
+}
+
+func rollupRange(p int) string {
+	return ` BETWEEN COALESCE($` + strconv.Itoa(p+1) + `::timestamptz, '-infinity') AND COALESCE($` + strconv.Itoa(p+2) + `::timestamptz, 'infinity')`
+}
+
+// interactionsSource counts the interactions per bucket, by answer status, answered by a FAQ and asked from a suggestion
+func interactionsSource(p int) string {
+	return `
+		SELECT bucket, interactions, answered, partial, not_found, faq_matched, suggestion_clicks
+		FROM analytics_hourly
+		WHERE bucket < ` + rollupCutoff(p) + ` AND bucket` + rollupRange(p) + `
+		UNION ALL
+		SELECT date_trunc('minute', created_at),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE answer_status = 'answered'),
+		       COUNT(*) FILTER (WHERE answer_status = 'partial'),
+		       COUNT(*) FILTER (WHERE answer_status = 'not_found'),
+		       COUNT(faq_id),
+		       COUNT(*) FILTER (WHERE from_suggestion)
+		FROM interactions
+		WHERE created_at >= ` + rollupCutoff(p) + ` AND created_at` + rollupRange(p) + `
+		GROUP BY 1`
+}
+
+// chatUserSource counts the interactions of every chat user per bucket
+func chatUserSource(p int) string {
+	return `
+		SELECT bucket, user_chat_id, interactions
+		FROM analytics_chat_user_hourly
+		WHERE bucket < ` + rollupCutoff(p) + ` AND bucket` + rollupRange(p) + `
+		UNION ALL
+		SELECT date_trunc('minute', created_at), user_chat_id, COUNT(*)
+		FROM interactions
+		WHERE created_at >= ` + rollupCutoff(p) + ` AND created_at` + rollupRange(p) + `
+		GROUP BY 1, 2`
+}
+
+// fileSource counts the citations of every cited file per bucket and the interactions citing it
+func fileSource(p int) string {
+	return `
+		SELECT bucket, s3_key, citations, interactions
+		FROM analytics_file_hourly
+		WHERE bucket < ` + rollupCutoff(p) + ` AND bucket` + rollupRange(p) + `
+		UNION ALL
+		SELECT date_trunc('minute', i.created_at), ` + citedKey + `, COUNT(*), COUNT(DISTINCT i.id)
+		FROM interactions i, unnest(i.context_interaction) AS c(uri)
+		WHERE c.uri IS NOT NULL AND i.created_at >= ` + rollupCutoff(p) + ` AND i.created_at` + rollupRange(p) + `
+		GROUP BY 1, 2`
+}
+
+// rollupArgs are the arguments of a source: the rollups are read when the range starts and ends on
+// whole hours and timeZone, if any, is a whole number of hours away from UTC
+func rollupArgs(startDate, endDate *time.Time, timeZone string) []interface{} {
+	if startDate == nil || endDate == nil {
+		startDate, endDate = nil, nil
+	}
+
+	useRollups := true
+	if startDate != nil {
+		// The range ends a nanosecond before an hour
+		next := endDate.Add(time.Nanosecond)
+		useRollups = startDate.Truncate(time.Hour).Equal(*startDate) && next.Truncate(time.Hour).Equal(next)
+	}
+	if timeZone != "" && useRollups {
+		loc, err := time.LoadLocation(timeZone)
+		if err != nil {
+			useRollups = false
+		} else {
+			now := time.Now()
+			for _, t := range []*time.Time{startDate, endDate, &now} {
+				if t == nil {
+					continue
+				}
+				if _, offset := t.In(loc).Zone(); offset%3600 != 0 {
+					useRollups = false
+				}
+			}
+		}
+	}
+
+	return []interface{}{useRollups, startDate, endDate}
+}
+
+// historyArgs are the arguments of a source covering all the history, for queries filtering the buckets
+// of the range themselves. Whether the rollups can be read still depends on the range.
+func historyArgs(startDate, endDate *time.Time, timeZone string) []interface{} {
+	args := rollupArgs(startDate, endDate, timeZone)
+	return []interface{}{args[0], nil, nil}
+}
+
+// rollupChunk bounds the hours a rollup transaction aggregates, so the first rollup of a long history
+// commits as it goes
+const rollupChunk = 7 * 24 * time.Hour
+
+// RollUp aggregates the interactions of the hours after the rolled up ones, up to a week of them and
+// never past until, and returns the end of the rolled up hours. The state row is locked, so instances
+// running the job at the same time never roll up an hour twice.
+func (s *PostgresStore) RollUp(ctx context.Context, until time.Time) (time.Time, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return time.Time{}, errors.ErrDatabase("failed to start transaction: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	var from time.Time
+	if err := tx.GetContext(ctx, &from, `SELECT rolled_up_to FROM analytics_rollup_state FOR UPDATE`); err != nil {
+		return time.Time{}, errors.ErrDatabase("failed to get rollup state: " + err.Error())
+	}
+
+	to := from.Add(rollupChunk)
+	if to.After(until) {
+		to = until
+	}
+	if !to.After(from) {
+		return from, nil
+	}
+
+	rollups := []struct {
+		name  string
+		query string
+	}{
+		{"interactions", `
+			INSERT INTO analytics_hourly (bucket, interactions, answered, partial, not_found, faq_matched, suggestion_clicks)
+			SELECT date_trunc('hour', created_at, 'UTC'),
+			       COUNT(*),
+			       COUNT(*) FILTER (WHERE answer_status = 'answered'),
+			       COUNT(*) FILTER (WHERE answer_status = 'partial'),
+			       COUNT(*) FILTER (WHERE answer_status = 'not_found'),
+			       COUNT(faq_id),
+			       COUNT(*) FILTER (WHERE from_suggestion)
+			FROM interactions
+			WHERE created_at >= $1 AND created_at < $2
+			GROUP BY 1
+			ON CONFLICT (bucket) DO UPDATE SET
+				interactions = EXCLUDED.interactions,
+				answered = EXCLUDED.answered,
+				partial = EXCLUDED.partial,
+				not_found = EXCLUDED.not_found,
+				faq_matched = EXCLUDED.faq_matched,
+				suggestion_clicks = EXCLUDED.suggestion_clicks`},
+		{"chat users", `
+			INSERT INTO analytics_chat_user_hourly (bucket, user_chat_id, interactions)
+			SELECT date_trunc('hour', created_at, 'UTC'), user_chat_id, COUNT(*)
+			FROM interactions
+			WHERE created_at >= $1 AND created_at < $2
+			GROUP BY 1, 2
+			ON CONFLICT (bucket, user_chat_id) DO UPDATE SET interactions = EXCLUDED.interactions`},
+		{"files", `
+			INSERT INTO analytics_file_hourly (bucket, s3_key, citations, interactions)
+			SELECT date_trunc('hour', i.created_at, 'UTC'), ` + citedKey + `, COUNT(*), COUNT(DISTINCT i.id)
+			FROM interactions i, unnest(i.context_interaction) AS c(uri)
+			WHERE c.uri IS NOT NULL AND i.created_at >= $1 AND i.created_at < $2
+			GROUP BY 1, 2
+			ON CONFLICT (bucket, s3_key) DO UPDATE SET
+				citations = EXCLUDED.citations,
+				interactions = EXCLUDED.interactions`},
+	}
+	for _, rollup := range rollups {
+		if _, err := tx.ExecContext(ctx, rollup.query, from, to); err != nil {
+			return time.Time{}, errors.ErrDatabase("failed to roll up " + rollup.name + ": " + err.Error())
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE analytics_rollup_state SET rolled_up_to = $1`, to); err != nil {
+		return time.Time{}, errors.ErrDatabase("failed to update rollup state: " + err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, errors.ErrDatabase("failed to commit rollup: " + err.Error())
+	}
+
+	return to, nil
+}
@@ -0,0 +1,94 @@
+package analyticsinfra
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// partSize is the size of every part but the last one, S3 needs at least 5 MiB
+const partSize = 8 << 20
+
+// S3Uploader uploads exports with S3 multipart uploads, holding a single part in memory
+type S3Uploader struct {
+	client *s3.Client
+	bucket string
+}
+
+func NewS3Uploader(client *s3.Client, bucket string) *S3Uploader {
+	return &S3Uploader{
+		client: client,
+		bucket: bucket,
+	}
+}
+
+// Upload reads body until EOF and uploads it to key, the upload is aborted if body or S3 fail
+func (u *S3Uploader) Upload(ctx context.Context, key string, contentType string, body io.Reader) error {
+	upload, err := u.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(u.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	parts, err := u.uploadParts(ctx, key, upload.UploadId, body)
+	if err != nil {
+		// A background context so a canceled export still releases its parts
+		u.client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(u.bucket),
+			Key:      aws.String(key),
+			UploadId: upload.UploadId,
+		})
+		return err
+	}
+
+	_, err = u.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.bucket),
+		Key:             aws.String(key),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+func (u *S3Uploader) uploadParts(ctx context.Context, key string, uploadID *string, body io.Reader) ([]types.CompletedPart, error) {
+	var parts []types.CompletedPart
+	buffer := make([]byte, partSize)
+	for number := int32(1); ; number++ {
+		n, err := io.ReadFull(body, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		// An empty body still needs one part to complete the upload
+		if n == 0 && len(parts) > 0 {
+			return parts, nil
+		}
+
+		part, uploadErr := u.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(u.bucket),
+			Key:           aws.String(key),
+			UploadId:      uploadID,
+			PartNumber:    aws.Int32(number),
+			Body:          bytes.NewReader(buffer[:n]),
+			ContentLength: aws.Int64(int64(n)),
+		})
+		if uploadErr != nil {
+			return nil, fmt.Errorf("failed to upload part %d: %w", number, uploadErr)
+		}
+		parts = append(parts, types.CompletedPart{ETag: part.ETag, PartNumber: aws.Int32(number)})
+
+		if err != nil {
+			return parts, nil
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package analiticssrv
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/analitics"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// DefaultCardDays is how many days, today included, the statistic cards cover when no date range is given
+const DefaultCardDays = 30
+
+// cardPeriod is the date range of the statistic cards, the period of the same length just before it
+// they are compared to, and the buckets of their sparklines
+type cardPeriod struct {
+	start, end                 time.Time
+	previousStart, previousEnd time.Time
+	granularity                string
+	buckets                    []time.Time
+	loc                        *time.Location
+}
+
+func newCardPeriod(startDate, endDate *time.Time, loc *time.Location) (cardPeriod, error) {
+	if startDate == nil || endDate == nil {
+		today := time.Now().In(loc)
+		first := today.AddDate(0, 0, -(DefaultCardDays - 1))
+		startDate, endDate = &first, &today
+	}
+	start, end := dayRange(*startDate, *endDate, loc)
+
+	// Calendar days, a day of loc is 23 or 25 hours long when daylight saving time changes
+	days := int(time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC).
+		Sub(time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)).Hours()/24) + 1
+
+	p := cardPeriod{
+		start:         start,
+		end:           end,
+		previousStart: start.AddDate(0, 0, -days),
+		previousEnd:   start.Add(-time.Nanosecond),
+		granularity:   sparklineGranularity(days),
+		loc:           loc,
+	}
+	var err error
+	p.buckets, err = buckets(start, end, p.granularity, loc)
+	return p, err
+}
+
+// sparklineGranularity keeps the sparkline of a period between a few and about a hundred points
+func sparklineGranularity(days int) string {
+	switch {
+	case days <= 92:
+		return analitics.GranularityDay
+	case days <= 731:
+		return analitics.GranularityWeek
+	default:
+		return analitics.GranularityMonth
+	}
+}
+
+func (s Service) interactionsCard(ctx context.Context, p cardPeriod) (*analitics.Statistic, error) {
+	card, err := s.repo.GetInteractions(ctx, &p.start, &p.end)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.repo.GetInteractions(ctx, &p.previousStart, &p.previousEnd)
+	if err != nil {
+		return nil, err
+	}
+	compare(card, previous.Statistic)
+
+	series, err := s.repo.GetDailyInteractions(ctx, p.start, p.end, p.granularity, p.loc.String())
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	card.Sparkline = sparkline(p.buckets, series)
+
+	return card, nil
+}
+
+func (s Service) usersCard(ctx context.Context, p cardPeriod) (*analitics.Statistic, error) {
+	card, err := s.repo.GetTotalUsers(ctx, &p.start, &p.end)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.repo.GetTotalUsers(ctx, &p.previousStart, &p.previousEnd)
+	if err != nil {
+		return nil, err
+	}
+	compare(card, previous.Statistic)
+
+	series, err := s.repo.GetDailyUsers(ctx, p.start, p.end, p.granularity, p.loc.String())
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	card.Sparkline = sparkline(p.buckets, series)
+
+	return card, nil
+}
+
+// mostConsultedCard names the most cited file of the period and of the previous one. The statistic is not
+// a number so there is no change, the sparkline is the citations of the current most cited file.
+func (s Service) mostConsultedCard(ctx context.Context, p cardPeriod) (*analitics.Statistic, error) {
+	files, err := s.repo.GetTopCitedFiles(ctx, &p.start, &p.end, nil, 1)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.repo.GetTopCitedFiles(ctx, &p.previousStart, &p.previousEnd, nil, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	card := analitics.NewMostConsultedDataBetweenDates(mostConsulted(files), p.start.Format("2006-01-02"), p.end.Format("2006-01-02"))
+	card.Previous = mostConsulted(previous)
+
+	var series []analitics.DailyStatistic
+	if len(files) > 0 {
+		counts, err := s.repo.GetFileCitationCounts(ctx, []string{files[0].S3Key}, p.start, p.end, p.granularity, p.loc.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range counts {
+			series = append(series, analitics.DailyStatistic{Date: c.Date, Count: c.Count})
+		}
+	}
+	card.Sparkline = sparkline(p.buckets, series)
+
+	return card, nil
+}
+
+func mostConsulted(files []analitics.FileCitations) string {
+	if len(files) == 0 {
+		return "No data available"
+	}
+	return files[0].Filename
+}
+
+// compare sets the previous statistic of a card and, when both are numbers, how much it changed
+func compare(card *analitics.Statistic, previous string) {
+	card.Previous = previous
+
+	current, err := strconv.ParseFloat(card.Statistic, 64)
+	if err != nil {
+		return
+	}
+	before, err := strconv.ParseFloat(previous, 64)
+	if err != nil {
+		return
+	}
+
+	change := current - before
+	card.Change = &change
+	if before != 0 {
+		percent := change / before * 100
+		card.ChangePercent = &percent
+	}
+}
+
+// sparkline fills the buckets without statistics with zeros
+func sparkline(buckets []time.Time, stats []analitics.DailyStatistic) []analitics.DailyStatistic {
+	// Buckets are keyed by their Unix time, the same instant read from Postgres has another location
+	counts := make(map[int64]int, len(stats))
+	for _, stat := range stats {
+		counts[stat.Date.Unix()] += stat.Count
+	}
+
+	series := make([]analitics.DailyStatistic, len(buckets))
+	for i, bucket := range buckets {
+		series[i] = analitics.DailyStatistic{Date: bucket, Count: counts[bucket.Unix()]}
+	}
+	return series
+}
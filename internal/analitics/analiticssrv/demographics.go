@@ -0,0 +1,168 @@
+package analiticssrv
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/analitics"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// DefaultMinGroupSize is the fewest chat users a demographic group needs to be reported on its own
+const DefaultMinGroupSize = 5
+
+// DefaultAgeBuckets are the boundaries of the age buckets: under 18, 18-24, ..., 65+
+var DefaultAgeBuckets = []int{18, 25, 35, 45, 55, 65}
+
+// unknownGroup holds the chat users that did not give the demographic value
+const unknownGroup = "unknown"
+
+// GetDemographicBreakdown groups the interactions in the date range and their chat users by dimension.
+// Ages are grouped by ageBuckets, the configured buckets when empty, and small groups are suppressed.
+func (s Service) GetDemographicBreakdown(ctx context.Context, dimension string, startDate, endDate *time.Time, loc *time.Location, ageBuckets []int) (*analitics.DemographicBreakdown, error) {
+	switch dimension {
+	case analitics.DimensionAge, analitics.DimensionGender, analitics.DimensionOccupation, analitics.DimensionLocation:
+	default:
+		return nil, errors.ErrBadRequest("dimension must be age, gender, occupation or location")
+	}
+
+	if len(ageBuckets) == 0 {
+		ageBuckets = s.ageBuckets
+	}
+	for i, b := range ageBuckets {
+		if b < 1 || (i > 0 && b <= ageBuckets[i-1]) {
+			return nil, errors.ErrBadRequest("age buckets must be increasing positive ages")
+		}
+	}
+
+	startDate, endDate = wholeDays(startDate, endDate, s.location(loc))
+	counts, err := s.repo.GetDemographicCounts(ctx, dimension, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Different values can land in the same group, every chat user has a single value so their counts add up
+	byGroup := make(map[string]*analitics.DemographicGroup)
+	var groups []*analitics.DemographicGroup
+	for _, c := range counts {
+		name := s.demographicGroup(dimension, c.Value, ageBuckets)
+		g, ok := byGroup[name]
+		if !ok {
+			g = &analitics.DemographicGroup{Group: name}
+			byGroup[name] = g
+			groups = append(groups, g)
+		}
+		g.Interactions += c.Interactions
+		g.ChatUsers += c.ChatUsers
+	}
+
+	if dimension == analitics.DimensionAge {
+		order := ageGroupOrder(ageBuckets)
+		sort.Slice(groups, func(i, j int) bool {
+			return order[groups[i].Group] < order[groups[j].Group]
+		})
+	} else {
+		sort.Slice(groups, func(i, j int) bool {
+			if (groups[i].Group == unknownGroup) != (groups[j].Group == unknownGroup) {
+				return groups[j].Group == unknownGroup
+			}
+			if groups[i].ChatUsers != groups[j].ChatUsers {
+				return groups[i].ChatUsers > groups[j].ChatUsers
+			}
+			return groups[i].Group < groups[j].Group
+		})
+	}
+
+	breakdown := &analitics.DemographicBreakdown{
+		Dimension:    dimension,
+		MinGroupSize: s.minGroupSize,
+		Groups:       []analitics.DemographicGroup{},
+	}
+	for _, g := range groups {
+		breakdown.TotalInteractions += g.Interactions
+		breakdown.TotalChatUsers += g.ChatUsers
+	}
+	suppress(breakdown, groups)
+
+	return breakdown, nil
+}
+
+// demographicGroup names the group of a demographic value. Free text values go through the PII
+// redaction, so an email typed as a location is reported as [EMAIL_1].
+func (s Service) demographicGroup(dimension, value string, ageBuckets []int) string {
+	if value == "" {
+		return unknownGroup
+	}
+	if dimension != analitics.DimensionAge {
+		return s.piiService.Redact(value)
+	}
+
+	age, err := strconv.Atoi(value)
+	if err != nil || age <= 0 {
+		return unknownGroup
+	}
+	return ageGroup(age, ageBuckets)
+}
+
+func ageGroup(age int, buckets []int) string {
+	if age < buckets[0] {
+		return "under " + strconv.Itoa(buckets[0])
+	}
+	// The last bucket starting at or before age
+	i := sort.SearchInts(buckets, age+1) - 1
+	if i == len(buckets)-1 {
+		return strconv.Itoa(buckets[i]) + "+"
+	}
+	return strconv.Itoa(buckets[i]) + "-" + strconv.Itoa(buckets[i+1]-1)
+}
+
+// ageGroupOrder ranks the age groups youngest first, with unknown last
+func ageGroupOrder(buckets []int) map[string]int {
+	order := map[string]int{
+		ageGroup(0, buckets): 0,
+		unknownGroup:         len(buckets) + 1,
+	}
+	for i, b := range buckets {
+		order[ageGroup(b, buckets)] = i + 1
+	}
+	return order
+}
+
+// suppress reports the groups with at least MinGroupSize chat users and merges the others into
+// Suppressed. While Suppressed is still below MinGroupSize the smallest reported group joins it,
+// otherwise a single small group could be worked out by subtracting the others from the totals.
+func suppress(breakdown *analitics.DemographicBreakdown, groups []*analitics.DemographicGroup) {
+	var visible []*analitics.DemographicGroup
+	suppressed := analitics.DemographicGroup{Group: "suppressed"}
+	for _, g := range groups {
+		if g.ChatUsers >= breakdown.MinGroupSize {
+			visible = append(visible, g)
+			continue
+		}
+		suppressed.Interactions += g.Interactions
+		suppressed.ChatUsers += g.ChatUsers
+		breakdown.SuppressedGroups++
+	}
+
+	if breakdown.SuppressedGroups > 0 {
+		for suppressed.ChatUsers < breakdown.MinGroupSize && len(visible) > 0 {
+			smallest := 0
+			for i, g := range visible {
+				if g.ChatUsers < visible[smallest].ChatUsers {
+					smallest = i
+				}
+			}
+			suppressed.Interactions += visible[smallest].Interactions
+			suppressed.ChatUsers += visible[smallest].ChatUsers
+			breakdown.SuppressedGroups++
+			visible = append(visible[:smallest], visible[smallest+1:]...)
+		}
+		breakdown.Suppressed = &suppressed
+	}
+
+	for _, g := range visible {
+		breakdown.Groups = append(breakdown.Groups, *g)
+	}
+}
@@ -0,0 +1,100 @@
+package analiticssrv
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Abraxas-365/opd/internal/analitics"
+)
+
+func TestAgeGroup(t *testing.T) {
+	buckets := []int{18, 25, 35, 45}
+	tests := []struct {
+		age  int
+		want string
+	}{
+		{1, "under 18"},
+		{17, "under 18"},
+		{18, "18-24"},
+		{24, "18-24"},
+		{25, "25-34"},
+		{44, "35-44"},
+		{45, "45+"},
+		{99, "45+"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := ageGroup(tt.age, buckets); got != tt.want {
+				t.Errorf("ageGroup(%d) = %q, want %q", tt.age, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuppress(t *testing.T) {
+	tests := []struct {
+		name             string
+		groups           []analitics.DemographicGroup
+		wantGroups       []string
+		wantSuppressed   *analitics.DemographicGroup
+		wantSuppressedNo int
+	}{
+		{
+			name:       "all groups large enough",
+			groups:     []analitics.DemographicGroup{{Group: "a", ChatUsers: 5, Interactions: 9}, {Group: "b", ChatUsers: 7, Interactions: 10}},
+			wantGroups: []string{"a", "b"},
+		},
+		{
+			name:             "small groups reaching the minimum together",
+			groups:           []analitics.DemographicGroup{{Group: "a", ChatUsers: 10, Interactions: 20}, {Group: "b", ChatUsers: 3, Interactions: 4}, {Group: "c", ChatUsers: 2, Interactions: 3}},
+			wantGroups:       []string{"a"},
+			wantSuppressed:   &analitics.DemographicGroup{Group: "suppressed", ChatUsers: 5, Interactions: 7},
+			wantSuppressedNo: 2,
+		},
+		{
+			name:             "a single small group takes the smallest other group with it",
+			groups:           []analitics.DemographicGroup{{Group: "a", ChatUsers: 10, Interactions: 20}, {Group: "b", ChatUsers: 6, Interactions: 8}, {Group: "c", ChatUsers: 1, Interactions: 1}},
+			wantGroups:       []string{"a"},
+			wantSuppressed:   &analitics.DemographicGroup{Group: "suppressed", ChatUsers: 7, Interactions: 9},
+			wantSuppressedNo: 2,
+		},
+		{
+			name:             "everything suppressed",
+			groups:           []analitics.DemographicGroup{{Group: "a", ChatUsers: 1, Interactions: 1}, {Group: "b", ChatUsers: 2, Interactions: 2}},
+			wantSuppressed:   &analitics.DemographicGroup{Group: "suppressed", ChatUsers: 3, Interactions: 3},
+			wantSuppressedNo: 2,
+		},
+		{
+			name:             "small group with only large groups left",
+			groups:           []analitics.DemographicGroup{{Group: "a", ChatUsers: 8, Interactions: 8}, {Group: "b", ChatUsers: 6, Interactions: 6}, {Group: "c", ChatUsers: 4, Interactions: 4}},
+			wantGroups:       []string{"a"},
+			wantSuppressed:   &analitics.DemographicGroup{Group: "suppressed", ChatUsers: 10, Interactions: 10},
+			wantSuppressedNo: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown := &analitics.DemographicBreakdown{MinGroupSize: 5}
+			var groups []*analitics.DemographicGroup
+			for i := range tt.groups {
+				groups = append(groups, &tt.groups[i])
+			}
+
+			suppress(breakdown, groups)
+
+			var names []string
+			for _, g := range breakdown.Groups {
+				names = append(names, g.Group)
+			}
+			if !reflect.DeepEqual(names, tt.wantGroups) {
+				t.Errorf("groups = %v, want %v", names, tt.wantGroups)
+			}
+			if !reflect.DeepEqual(breakdown.Suppressed, tt.wantSuppressed) {
+				t.Errorf("suppressed = %+v, want %+v", breakdown.Suppressed, tt.wantSuppressed)
+			}
+			if breakdown.SuppressedGroups != tt.wantSuppressedNo {
+				t.Errorf("suppressed groups = %d, want %d", breakdown.SuppressedGroups, tt.wantSuppressedNo)
+			}
+		})
+	}
+}
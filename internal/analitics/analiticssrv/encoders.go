@@ -0,0 +1,409 @@
+package analiticssrv
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/analitics"
+	"github.com/parquet-go/parquet-go"
+	"github.com/xuri/excelize/v2"
+)
+
+// parquetRowGroupSize bounds how many rows a Parquet writer buffers before flushing a row group
+const parquetRowGroupSize = 50000
+
+// exportTable is an entity of the export, written as a file of the archive or a sheet of the workbook
+type exportTable struct {
+	name    string
+	columns []string
+	schema  *parquet.Schema
+}
+
+var (
+	chatUsersTable = exportTable{
+		name:    "chat_users",
+		columns: []string{"id", "age", "gender", "occupation", "location", "created_at"},
+		schema:  parquet.SchemaOf(analitics.ExportChatUser{}),
+	}
+	interactionsTable = exportTable{
+		name:    "interactions",
+		columns: []string{"id", "user_chat_id", "question", "answer_status", "citations", "cited_files", "faq_id", "cached", "helpful", "created_at"},
+		schema:  parquet.SchemaOf(analitics.ExportInteraction{}),
+	}
+	filesTable = exportTable{
+		name:    "files",
+		columns: []string{"id", "filename", "s3_key", "user_id", "user_email", "created_at"},
+		schema:  parquet.SchemaOf(analitics.ExportFile{}),
+	}
+)
+
+// exportEncoder writes the tables of an export in one format
+type exportEncoder interface {
+	// table starts the table of an entity and returns the name of the file or sheet it is written to
+	table(t exportTable) (string, error)
+	// row writes an analitics.ExportChatUser, ExportInteraction or ExportFile to the current table
+	row(v any) error
+	// close ends the current table, writes the manifest and finishes the export
+	close(manifest analitics.ExportManifest) error
+}
+
+// validFormat reports whether format is one of the export formats
+func validFormat(format string) bool {
+	switch format {
+	case analitics.ExportCSV, analitics.ExportNDJSON, analitics.ExportXLSX, analitics.ExportParquet:
+		return true
+	}
+	return false
+}
+
+// exportFileType returns the extension and content type of the file an export format produces
+func exportFileType(format string) (string, string) {
+	if format == analitics.ExportXLSX {
+		return ".xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return ".zip", "application/zip"
+}
+
+func newExportEncoder(format string, w io.Writer) exportEncoder {
+	switch format {
+	case analitics.ExportNDJSON:
+		return &ndjsonEncoder{archive: zip.NewWriter(w)}
+	case analitics.ExportXLSX:
+		return &xlsxEncoder{file: excelize.NewFile(), w: w}
+	case analitics.ExportParquet:
+		return &parquetEncoder{archive: zip.NewWriter(w)}
+	default:
+		return &csvEncoder{archive: zip.NewWriter(w)}
+	}
+}
+
+// csvEncoder writes a CSV with a single header row per table
+type csvEncoder struct {
+	archive *zip.Writer
+	writer  *csv.Writer
+}
+
+func (e *csvEncoder) table(t exportTable) (string, error) {
+	if err := e.flush(); err != nil {
+		return "", err
+	}
+
+	name := t.name + ".csv"
+	file, err := e.archive.Create(name)
+	if err != nil {
+		return "", fmt.Errorf("error writing %s: %w", name, err)
+	}
+	e.writer = csv.NewWriter(file)
+	return name, e.writer.Write(t.columns)
+}
+
+func (e *csvEncoder) row(v any) error {
+	return e.writer.Write(escapeFormulas(csvRecord(v)))
+}
+
+func (e *csvEncoder) flush() error {
+	if e.writer == nil {
+		return nil
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvEncoder) close(manifest analitics.ExportManifest) error {
+	if err := e.flush(); err != nil {
+		return err
+	}
+	return closeArchive(e.archive, manifest)
+}
+
+// ndjsonEncoder writes a JSON object per line and table
+type ndjsonEncoder struct {
+	archive *zip.Writer
+	encoder *json.Encoder
+}
+
+func (e *ndjsonEncoder) table(t exportTable) (string, error) {
+	name := t.name + ".ndjson"
+	file, err := e.archive.Create(name)
+	if err != nil {
+		return "", fmt.Errorf("error writing %s: %w", name, err)
+	}
+	e.encoder = json.NewEncoder(file)
+	return name, nil
+}
+
+func (e *ndjsonEncoder) row(v any) error {
+	return e.encoder.Encode(v)
+}
+
+func (e *ndjsonEncoder) close(manifest analitics.ExportManifest) error {
+	return closeArchive(e.archive, manifest)
+}
+
+// parquetEncoder writes a Parquet file with the typed columns of the analitics export types per table
+type parquetEncoder struct {
+	archive *zip.Writer
+	writer  *parquet.Writer
+}
+
+func (e *parquetEncoder) table(t exportTable) (string, error) {
+	if err := e.flush(); err != nil {
+		return "", err
+	}
+
+	name := t.name + ".parquet"
+	file, err := e.archive.Create(name)
+	if err != nil {
+		return "", fmt.Errorf("error writing %s: %w", name, err)
+	}
+	e.writer = parquet.NewWriter(file, t.schema, parquet.MaxRowsPerRowGroup(parquetRowGroupSize))
+	return name, nil
+}
+
+func (e *parquetEncoder) row(v any) error {
+	return e.writer.Write(v)
+}
+
+func (e *parquetEncoder) flush() error {
+	if e.writer == nil {
+		return nil
+	}
+	return e.writer.Close()
+}
+
+func (e *parquetEncoder) close(manifest analitics.ExportManifest) error {
+	if err := e.flush(); err != nil {
+		return err
+	}
+	return closeArchive(e.archive, manifest)
+}
+
+// xlsxEncoder writes a workbook with a sheet per table and a manifest sheet
+type xlsxEncoder struct {
+	file   *excelize.File
+	w      io.Writer
+	stream *excelize.StreamWriter
+	sheets int
+	rows   int
+}
+
+func (e *xlsxEncoder) table(t exportTable) (string, error) {
+	if err := e.flush(); err != nil {
+		return "", err
+	}
+
+	// The new workbook comes with an empty sheet, the first table takes it
+	var err error
+	if e.sheets == 0 {
+		err = e.file.SetSheetName(e.file.GetSheetName(0), t.name)
+	} else {
+		_, err = e.file.NewSheet(t.name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error writing sheet %s: %w", t.name, err)
+	}
+	e.sheets++
+
+	e.stream, err = e.file.NewStreamWriter(t.name)
+	if err != nil {
+		return "", fmt.Errorf("error writing sheet %s: %w", t.name, err)
+	}
+
+	header := make([]interface{}, len(t.columns))
+	for i, column := range t.columns {
+		header[i] = column
+	}
+	e.rows = 1
+	return t.name, e.stream.SetRow("A1", header)
+}
+
+func (e *xlsxEncoder) row(v any) error {
+	e.rows++
+	if e.rows > excelize.TotalRows {
+		return fmt.Errorf("more than %d rows do not fit in an XLSX sheet, export as csv or parquet", excelize.TotalRows-1)
+	}
+
+	cell, err := excelize.CoordinatesToCellName(1, e.rows)
+	if err != nil {
+		return err
+	}
+	return e.stream.SetRow(cell, escapeFormulaCells(xlsxRecord(v)))
+}
+
+func (e *xlsxEncoder) flush() error {
+	if e.stream == nil {
+		return nil
+	}
+	return e.stream.Flush()
+}
+
+func (e *xlsxEncoder) close(manifest analitics.ExportManifest) error {
+	defer e.file.Close()
+
+	if err := e.flush(); err != nil {
+		return err
+	}
+
+	const sheet = "manifest"
+	if _, err := e.file.NewSheet(sheet); err != nil {
+		return fmt.Errorf("error writing export manifest: %w", err)
+	}
+	rows := [][]interface{}{
+		{"generated_at", manifest.GeneratedAt},
+		{"start_date", optionalTime(manifest.StartDate)},
+		{"end_date", optionalTime(manifest.EndDate)},
+		{"time_zone", manifest.TimeZone},
+		{},
+		{"sheet", "rows"},
+	}
+	for _, f := range manifest.Files {
+		rows = append(rows, []interface{}{f.Name, f.Rows})
+	}
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		if err := e.file.SetSheetRow(sheet, cell, &row); err != nil {
+			return fmt.Errorf("error writing export manifest: %w", err)
+		}
+	}
+
+	if err := e.file.Write(e.w); err != nil {
+		return fmt.Errorf("error writing export workbook: %w", err)
+	}
+	return nil
+}
+
+// closeArchive writes the manifest and finishes a ZIP export
+func closeArchive(archive *zip.Writer, manifest analitics.ExportManifest) error {
+	file, err := archive.Create("manifest.json")
+	if err != nil {
+		return fmt.Errorf("error writing export manifest: %w", err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("error writing export manifest: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("error writing export archive: %w", err)
+	}
+	return nil
+}
+
+func csvRecord(v any) []string {
+	switch row := v.(type) {
+	case analitics.ExportChatUser:
+		age := ""
+		if row.Age != nil {
+			age = strconv.Itoa(*row.Age)
+		}
+		return []string{row.ID, age, row.Gender, row.Occupation, row.Location, formatTimestamp(row.CreatedAt)}
+	case analitics.ExportInteraction:
+		faqID := ""
+		if row.FAQID != nil {
+			faqID = strconv.Itoa(*row.FAQID)
+		}
+		helpful := ""
+		if row.Helpful != nil {
+			helpful = strconv.FormatBool(*row.Helpful)
+		}
+		return []string{
+			strconv.Itoa(row.ID),
+			row.UserChatID,
+			row.Question,
+			row.AnswerStatus,
+			strings.Join(row.Citations, listSeparator),
+			strings.Join(row.CitedFiles, listSeparator),
+			faqID,
+			strconv.FormatBool(row.Cached),
+			helpful,
+			formatTimestamp(row.CreatedAt),
+		}
+	case analitics.ExportFile:
+		return []string{strconv.Itoa(row.ID), row.Filename, row.S3Key, row.UserID, row.UserEmail, formatTimestamp(row.CreatedAt)}
+	}
+	return nil
+}
+
+// xlsxRecord keeps numbers, booleans and dates typed, empty values are left as empty cells
+func xlsxRecord(v any) []interface{} {
+	switch row := v.(type) {
+	case analitics.ExportChatUser:
+		var age interface{}
+		if row.Age != nil {
+			age = *row.Age
+		}
+		return []interface{}{row.ID, age, row.Gender, row.Occupation, row.Location, row.CreatedAt.UTC()}
+	case analitics.ExportInteraction:
+		var faqID, helpful interface{}
+		if row.FAQID != nil {
+			faqID = *row.FAQID
+		}
+		if row.Helpful != nil {
+			helpful = *row.Helpful
+		}
+		return []interface{}{
+			row.ID,
+			row.UserChatID,
+			row.Question,
+			row.AnswerStatus,
+			strings.Join(row.Citations, listSeparator),
+			strings.Join(row.CitedFiles, listSeparator),
+			faqID,
+			row.Cached,
+			helpful,
+			row.CreatedAt.UTC(),
+		}
+	case analitics.ExportFile:
+		return []interface{}{row.ID, row.Filename, row.S3Key, row.UserID, row.UserEmail, row.CreatedAt.UTC()}
+	}
+	return nil
+}
+
+// escapeFormulas prefixes values that spreadsheets would run as formulas with a quote.
+// Questions, locations and file names come from chat users and uploads.
+func escapeFormulas(record []string) []string {
+	for i, value := range record {
+		record[i] = escapeFormula(value)
+	}
+	return record
+}
+
+// escapeFormulaCells escapes the text cells of an XLSX row, they are written as strings
+// but become formulas when a cell is edited or the sheet is saved as CSV
+func escapeFormulaCells(record []interface{}) []interface{} {
+	for i, value := range record {
+		if text, ok := value.(string); ok {
+			record[i] = escapeFormula(text)
+		}
+	}
+	return record
+}
+
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func optionalTime(t *time.Time) interface{} {
+	if t == nil {
+		return "complete database"
+	}
+	return t.UTC()
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
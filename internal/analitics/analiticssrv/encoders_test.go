@@ -0,0 +1,25 @@
+package analiticssrv
+
+import "testing"
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1 555 0100", "'+1 555 0100"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"how much is 1+1?", "how much is 1+1?"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := escapeFormula(tt.value); got != tt.want {
+				t.Errorf("escapeFormula(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
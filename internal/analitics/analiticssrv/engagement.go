@@ -0,0 +1,96 @@
+package analiticssrv
+
+import (
+	"context"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/analitics"
+)
+
+// GetActiveUsers returns the rolling DAU, WAU and MAU of every day of loc in the date range and their stickiness
+func (s Service) GetActiveUsers(ctx context.Context, startDate, endDate time.Time, loc *time.Location) (*analitics.ActiveUsersReport, error) {
+	loc = s.location(loc)
+	start, end := dayRange(startDate, endDate, loc)
+
+	series, err := s.repo.GetActiveUsers(ctx, start, end, loc.String())
+	if err != nil {
+		return nil, err
+	}
+
+	report := &analitics.ActiveUsersReport{Series: series}
+	if len(series) == 0 {
+		return report, nil
+	}
+
+	var dau, wau, mau int
+	for i := range series {
+		series[i].Date = inDay(series[i].Date, loc)
+		series[i].Stickiness = ratio(series[i].DAU, series[i].MAU)
+		dau += series[i].DAU
+		wau += series[i].WAU
+		mau += series[i].MAU
+	}
+	days := float64(len(series))
+	report.AverageDAU = float64(dau) / days
+	report.AverageWAU = float64(wau) / days
+	report.AverageMAU = float64(mau) / days
+	report.Stickiness = ratio(dau, mau)
+
+	return report, nil
+}
+
+// GetRetentionCohorts groups the chat users whose first interaction was in the date range by the week of loc
+// of that interaction, and returns the share of every cohort active in each week since, up to the current one
+func (s Service) GetRetentionCohorts(ctx context.Context, startDate, endDate time.Time, loc *time.Location) ([]analitics.RetentionCohort, error) {
+	loc = s.location(loc)
+	start, end := dayRange(startDate, endDate, loc)
+	start = bucketStart(start, analitics.GranularityWeek, loc)
+
+	activity, err := s.repo.GetCohortActivity(ctx, start, end, loc.String())
+	if err != nil {
+		return nil, err
+	}
+
+	currentWeek := bucketStart(time.Now(), analitics.GranularityWeek, loc)
+	cohorts := []analitics.RetentionCohort{}
+	for _, a := range activity {
+		cohort := inDay(a.Cohort, loc)
+		if len(cohorts) == 0 || !cohorts[len(cohorts)-1].Cohort.Equal(cohort) {
+			// Every week from the cohort week to the current one, weeks nobody came back included
+			weeks := int(currentWeek.Sub(cohort).Round(24*time.Hour).Hours()/(24*7)) + 1
+			retention := make([]analitics.RetentionWeek, weeks)
+			for week := range retention {
+				retention[week].Week = week
+			}
+			cohorts = append(cohorts, analitics.RetentionCohort{Cohort: cohort, Retention: retention})
+		}
+
+		c := &cohorts[len(cohorts)-1]
+		if a.Week == 0 {
+			c.Users = a.Users
+		}
+		if a.Week < len(c.Retention) {
+			c.Retention[a.Week].Users = a.Users
+		}
+	}
+
+	for i := range cohorts {
+		for week := range cohorts[i].Retention {
+			cohorts[i].Retention[week].Rate = ratio(cohorts[i].Retention[week].Users, cohorts[i].Users)
+		}
+	}
+
+	return cohorts, nil
+}
+
+// inDay returns the start in loc of the calendar day of a Postgres date
+func inDay(date time.Time, loc *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+}
+
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
@@ -0,0 +1,258 @@
+package analiticssrv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/analitics"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+const (
+	// exportURLTTL is how long the download link of an export works
+	exportURLTTL = 24 * time.Hour
+	// listSeparator joins the citations and cited files of an interaction inside a CSV or XLSX cell
+	listSeparator = "|"
+	// progressInterval is how many rows are written between progress updates of an export job
+	progressInterval = 5000
+)
+
+// StartExport creates a job exporting the chat users, interactions and files created in the date range
+// in format and builds it in the background, poll GetExport for its progress. Without a date range the
+// complete database is exported. The requester is notified when the download link is ready.
+func (s Service) StartExport(ctx context.Context, startDate, endDate *time.Time, loc *time.Location, format string, userID string) (*analitics.ExportJob, error) {
+	if format == "" {
+		format = analitics.ExportCSV
+	}
+	if !validFormat(format) {
+		return nil, errors.ErrBadRequest("format must be csv, ndjson, xlsx or parquet")
+	}
+
+	if startDate == nil || endDate == nil {
+		startDate, endDate = nil, nil
+	}
+	loc = s.location(loc)
+	startDate, endDate = wholeDays(startDate, endDate, loc)
+
+	total, err := s.repo.CountExportRows(ctx, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.repo.CreateExportJob(ctx, analitics.ExportJob{
+		Format:      format,
+		TimeZone:    loc.String(),
+		RequestedBy: &userID,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		TotalRows:   total,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go s.runExport(*job)
+
+	return s.withDownload(job)
+}
+
+// GetExport returns an export job with its progress, and the download link once it is completed
+func (s Service) GetExport(ctx context.Context, id int) (*analitics.ExportJob, error) {
+	job, err := s.repo.GetExportJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.withDownload(job)
+}
+
+func (s Service) withDownload(job *analitics.ExportJob) (*analitics.ExportJob, error) {
+	switch {
+	case job.Status == analitics.ExportCompleted:
+		job.Progress = 1
+	case job.TotalRows > 0:
+		job.Progress = float64(job.ExportedRows) / float64(job.TotalRows)
+	}
+
+	if job.Status == analitics.ExportCompleted && job.S3Key != nil {
+		url, err := s.s3Client.GeneratePresignedGetURL(*job.S3Key, exportURLTTL)
+		if err != nil {
+			return nil, errors.ErrServiceUnavailable("failed to generate presigned URL: " + err.Error())
+		}
+		job.DownloadURL = url
+	}
+	return job, nil
+}
+
+// runExport streams the export through a pipe from the Postgres cursors into a multipart upload,
+// so only a batch of rows and a part of the archive are in memory at any time
+func (s Service) runExport(job analitics.ExportJob) {
+	ctx := context.Background()
+	extension, contentType := exportFileType(job.Format)
+	key := exportKey(job, extension)
+
+	reader, writer := io.Pipe()
+	var rows int
+	var writeErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rows, writeErr = s.writeExport(ctx, job, writer)
+		writer.CloseWithError(writeErr)
+	}()
+
+	uploadErr := s.uploader.Upload(ctx, key, contentType, reader)
+	// Unblocks the writer when the upload stopped before reading everything
+	reader.CloseWithError(uploadErr)
+	<-done
+
+	err := uploadErr
+	if err == nil {
+		err = writeErr
+	}
+	if err != nil {
+		log.Printf("export %d failed: %v", job.ID, err)
+		if err := s.repo.FailExportJob(ctx, job.ID, err.Error()); err != nil {
+			log.Printf("failed to record export %d failure: %v", job.ID, err)
+		}
+		return
+	}
+
+	completed, err := s.repo.CompleteExportJob(ctx, job.ID, key, rows)
+	if err != nil {
+		log.Printf("failed to complete export %d: %v", job.ID, err)
+		return
+	}
+
+	if err := s.notifyExportReady(ctx, completed); err != nil {
+		log.Printf("failed to notify export %d: %v", job.ID, err)
+	}
+}
+
+func (s Service) notifyExportReady(ctx context.Context, job *analitics.ExportJob) error {
+	if s.notifier == nil || job.RequestedBy == nil {
+		return nil
+	}
+
+	requester, err := s.userService.GetUser(ctx, *job.RequestedBy)
+	if err != nil {
+		return err
+	}
+
+	job, err = s.withDownload(job)
+	if err != nil {
+		return err
+	}
+	return s.notifier.NotifyExportReady(ctx, requester.Email, job.DownloadURL)
+}
+
+// writeExport writes the export in the job format to w and returns the rows written
+func (s Service) writeExport(ctx context.Context, job analitics.ExportJob, w io.Writer) (int, error) {
+	e := exportWriter{
+		encoder: newExportEncoder(job.Format, w),
+		manifest: analitics.ExportManifest{
+			GeneratedAt: time.Now().UTC(),
+			StartDate:   inZone(job.StartDate, job.TimeZone),
+			EndDate:     inZone(job.EndDate, job.TimeZone),
+			TimeZone:    job.TimeZone,
+		},
+		progress: func(rows int) {
+			if err := s.repo.UpdateExportProgress(ctx, job.ID, rows); err != nil {
+				log.Printf("failed to update export %d progress: %v", job.ID, err)
+			}
+		},
+	}
+
+	err := e.table(chatUsersTable, func(write func(any) error) error {
+		return s.repo.GetAllChatUsers(ctx, job.StartDate, job.EndDate, func(chatUser analitics.ExportChatUser) error {
+			chatUser.Gender = s.piiService.Redact(chatUser.Gender)
+			chatUser.Occupation = s.piiService.Redact(chatUser.Occupation)
+			chatUser.Location = s.piiService.Redact(chatUser.Location)
+			return write(chatUser)
+		})
+	})
+	if err != nil {
+		return e.rows, err
+	}
+
+	err = e.table(interactionsTable, func(write func(any) error) error {
+		return s.repo.GetAllInteractionsData(ctx, job.StartDate, job.EndDate, func(interaction analitics.ExportInteraction) error {
+			return write(interaction)
+		})
+	})
+	if err != nil {
+		return e.rows, err
+	}
+
+	err = e.table(filesTable, func(write func(any) error) error {
+		return s.repo.GetAllFiles(ctx, job.StartDate, job.EndDate, func(file analitics.ExportFile) error {
+			file.UserEmail = s.piiService.Redact(file.UserEmail)
+			return write(file)
+		})
+	})
+	if err != nil {
+		return e.rows, err
+	}
+
+	return e.rows, e.encoder.close(e.manifest)
+}
+
+// exportWriter writes the tables of an export, counting rows for the manifest and the job progress
+type exportWriter struct {
+	encoder  exportEncoder
+	manifest analitics.ExportManifest
+	rows     int
+	progress func(rows int)
+}
+
+// table writes a table of the export, stream calls write for every row
+func (e *exportWriter) table(t exportTable, stream func(write func(any) error) error) error {
+	name, err := e.encoder.table(t)
+	if err != nil {
+		return err
+	}
+
+	rows := 0
+	err = stream(func(v any) error {
+		if err := e.encoder.row(v); err != nil {
+			return fmt.Errorf("error writing %s: %w", name, err)
+		}
+		rows++
+		e.rows++
+		if e.rows%progressInterval == 0 {
+			e.progress(e.rows)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	e.manifest.Files = append(e.manifest.Files, analitics.ExportedFileInfo{Name: name, Rows: rows})
+	return nil
+}
+
+// exportKey names the export after its date range, e.g. exports/database_export_2024-01-01_to_2024-01-31_42.zip
+func exportKey(job analitics.ExportJob, extension string) string {
+	prefix := "complete"
+	if job.StartDate != nil && job.EndDate != nil {
+		prefix = inZone(job.StartDate, job.TimeZone).Format("2006-01-02") + "_to_" + inZone(job.EndDate, job.TimeZone).Format("2006-01-02")
+	}
+	return "exports/database_export_" + prefix + "_" + strconv.Itoa(job.ID) + extension
+}
+
+// inZone shows t in the IANA time zone of an export job, in UTC if the zone is unknown
+func inZone(t *time.Time, zone string) *time.Time {
+	if t == nil {
+		return nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		loc = time.UTC
+	}
+	in := t.In(loc)
+	return &in
+}
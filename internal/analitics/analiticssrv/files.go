@@ -0,0 +1,104 @@
+package analiticssrv
+
+import (
+	"context"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/analitics"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+const (
+	// DefaultTopFiles is how many files the citation reports rank when no limit is given
+	DefaultTopFiles = 10
+	// maxTopFiles bounds the limit of the citation reports
+	maxTopFiles = 100
+)
+
+// GetTopCitedFiles ranks the files cited in the date range by how many times answers cited them
+func (s Service) GetTopCitedFiles(ctx context.Context, startDate, endDate *time.Time, loc *time.Location, limit int) ([]analitics.FileCitations, error) {
+	limit, err := topFilesLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate, endDate = wholeDays(startDate, endDate, s.location(loc))
+	return s.repo.GetTopCitedFiles(ctx, startDate, endDate, nil, limit)
+}
+
+// GetFileCitationTrends returns the citations per hour, day, week or month of loc of the files with the given
+// S3 keys, or of the limit most cited files of the date range when no key is given. Files not cited in the
+// range are left out.
+func (s Service) GetFileCitationTrends(ctx context.Context, startDate, endDate time.Time, loc *time.Location, granularity string, s3Keys []string, limit int) ([]analitics.FileCitationTrend, error) {
+	if err := validGranularity(granularity); err != nil {
+		return nil, err
+	}
+	if len(s3Keys) > 0 {
+		limit = len(s3Keys)
+	}
+	limit, err := topFilesLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	loc = s.location(loc)
+	start, end := dayRange(startDate, endDate, loc)
+	series, err := buckets(start, end, granularity, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := s.repo.GetTopCitedFiles(ctx, &start, &end, s3Keys, limit)
+	if err != nil {
+		return nil, err
+	}
+	trends := []analitics.FileCitationTrend{}
+	if len(files) == 0 {
+		return trends, nil
+	}
+
+	keys := make([]string, len(files))
+	for i, f := range files {
+		keys[i] = f.S3Key
+	}
+	counts, err := s.repo.GetFileCitationCounts(ctx, keys, start, end, granularity, loc.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// Buckets are keyed by their Unix time, the same instant read from Postgres has another location
+	byBucket := make(map[string]map[int64]int, len(files))
+	for _, c := range counts {
+		if byBucket[c.S3Key] == nil {
+			byBucket[c.S3Key] = make(map[int64]int)
+		}
+		byBucket[c.S3Key][c.Date.Unix()] += c.Count
+	}
+
+	for _, f := range files {
+		trend := analitics.FileCitationTrend{FileCitations: f}
+		for _, bucket := range series {
+			trend.Series = append(trend.Series, analitics.DailyStatistic{Date: bucket, Count: byBucket[f.S3Key][bucket.Unix()]})
+		}
+		trends = append(trends, trend)
+	}
+
+	return trends, nil
+}
+
+// GetUncitedFiles lists the files uploaded by the end of the date range that no answer in the range cited.
+// Without a date range it lists the files that were never cited.
+func (s Service) GetUncitedFiles(ctx context.Context, startDate, endDate *time.Time, loc *time.Location) ([]analitics.UncitedFile, error) {
+	startDate, endDate = wholeDays(startDate, endDate, s.location(loc))
+	return s.repo.GetUncitedFiles(ctx, startDate, endDate)
+}
+
+func topFilesLimit(limit int) (int, error) {
+	if limit == 0 {
+		return DefaultTopFiles, nil
+	}
+	if limit < 0 || limit > maxTopFiles {
+		return 0, errors.ErrBadRequest("limit must be between 1 and 100")
+	}
+	return limit, nil
+}
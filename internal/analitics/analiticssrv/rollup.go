@@ -0,0 +1,43 @@
+package analiticssrv
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// rollupDelay is how long after an hour ends it is rolled up, so interactions still being written land in it
+const rollupDelay = 5 * time.Minute
+
+// RollUp keeps the analytics rollups up to date, rolling up the hours that ended every interval until
+// ctx is done. Reports read the rollups for the rolled up hours and the interactions table after them.
+func (s Service) RollUp(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.rollUp(ctx); err != nil {
+			log.Printf("analytics: failed to roll up interactions: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// rollUp rolls up every hour that ended rollupDelay ago, a chunk at a time, catching up after downtime
+func (s Service) rollUp(ctx context.Context) error {
+	until := time.Now().Add(-rollupDelay).Truncate(time.Hour)
+	for {
+		rolledUpTo, err := s.repo.RollUp(ctx, until)
+		if err != nil {
+			return err
+		}
+		if !rolledUpTo.Before(until) || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
@@ -1,169 +1,216 @@
 package analiticssrv
 
 import (
-	"bytes"
 	"context"
-	"encoding/csv"
+	"slices"
+	"sort"
 	"time"
 
 	"github.com/Abraxas-365/opd/internal/analitics"
-	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/Abraxas-365/opd/internal/interaction"
+	"github.com/Abraxas-365/opd/internal/pii/piisrv"
+	"github.com/Abraxas-365/opd/internal/user/usersrv"
+	"github.com/Abraxas-365/opd/pkg/textsim"
 	"github.com/Abraxas-365/toolkit/pkg/s3client"
 )
 
 type Service struct {
-	repo     analitics.Repository
-	s3Client s3client.Client
+	repo         analitics.Repository
+	s3Client     s3client.Client
+	uploader     analitics.Uploader
+	piiService   *piisrv.Service
+	userService  *usersrv.Service
+	notifier     analitics.Notifier
+	minGroupSize int
+	ageBuckets   []int
+	timeZone     *time.Location
 }
 
-func NewService(repo analitics.Repository, s3Client s3client.Client) *Service {
+func NewService(repo analitics.Repository,
+	s3Client s3client.Client,
+	uploader analitics.Uploader,
+	piiService *piisrv.Service,
+	userService *usersrv.Service,
+	minGroupSize int,
+	ageBuckets []int,
+	timeZone *time.Location,
+) *Service {
+	if minGroupSize <= 0 {
+		minGroupSize = DefaultMinGroupSize
+	}
+	if len(ageBuckets) == 0 {
+		ageBuckets = DefaultAgeBuckets
+	}
+	if timeZone == nil {
+		timeZone = time.UTC
+	}
 	return &Service{
-		repo:     repo,
-		s3Client: s3Client,
+		repo:         repo,
+		s3Client:     s3Client,
+		uploader:     uploader,
+		piiService:   piiService,
+		userService:  userService,
+		minGroupSize: minGroupSize,
+		ageBuckets:   ageBuckets,
+		timeZone:     timeZone,
 	}
 }
 
-func (s Service) GetAllAnalitics(ctx context.Context, startDate *time.Time, endDate *time.Time) ([]analitics.Statistic, error) {
-	var allAnalitics []analitics.Statistic
+// RegisterNotifier sets how admins learn that their export is ready, without one they poll GetExport
+func (s *Service) RegisterNotifier(notifier analitics.Notifier) {
+	s.notifier = notifier
+}
 
-	interactions, err := s.repo.GetInteractions(ctx, startDate, endDate)
+// GetAllAnalitics returns the statistic cards of the dashboard for the date range, the last DefaultCardDays
+// days when none is given, each compared to the period of the same length just before. Every method taking
+// a date range reads its dates as calendar days in loc, the configured time zone when nil.
+func (s Service) GetAllAnalitics(ctx context.Context, startDate *time.Time, endDate *time.Time, loc *time.Location) ([]analitics.Statistic, error) {
+	loc = s.location(loc)
+	p, err := newCardPeriod(startDate, endDate, loc)
 	if err != nil {
 		return nil, err
 	}
-	allAnalitics = append(allAnalitics, *interactions)
 
-	data, err := s.repo.GetMostConsultedData(ctx, startDate, endDate)
+	interactions, err := s.interactionsCard(ctx, p)
 	if err != nil {
 		return nil, err
 	}
-	allAnalitics = append(allAnalitics, *data)
 
-	users, err := s.repo.GetTotalUsers(ctx, startDate, endDate)
+	data, err := s.mostConsultedCard(ctx, p)
 	if err != nil {
 		return nil, err
 	}
-	allAnalitics = append(allAnalitics, *users)
-
-	return allAnalitics, nil
 
-}
-
-func (s Service) GetDailyUsersInRange(ctx context.Context, startDate, endDate time.Time) ([]analitics.DailyStatistic, error) {
-	// Normalize times to start and end of day
-	start := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
-	end := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, time.UTC)
+	users, err := s.usersCard(ctx, p)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.repo.GetDailyUsers(ctx, start, end)
+	return []analitics.Statistic{*interactions, *data, *users}, nil
 }
 
-// GetDailyInteractionsInRange gets interactions per day for a specific date range
-func (s Service) GetDailyInteractionsInRange(ctx context.Context, startDate, endDate time.Time) ([]analitics.DailyStatistic, error) {
-	// Normalize times to start and end of day
-	start := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
-	end := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, time.UTC)
+// GetDailyUsersInRange gets new users per hour, day, week or month of loc for a specific date range
+func (s Service) GetDailyUsersInRange(ctx context.Context, startDate, endDate time.Time, loc *time.Location, granularity string) ([]analitics.DailyStatistic, error) {
+	if err := validGranularity(granularity); err != nil {
+		return nil, err
+	}
+	loc = s.location(loc)
+	start, end := dayRange(startDate, endDate, loc)
 
-	return s.repo.GetDailyInteractions(ctx, start, end)
+	stats, err := s.repo.GetDailyUsers(ctx, start, end, granularity, loc.String())
+	if err != nil {
+		return nil, err
+	}
+	return inLocation(stats, loc), nil
 }
 
-func (s Service) ExportDatabaseToCSV(ctx context.Context, startDate, endDate *time.Time) (string, error) {
-	var start, end time.Time
-	if startDate != nil && endDate != nil {
-		start = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
-		end = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, time.UTC)
+// GetDailyInteractionsInRange gets interactions per hour, day, week or month of loc for a specific date range
+func (s Service) GetDailyInteractionsInRange(ctx context.Context, startDate, endDate time.Time, loc *time.Location, granularity string) ([]analitics.DailyStatistic, error) {
+	if err := validGranularity(granularity); err != nil {
+		return nil, err
 	}
+	loc = s.location(loc)
+	start, end := dayRange(startDate, endDate, loc)
 
-	chatUsers, err := s.repo.GetAllChatUsers(ctx, startDate, endDate)
+	stats, err := s.repo.GetDailyInteractions(ctx, start, end, granularity, loc.String())
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return inLocation(stats, loc), nil
+}
 
-	interactions, err := s.repo.GetAllInteractionsData(ctx, startDate, endDate)
-	if err != nil {
-		return "", err
+// inLocation shows the bucket starts in loc, so a day reads 2024-01-02T00:00:00-05:00
+func inLocation(stats []analitics.DailyStatistic, loc *time.Location) []analitics.DailyStatistic {
+	for i := range stats {
+		stats[i].Date = stats[i].Date.In(loc)
 	}
+	return stats
+}
 
-	files, err := s.repo.GetAllFiles(ctx, startDate, endDate)
-	if err != nil {
-		return "", err
-	}
+// GetHandoffStatistics counts escalations to human agents and what agents did with them
+func (s Service) GetHandoffStatistics(ctx context.Context, startDate, endDate *time.Time, loc *time.Location) (*analitics.HandoffStatistics, error) {
+	startDate, endDate = wholeDays(startDate, endDate, s.location(loc))
 
-	var buffer bytes.Buffer
-	writer := csv.NewWriter(&buffer)
+	return s.repo.GetHandoffStatistics(ctx, startDate, endDate)
+}
 
-	// Write date range information
-	if startDate != nil && endDate != nil {
-		writer.Write([]string{"Date Range:", "From " + start.Format("2006-01-02") + " To " + end.Format("2006-01-02")})
-	} else {
-		writer.Write([]string{"Date Range:", "Complete Database"})
-	}
-	writer.Write([]string{""})
+// GetFAQStatistics shows how often questions are answered by curated FAQs instead of the model
+func (s Service) GetFAQStatistics(ctx context.Context, startDate, endDate *time.Time, loc *time.Location) (*analitics.FAQStatistics, error) {
+	startDate, endDate = wholeDays(startDate, endDate, s.location(loc))
 
-	// Write Chat Users
-	writer.Write([]string{"=== Chat Users ===", "ID", "Age", "Gender", "Occupation", "Location"})
-	for _, chatUser := range chatUsers {
-		id := ""
-		if chatUser.ID != nil {
-			id = *chatUser.ID
-		}
-		writer.Write([]string{
-			id,
-			string(rune(chatUser.Age)),
-			chatUser.Gender,
-			chatUser.Ocupation,
-			chatUser.Location,
-		})
-	}
+	return s.repo.GetFAQStatistics(ctx, startDate, endDate)
+}
 
-	writer.Write([]string{""})
+// GetSuggestionStatistics shows how often chat users ask one of the suggested follow-ups
+func (s Service) GetSuggestionStatistics(ctx context.Context, startDate, endDate *time.Time, loc *time.Location) (*analitics.SuggestionStatistics, error) {
+	startDate, endDate = wholeDays(startDate, endDate, s.location(loc))
 
-	// Write Interactions
-	writer.Write([]string{"=== Interactions ===", "ID", "User Chat ID", "Context Interaction"})
-	for _, interaction := range interactions {
-		writer.Write([]string{
-			string(rune(interaction.ID)),
-			interaction.UserChatID,
-			string(rune(len(interaction.ContextInteraction))), // Consider how you want to format this array
-		})
-	}
+	return s.repo.GetSuggestionStatistics(ctx, startDate, endDate)
+}
 
-	writer.Write([]string{""})
+// GetExperimentStatistics compares the feedback, not found rate and latency of the variants of an experiment
+func (s Service) GetExperimentStatistics(ctx context.Context, experimentID int) ([]analitics.VariantStatistics, error) {
+	return s.repo.GetExperimentStatistics(ctx, experimentID)
+}
 
-	// Write Files
-	writer.Write([]string{"=== Files ===", "ID", "Filename", "S3 Key", "User ID", "User Email"})
-	for _, file := range files {
-		writer.Write([]string{
-			string(rune(file.ID)),
-			file.Filename,
-			file.S3Key,
-			file.UserID,
-			file.UserEmail,
-		})
-	}
+// contentGapSimilarity is how alike two unanswered questions must be to count as the same gap
+const contentGapSimilarity = 0.5
 
-	writer.Flush()
+// contentGapExamples is how many distinct phrasings are shown per gap
+const contentGapExamples = 5
 
-	if err := writer.Error(); err != nil {
-		return "", errors.ErrUnexpected("error writing CSV: " + err.Error())
-	}
+// GetContentGaps groups similar questions the knowledge base could not fully answer, biggest
+// groups first, so editors know which documents to write next
+func (s Service) GetContentGaps(ctx context.Context, startDate, endDate *time.Time, loc *time.Location, limit int) (*analitics.ContentGapReport, error) {
+	startDate, endDate = wholeDays(startDate, endDate, s.location(loc))
 
-	// Generate filename
-	filenamePrefix := "complete"
-	if startDate != nil && endDate != nil {
-		filenamePrefix = start.Format("2006-01-02") + "_to_" + end.Format("2006-01-02")
+	counts, err := s.repo.GetAnswerStatusCounts(ctx, startDate, endDate)
+	if err != nil {
+		return nil, err
 	}
-	filename := "exports/database_export_" + filenamePrefix + "_" + time.Now().Format("150405") + ".csv"
 
-	// Upload to S3
-	err = s.s3Client.UploadCSV(ctx, filename, buffer.Bytes())
+	questions, err := s.repo.GetUnansweredQuestions(ctx, startDate, endDate)
 	if err != nil {
-		return "", errors.ErrServiceUnavailable("failed to upload to S3: " + err.Error())
+		return nil, err
 	}
 
-	// Generate presigned URL
-	presignedURL, err := s.s3Client.GeneratePresignedGetURL(filename, 24*time.Hour)
-	if err != nil {
-		return "", errors.ErrServiceUnavailable("failed to generate presigned URL: " + err.Error())
+	// Leader clustering: a question joins the first gap whose leading question is similar enough
+	gaps := []analitics.ContentGap{}
+	for _, q := range questions {
+		i := 0
+		for ; i < len(gaps); i++ {
+			if textsim.Similarity(q.Question, gaps[i].Question) >= contentGapSimilarity {
+				break
+			}
+		}
+		if i == len(gaps) {
+			gaps = append(gaps, analitics.ContentGap{Question: q.Question, LastAskedAt: q.CreatedAt})
+		}
+
+		gap := &gaps[i]
+		gap.Count++
+		if q.AnswerStatus == interaction.StatusNotFound {
+			gap.NotFound++
+		} else {
+			gap.Partial++
+		}
+		if q.CreatedAt.After(gap.LastAskedAt) {
+			gap.LastAskedAt = q.CreatedAt
+		}
+		if len(gap.Examples) < contentGapExamples && !slices.Contains(gap.Examples, q.Question) {
+			gap.Examples = append(gap.Examples, q.Question)
+		}
+	}
+
+	sort.SliceStable(gaps, func(i, j int) bool {
+		return gaps[i].Count > gaps[j].Count
+	})
+	if len(gaps) > limit {
+		gaps = gaps[:limit]
 	}
 
-	return presignedURL, nil
+	return &analitics.ContentGapReport{
+		AnswerStatusCounts: *counts,
+		Gaps:               gaps,
+	}, nil
 }
@@ -0,0 +1,90 @@
+package analiticssrv
+
+import (
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/analitics"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// maxBuckets bounds how many buckets a time series filled with empty buckets can have,
+// a year of hours fits but not a decade
+const maxBuckets = 24 * 370
+
+// location returns loc, or the configured time zone of the reports when nil
+func (s Service) location(loc *time.Location) *time.Location {
+	if loc != nil {
+		return loc
+	}
+	return s.timeZone
+}
+
+// wholeDays widens a date range to cover the start and end days completely in loc
+func wholeDays(startDate, endDate *time.Time, loc *time.Location) (*time.Time, *time.Time) {
+	if startDate == nil || endDate == nil {
+		return startDate, endDate
+	}
+	start, end := dayRange(*startDate, *endDate, loc)
+	return &start, &end
+}
+
+// dayRange returns the first and last instants of the calendar days of startDate and endDate in loc.
+// The dates are read as calendar dates, so a date parsed as UTC midnight still means that day in loc.
+func dayRange(startDate, endDate time.Time, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, loc)
+	return start, end
+}
+
+func validGranularity(granularity string) error {
+	switch granularity {
+	case analitics.GranularityHour, analitics.GranularityDay, analitics.GranularityWeek, analitics.GranularityMonth:
+		return nil
+	}
+	return errors.ErrBadRequest("granularity must be hour, day, week or month")
+}
+
+// bucketStart truncates t to the start of its bucket in loc, weeks start on Monday like Postgres date_trunc
+func bucketStart(t time.Time, granularity string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch granularity {
+	case analitics.GranularityHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case analitics.GranularityWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		// time.Sunday is 0
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case analitics.GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+// nextBucket returns the start of the bucket after the one starting at t. Days, weeks and months
+// move on the calendar of loc, so a day is 23 or 25 hours long when daylight saving time changes.
+func nextBucket(t time.Time, granularity string) time.Time {
+	switch granularity {
+	case analitics.GranularityHour:
+		return t.Add(time.Hour)
+	case analitics.GranularityWeek:
+		return t.AddDate(0, 0, 7)
+	case analitics.GranularityMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// buckets returns the starts of the buckets between start and end, or a bad request error
+// when there are more than maxBuckets
+func buckets(start, end time.Time, granularity string, loc *time.Location) ([]time.Time, error) {
+	var starts []time.Time
+	for t := bucketStart(start, granularity, loc); !t.After(end); t = nextBucket(t, granularity) {
+		if len(starts) == maxBuckets {
+			return nil, errors.ErrBadRequest("the date range has too many " + granularity + "s, use a coarser granularity")
+		}
+		starts = append(starts, t)
+	}
+	return starts, nil
+}
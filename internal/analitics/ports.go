@@ -2,28 +2,71 @@ package analitics
 
 import (
 	"context"
+	"io"
 	"time"
-
-	"github.com/Abraxas-365/opd/internal/chatuser"
-	"github.com/Abraxas-365/opd/internal/interaction"
-	"github.com/Abraxas-365/opd/internal/kb"
 )
 
 type Repository interface {
 	// GetInteractions returns statistics about interactions
 	GetInteractions(ctx context.Context, startDate *time.Time, endDate *time.Time) (*Statistic, error)
 
-	// GetMostConsultedData returns statistics about most consulted data
-	GetMostConsultedData(ctx context.Context, startDate *time.Time, endDate *time.Time) (*Statistic, error)
-
 	// GetTotalUsers returns statistics about total users
 	GetTotalUsers(ctx context.Context, startDate *time.Time, endDate *time.Time) (*Statistic, error)
 
-	GetDailyUsers(ctx context.Context, startDate, endDate time.Time) ([]DailyStatistic, error)
-	GetDailyInteractions(ctx context.Context, startDate, endDate time.Time) ([]DailyStatistic, error)
-	GetDailyActiveUsers(ctx context.Context, startDate, endDate time.Time, activeDays int) ([]DailyStatistic, error)
+	// GetDailyUsers and GetDailyInteractions count the new users and the interactions per Granularity bucket
+	// of the IANA timeZone, skipping empty buckets
+	GetDailyUsers(ctx context.Context, startDate, endDate time.Time, granularity string, timeZone string) ([]DailyStatistic, error)
+	GetDailyInteractions(ctx context.Context, startDate, endDate time.Time, granularity string, timeZone string) ([]DailyStatistic, error)
+	// GetActiveUsers returns the DAU, WAU and MAU of every day of timeZone in the range, days without activity included
+	GetActiveUsers(ctx context.Context, startDate, endDate time.Time, timeZone string) ([]ActiveUsers, error)
+	// GetCohortActivity counts the chat users of the weekly cohorts starting in the range active every week since,
+	// a cohort being the chat users whose first interaction was in that week of timeZone
+	GetCohortActivity(ctx context.Context, startDate, endDate time.Time, timeZone string) ([]CohortActivity, error)
+
+	GetHandoffStatistics(ctx context.Context, startDate, endDate *time.Time) (*HandoffStatistics, error)
+	GetFAQStatistics(ctx context.Context, startDate, endDate *time.Time) (*FAQStatistics, error)
+	GetSuggestionStatistics(ctx context.Context, startDate, endDate *time.Time) (*SuggestionStatistics, error)
+	// GetExperimentStatistics returns one row per variant of the experiment, including variants without interactions
+	GetExperimentStatistics(ctx context.Context, experimentID int) ([]VariantStatistics, error)
+	GetAnswerStatusCounts(ctx context.Context, startDate, endDate *time.Time) (*AnswerStatusCounts, error)
+	GetUnansweredQuestions(ctx context.Context, startDate, endDate *time.Time) ([]UnansweredQuestion, error)
+	// GetDemographicCounts counts interactions and their distinct chat users per value of a Dimension
+	GetDemographicCounts(ctx context.Context, dimension string, startDate, endDate *time.Time) ([]DemographicCount, error)
+
+	// GetTopCitedFiles ranks the files cited in the range by citations, with the uploaded file they belong to.
+	// When s3Keys is not empty only those files are ranked.
+	GetTopCitedFiles(ctx context.Context, startDate, endDate *time.Time, s3Keys []string, limit int) ([]FileCitations, error)
+	// GetFileCitationCounts counts the citations of the files with the given S3 keys per Granularity bucket
+	// of timeZone, skipping buckets without citations
+	GetFileCitationCounts(ctx context.Context, s3Keys []string, startDate, endDate time.Time, granularity string, timeZone string) ([]FileCitationCount, error)
+	// GetUncitedFiles returns the files uploaded before the end of the range that no answer in the range cited, oldest first
+	GetUncitedFiles(ctx context.Context, startDate, endDate *time.Time) ([]UncitedFile, error)
+
+	// RollUp aggregates the next complete hours of interactions, never past until, into the rollup tables
+	// the reports read instead of the interactions table, and returns the end of the rolled up hours
+	RollUp(ctx context.Context, until time.Time) (time.Time, error)
+
+	// GetAllChatUsers, GetAllInteractionsData and GetAllFiles stream the rows of the database export through
+	// a cursor, calling fn for every row and stopping at the first error it returns
+	GetAllChatUsers(ctx context.Context, startDate, endDate *time.Time, fn func(ExportChatUser) error) error
+	GetAllInteractionsData(ctx context.Context, startDate, endDate *time.Time, fn func(ExportInteraction) error) error
+	GetAllFiles(ctx context.Context, startDate, endDate *time.Time, fn func(ExportFile) error) error
+	// CountExportRows counts the chat users, interactions and files a database export of the range has
+	CountExportRows(ctx context.Context, startDate, endDate *time.Time) (int, error)
+
+	CreateExportJob(ctx context.Context, job ExportJob) (*ExportJob, error)
+	GetExportJob(ctx context.Context, id int) (*ExportJob, error)
+	UpdateExportProgress(ctx context.Context, id int, exportedRows int) error
+	CompleteExportJob(ctx context.Context, id int, s3Key string, exportedRows int) (*ExportJob, error)
+	FailExportJob(ctx context.Context, id int, reason string) error
+}
+
+// Uploader stores an export in S3 as it is written, one part at a time
+type Uploader interface {
+	Upload(ctx context.Context, key string, contentType string, body io.Reader) error
+}
 
-	GetAllChatUsers(ctx context.Context, startDate, endDate *time.Time) ([]chatuser.ChatUser, error)
-	GetAllInteractionsData(ctx context.Context, startDate, endDate *time.Time) ([]interaction.Interaction, error)
-	GetAllFiles(ctx context.Context, startDate, endDate *time.Time) ([]kb.DataFile, error)
+// Notifier tells the admin who requested an export that it is ready to download
+type Notifier interface {
+	NotifyExportReady(ctx context.Context, address string, downloadURL string) error
 }
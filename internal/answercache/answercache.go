@@ -0,0 +1,30 @@
+package answercache
+
+import "time"
+
+// Entry is a knowledge base answer kept to serve the same question again
+type Entry struct {
+	ID                 int         `json:"id" db:"id"`
+	KnowledgeBaseID    string      `json:"kb_id" db:"kb_id"`
+	PromptVersion      string      `json:"prompt_version" db:"prompt_version"`
+	Question           string      `json:"question" db:"question"`
+	NormalizedQuestion string      `json:"-" db:"normalized_question"`
+	Answer             string      `json:"answer" db:"answer"`
+	References         []Reference `json:"references" db:"-"`
+	Hits               int         `json:"hits" db:"hits"`
+	LastHitAt          *time.Time  `json:"last_hit_at" db:"last_hit_at"`
+	ExpiresAt          time.Time   `json:"expires_at" db:"expires_at"`
+	CreatedAt          time.Time   `json:"created_at" db:"created_at"`
+}
+
+// Reference is a knowledge base chunk the answer cited
+type Reference struct {
+	URI     string `json:"uri"`
+	Content string `json:"content"`
+}
+
+// Hit is a cached answer and how similar its question is to the one asked, from 0 to 1
+type Hit struct {
+	Entry Entry   `json:"entry"`
+	Score float64 `json:"score"`
+}
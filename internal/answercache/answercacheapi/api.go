@@ -0,0 +1,21 @@
+package answercacheapi
+
+import (
+	"github.com/Abraxas-365/opd/internal/answercache/answercachesrv"
+	"github.com/Abraxas-365/opd/internal/user"
+	"github.com/Abraxas-365/toolkit/pkg/lucia"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the admin routes of the answer cache
+func SetupRoutes(app *fiber.App, service *answercachesrv.Service, authMiddleware *lucia.AuthMiddleware[*user.User]) {
+	// Drop every cached answer, for example after correcting a document the cache answered from
+	app.Delete("/answer-cache", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		deleted, err := service.Flush(c.Context())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{"deleted": deleted})
+	})
+}
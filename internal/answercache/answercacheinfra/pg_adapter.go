@@ -0,0 +1,138 @@
+package answercacheinfra
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/answercache"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+const entryColumns = `id, kb_id, prompt_version, question, normalized_question, answer, refs, hits, last_hit_at, expires_at, created_at`
+
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewAnswerCacheStore creates a new PostgresStore for answer cache repository
+func NewAnswerCacheStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEntry(row rowScanner) (*answercache.Entry, error) {
+	var e answercache.Entry
+	var refs []byte
+	err := row.Scan(
+		&e.ID,
+		&e.KnowledgeBaseID,
+		&e.PromptVersion,
+		&e.Question,
+		&e.NormalizedQuestion,
+		&e.Answer,
+		&refs,
+		&e.Hits,
+		&e.LastHitAt,
+		&e.ExpiresAt,
+		&e.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(refs, &e.References); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// CreateEntry stores an answer in the cache
+func (s *PostgresStore) CreateEntry(ctx context.Context, e answercache.Entry) (*answercache.Entry, error) {
+	refs, err := json.Marshal(e.References)
+	if err != nil {
+		return nil, errors.ErrUnexpected(fmt.Sprintf("Failed to encode cache references: %v", err))
+	}
+
+	query := `
+		INSERT INTO answer_cache (kb_id, prompt_version, question, normalized_question, answer, refs, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + entryColumns
+
+	created, err := scanEntry(s.db.QueryRowContext(ctx, query,
+		e.KnowledgeBaseID, e.PromptVersion, e.Question, e.NormalizedQuestion, e.Answer, refs, e.ExpiresAt))
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create cache entry: %v", err))
+	}
+	return created, nil
+}
+
+// GetCandidates returns unexpired entries to compare with a question
+func (s *PostgresStore) GetCandidates(ctx context.Context, kbID, promptVersion, normalizedQuestion string, limit int) ([]answercache.Entry, error) {
+	query := `
+		SELECT ` + entryColumns + `
+		FROM answer_cache
+		WHERE kb_id = $1 AND prompt_version = $2 AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY normalized_question = $3 DESC, hits DESC, created_at DESC
+		LIMIT $4`
+
+	rows, err := s.db.QueryContext(ctx, query, kbID, promptVersion, normalizedQuestion, limit)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get cache entries: %v", err))
+	}
+	defer rows.Close()
+
+	var entries []answercache.Entry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, errors.ErrDatabase(fmt.Sprintf("Failed to scan cache entry: %v", err))
+		}
+		entries = append(entries, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Error iterating cache entries: %v", err))
+	}
+	return entries, nil
+}
+
+// RecordHit counts a use of a cache entry
+func (s *PostgresStore) RecordHit(ctx context.Context, id int) error {
+	query := `UPDATE answer_cache SET hits = hits + 1, last_hit_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := s.db.ExecContext(ctx, query, id); err != nil {
+		return errors.ErrDatabase(fmt.Sprintf("Failed to record cache hit: %v", err))
+	}
+	return nil
+}
+
+// DeleteCreatedBefore drops the entries of a knowledge base created before t
+func (s *PostgresStore) DeleteCreatedBefore(ctx context.Context, kbID string, t time.Time) (int64, error) {
+	return s.delete(ctx, `DELETE FROM answer_cache WHERE kb_id = $1 AND created_at < $2`, kbID, t)
+}
+
+// DeleteExpired drops the entries past their TTL
+func (s *PostgresStore) DeleteExpired(ctx context.Context) (int64, error) {
+	return s.delete(ctx, `DELETE FROM answer_cache WHERE expires_at <= CURRENT_TIMESTAMP`)
+}
+
+// DeleteAll empties the cache
+func (s *PostgresStore) DeleteAll(ctx context.Context) (int64, error) {
+	return s.delete(ctx, `DELETE FROM answer_cache`)
+}
+
+func (s *PostgresStore) delete(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, errors.ErrDatabase(fmt.Sprintf("Failed to delete cache entries: %v", err))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.ErrDatabase(fmt.Sprintf("Failed to get affected rows: %v", err))
+	}
+	return rows, nil
+}
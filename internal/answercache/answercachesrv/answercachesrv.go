@@ -0,0 +1,112 @@
+package answercachesrv
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/answercache"
+	"github.com/Abraxas-365/opd/pkg/textsim"
+)
+
+// DefaultThreshold is the similarity a question needs to reuse a cached answer
+const DefaultThreshold = 0.9
+
+// candidateLimit caps the entries compared with each question
+const candidateLimit = 1000
+
+type Service struct {
+	repo      answercache.Repository
+	ttl       time.Duration
+	threshold float64
+}
+
+// New creates the answer cache, a zero ttl disables it
+func New(repo answercache.Repository, ttl time.Duration, threshold float64) *Service {
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultThreshold
+	}
+	return &Service{
+		repo:      repo,
+		ttl:       ttl,
+		threshold: threshold,
+	}
+}
+
+func (s *Service) Enabled() bool {
+	return s.ttl > 0
+}
+
+// Lookup returns the cached answer whose question is closest to question,
+// or nil when none is similar enough
+func (s *Service) Lookup(ctx context.Context, kbID, promptVersion, question string) (*answercache.Hit, error) {
+	if !s.Enabled() {
+		return nil, nil
+	}
+
+	normalized := textsim.Normalize(question)
+	entries, err := s.repo.GetCandidates(ctx, kbID, promptVersion, normalized, candidateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *answercache.Hit
+	for _, e := range entries {
+		score := 1.0
+		if e.NormalizedQuestion != normalized {
+			score = textsim.Similarity(question, e.Question)
+		}
+		if score >= s.threshold && (best == nil || score > best.Score) {
+			best = &answercache.Hit{Entry: e, Score: score}
+		}
+		if score == 1 {
+			break
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	if err := s.repo.RecordHit(ctx, best.Entry.ID); err != nil {
+		return nil, err
+	}
+	return best, nil
+}
+
+// Store keeps an answer for the ttl
+func (s *Service) Store(ctx context.Context, kbID, promptVersion, question, answer string, refs []answercache.Reference) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	_, err := s.repo.CreateEntry(ctx, answercache.Entry{
+		KnowledgeBaseID:    kbID,
+		PromptVersion:      promptVersion,
+		Question:           question,
+		NormalizedQuestion: textsim.Normalize(question),
+		Answer:             answer,
+		References:         refs,
+		ExpiresAt:          time.Now().Add(s.ttl),
+	})
+	return err
+}
+
+// InvalidateBefore drops the answers of a knowledge base cached before t,
+// when its content changed
+func (s *Service) InvalidateBefore(ctx context.Context, kbID string, t time.Time) error {
+	deleted, err := s.repo.DeleteCreatedBefore(ctx, kbID, t)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("answer cache: invalidated %d answers of knowledge base %s", deleted, kbID)
+	}
+
+	_, err = s.repo.DeleteExpired(ctx)
+	return err
+}
+
+// Flush empties the cache and returns how many answers were dropped
+func (s *Service) Flush(ctx context.Context) (int64, error) {
+	return s.repo.DeleteAll(ctx)
+}
@@ -0,0 +1,17 @@
+package answercache
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	CreateEntry(ctx context.Context, e Entry) (*Entry, error)
+	// GetCandidates returns unexpired entries, the exact normalized question first, then the most used
+	GetCandidates(ctx context.Context, kbID, promptVersion, normalizedQuestion string, limit int) ([]Entry, error)
+	RecordHit(ctx context.Context, id int) error
+	// DeleteCreatedBefore drops the entries of a knowledge base created before t
+	DeleteCreatedBefore(ctx context.Context, kbID string, t time.Time) (int64, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+	DeleteAll(ctx context.Context) (int64, error)
+}
@@ -1,5 +1,7 @@
 package chatuser
 
+import "time"
+
 type ChatUser struct {
 	ID        *string `json:"id" db:"id"`
 	Age       int     `json:"age" db:"age"`
@@ -7,3 +9,31 @@ type ChatUser struct {
 	Ocupation string  `json:"ocupation" db:"occupation"`
 	Location  string  `json:"location" db:"location"`
 }
+
+// Channels chat users can reach the knowledge base through.
+// ChannelWeb is the HTTP and WebSocket API, used by chat users without a ChannelIdentity.
+const (
+	ChannelWeb      = "web"
+	ChannelWhatsApp = "whatsapp"
+	ChannelSlack    = "slack"
+	ChannelTelegram = "telegram"
+	ChannelEmail    = "email"
+)
+
+// ChannelIdentity links an identity on an external channel, such as a phone number, to a chat user
+type ChannelIdentity struct {
+	Channel    string `json:"channel" db:"channel"`
+	ExternalID string `json:"external_id" db:"external_id"`
+	ChatUserID string `json:"chat_user_id" db:"user_chat_id"`
+}
+
+// Session is the Bedrock conversation a chat user is currently in
+type Session struct {
+	ChatUserID string    `json:"chat_user_id" db:"user_chat_id"`
+	SessionID  string    `json:"session_id" db:"session_id"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+}
+
+func (s Session) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
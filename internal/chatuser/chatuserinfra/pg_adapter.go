@@ -2,11 +2,13 @@ package chatuserinfra
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/Abraxas-365/opd/internal/chatuser"
 	"github.com/Abraxas-365/toolkit/pkg/errors"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type PostgresStore struct {
@@ -66,3 +68,131 @@ func (s *PostgresStore) CreateChatUser(ctx context.Context, u chatuser.ChatUser)
 
 	return &u, nil
 }
+
+// GetChatUserByChannel retrieves the chat user linked to an external channel identity
+func (s *PostgresStore) GetChatUserByChannel(ctx context.Context, channel, externalID string) (*chatuser.ChatUser, error) {
+	var u chatuser.ChatUser
+
+	query := `
+		SELECT c.id, c.age, c.gender, c.occupation, c.location
+		FROM chatUser c
+		JOIN chat_user_channels ch ON ch.user_chat_id = c.id
+		WHERE ch.channel = $1 AND ch.external_id = $2`
+	err := s.db.GetContext(ctx, &u, query, channel, externalID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("Chat user not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get chat user by channel: %v", err))
+	}
+	return &u, nil
+}
+
+// CreateChannelChatUser inserts a chat user and links it to an external channel identity
+func (s *PostgresStore) CreateChannelChatUser(ctx context.Context, u chatuser.ChatUser, channel, externalID string) (*chatuser.ChatUser, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to start transaction: %v", err))
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO chatUser (id, age, gender, occupation, location) 
+		VALUES ($1, $2, $3, $4, $5) 
+		RETURNING id, age, gender, occupation, location`
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		u.ID,
+		u.Age,
+		u.Gender,
+		u.Ocupation,
+		u.Location,
+	).Scan(&u.ID, &u.Age, &u.Gender, &u.Ocupation, &u.Location)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create chat user: %v", err))
+	}
+
+	linkQuery := `INSERT INTO chat_user_channels (channel, external_id, user_chat_id) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, linkQuery, channel, externalID, u.ID); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, errors.ErrConflict("Channel identity already linked to a chat user")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to link chat user to channel: %v", err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to commit chat user: %v", err))
+	}
+
+	return &u, nil
+}
+
+// GetChannelIdentities retrieves the external channel identities linked to a chat user
+func (s *PostgresStore) GetChannelIdentities(ctx context.Context, chatUserID string) ([]chatuser.ChannelIdentity, error) {
+	query := `
+		SELECT channel, external_id, user_chat_id
+		FROM chat_user_channels
+		WHERE user_chat_id = $1
+		ORDER BY created_at`
+
+	var identities []chatuser.ChannelIdentity
+	if err := s.db.SelectContext(ctx, &identities, query, chatUserID); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get channel identities: %v", err))
+	}
+	return identities, nil
+}
+
+// GetSession retrieves the active (not expired) session of a chat user
+func (s *PostgresStore) GetSession(ctx context.Context, chatUserID string) (*chatuser.Session, error) {
+	var session chatuser.Session
+
+	query := `
+		SELECT user_chat_id, session_id, expires_at
+		FROM chat_sessions
+		WHERE user_chat_id = $1 AND expires_at > CURRENT_TIMESTAMP`
+	err := s.db.GetContext(ctx, &session, query, chatUserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("No active session for chat user")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get chat session: %v", err))
+	}
+	return &session, nil
+}
+
+// SaveSession creates or replaces the session of a chat user
+func (s *PostgresStore) SaveSession(ctx context.Context, session chatuser.Session) (*chatuser.Session, error) {
+	query := `
+		INSERT INTO chat_sessions (user_chat_id, session_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_chat_id)
+		DO UPDATE SET session_id = EXCLUDED.session_id, expires_at = EXCLUDED.expires_at
+		RETURNING user_chat_id, session_id, expires_at`
+
+	err := s.db.QueryRowxContext(
+		ctx,
+		query,
+		session.ChatUserID,
+		session.SessionID,
+		session.ExpiresAt,
+	).StructScan(&session)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return nil, errors.ErrNotFound("Referenced chat user not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to save chat session: %v", err))
+	}
+
+	return &session, nil
+}
+
+// DeleteSession removes the session of a chat user, if any
+func (s *PostgresStore) DeleteSession(ctx context.Context, chatUserID string) error {
+	query := `DELETE FROM chat_sessions WHERE user_chat_id = $1`
+	if _, err := s.db.ExecContext(ctx, query, chatUserID); err != nil {
+		return errors.ErrDatabase(fmt.Sprintf("Failed to delete chat session: %v", err))
+	}
+	return nil
+}
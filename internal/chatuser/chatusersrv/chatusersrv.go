@@ -2,11 +2,16 @@ package chatusersrv
 
 import (
 	"context"
+	"time"
 
 	"github.com/Abraxas-365/opd/internal/chatuser"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
 	"github.com/google/uuid"
 )
 
+// SessionTTL is how long a conversation stays resumable after the last message
+const SessionTTL = 30 * time.Minute
+
 type Service struct {
 	repo chatuser.Repository
 }
@@ -28,3 +33,72 @@ func (s *Service) CreateChatUser(ctx context.Context, cu chatuser.ChatUser) (*ch
 func (s *Service) GetChatUserByID(ctx context.Context, chatUserID string) (*chatuser.ChatUser, error) {
 	return s.repo.GetChatUserByID(ctx, chatUserID)
 }
+
+// GetOrCreateChannelChatUser returns the chat user linked to an external channel identity,
+// creating one on first contact
+func (s *Service) GetOrCreateChannelChatUser(ctx context.Context, channel, externalID string) (*chatuser.ChatUser, error) {
+	u, err := s.repo.GetChatUserByChannel(ctx, channel, externalID)
+	if err == nil {
+		return u, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	u, err = s.repo.CreateChannelChatUser(ctx, chatuser.ChatUser{ID: &id}, channel, externalID)
+	if errors.IsConflict(err) {
+		// Another message from the same identity created it first
+		return s.repo.GetChatUserByChannel(ctx, channel, externalID)
+	}
+	return u, err
+}
+
+// GetChannelIdentity returns the channel identity the chat user reached us through,
+// or a ChannelWeb identity for chat users of the HTTP API
+func (s *Service) GetChannelIdentity(ctx context.Context, chatUserID string) (*chatuser.ChannelIdentity, error) {
+	identities, err := s.repo.GetChannelIdentities(ctx, chatUserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		return &chatuser.ChannelIdentity{
+			Channel:    chatuser.ChannelWeb,
+			ExternalID: chatUserID,
+			ChatUserID: chatUserID,
+		}, nil
+	}
+	return &identities[0], nil
+}
+
+// GetActiveSessionID returns the session the chat user can resume, or nil if there is none
+func (s *Service) GetActiveSessionID(ctx context.Context, chatUserID string) (*string, error) {
+	session, err := s.repo.GetSession(ctx, chatUserID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if session.IsExpired() {
+		return nil, nil
+	}
+	return &session.SessionID, nil
+}
+
+// TouchSession stores sessionID as the chat user's active session and extends its expiry
+func (s *Service) TouchSession(ctx context.Context, chatUserID string, sessionID string) (*chatuser.Session, error) {
+	return s.repo.SaveSession(ctx, chatuser.Session{
+		ChatUserID: chatUserID,
+		SessionID:  sessionID,
+		ExpiresAt:  time.Now().Add(SessionTTL),
+	})
+}
+
+// EndSession forgets the active session so the next message starts a new conversation
+func (s *Service) EndSession(ctx context.Context, chatUserID string) error {
+	if _, err := s.repo.GetChatUserByID(ctx, chatUserID); err != nil {
+		return err
+	}
+	return s.repo.DeleteSession(ctx, chatUserID)
+}
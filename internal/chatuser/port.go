@@ -5,4 +5,12 @@ import "context"
 type Repository interface {
 	GetChatUserByID(ctx context.Context, chatUserID string) (*ChatUser, error)
 	CreateChatUser(ctx context.Context, u ChatUser) (*ChatUser, error)
+
+	GetChatUserByChannel(ctx context.Context, channel, externalID string) (*ChatUser, error)
+	CreateChannelChatUser(ctx context.Context, u ChatUser, channel, externalID string) (*ChatUser, error)
+	GetChannelIdentities(ctx context.Context, chatUserID string) ([]ChannelIdentity, error)
+
+	GetSession(ctx context.Context, chatUserID string) (*Session, error)
+	SaveSession(ctx context.Context, s Session) (*Session, error)
+	DeleteSession(ctx context.Context, chatUserID string) error
 }
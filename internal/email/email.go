@@ -0,0 +1,44 @@
+package email
+
+import "strings"
+
+// Message is an inbound question received by email
+type Message struct {
+	MessageID  string
+	References []string
+	From       string
+	Subject    string
+	Text       string
+	// AutoSubmitted is set on auto-replies and bulk mail, which must not be answered
+	AutoSubmitted bool
+}
+
+// Reply is an answer sent back to the sender of a Message
+type Reply struct {
+	To         string
+	Subject    string
+	InReplyTo  string
+	References []string
+	Text       string
+}
+
+// NewReply creates a reply threaded to m
+func NewReply(m Message, text string) Reply {
+	subject := m.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+
+	references := append([]string{}, m.References...)
+	if m.MessageID != "" {
+		references = append(references, m.MessageID)
+	}
+
+	return Reply{
+		To:         m.From,
+		Subject:    subject,
+		InReplyTo:  m.MessageID,
+		References: references,
+		Text:       text,
+	}
+}
@@ -0,0 +1,36 @@
+package emailapi
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+
+	"github.com/Abraxas-365/opd/internal/email"
+	"github.com/Abraxas-365/opd/internal/email/emailsrv"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the inbound email webhook, which takes the raw RFC 5322 message as body
+func SetupRoutes(app *fiber.App, service *emailsrv.Service) {
+	app.Post("/webhooks/email", func(c *fiber.Ctx) error {
+		token := strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+		if err := service.VerifyWebhookToken(token); err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid authorization"})
+		}
+
+		// Reject malformed messages now, while the provider can still report it
+		raw := append([]byte(nil), c.Body()...)
+		if _, err := email.ParseMessage(bytes.NewReader(raw)); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		go func() {
+			if err := service.HandleMessage(context.Background(), raw); err != nil {
+				log.Printf("email: failed to answer message: %v", err)
+			}
+		}()
+
+		return c.SendStatus(fiber.StatusAccepted)
+	})
+}
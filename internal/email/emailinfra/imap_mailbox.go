@@ -0,0 +1,86 @@
+package emailinfra
+
+import (
+	"context"
+	"io"
+
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// IMAPMailbox reads questions from an IMAP folder over TLS
+type IMAPMailbox struct {
+	addr     string
+	username string
+	password string
+	folder   string
+}
+
+func NewIMAPMailbox(addr, username, password, folder string) *IMAPMailbox {
+	if folder == "" {
+		folder = "INBOX"
+	}
+	return &IMAPMailbox{
+		addr:     addr,
+		username: username,
+		password: password,
+		folder:   folder,
+	}
+}
+
+// FetchUnseen returns the unread messages, fetching BODY[] marks them as read
+func (m *IMAPMailbox) FetchUnseen(ctx context.Context) ([][]byte, error) {
+	c, err := client.DialTLS(m.addr, nil)
+	if err != nil {
+		return nil, errors.ErrServiceUnavailable("failed to connect to IMAP server: " + err.Error())
+	}
+	defer c.Logout()
+
+	if err := c.Login(m.username, m.password); err != nil {
+		return nil, errors.ErrUnauthorized("IMAP login failed: " + err.Error())
+	}
+
+	if _, err := c.Select(m.folder, false); err != nil {
+		return nil, errors.ErrServiceUnavailable("failed to select IMAP folder: " + err.Error())
+	}
+
+	criteria := imap.NewSearchCriteria()
+	criteria.WithoutFlags = []string{imap.SeenFlag}
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return nil, errors.ErrServiceUnavailable("IMAP search failed: " + err.Error())
+	}
+	if len(uids) == 0 {
+		return nil, nil
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uids...)
+	section := &imap.BodySectionName{}
+
+	messages := make(chan *imap.Message, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.UidFetch(seqSet, []imap.FetchItem{section.FetchItem()}, messages)
+	}()
+
+	var raw [][]byte
+	for msg := range messages {
+		body := msg.GetBody(section)
+		if body == nil {
+			continue
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			continue
+		}
+		raw = append(raw, data)
+	}
+
+	if err := <-done; err != nil {
+		return nil, errors.ErrServiceUnavailable("IMAP fetch failed: " + err.Error())
+	}
+
+	return raw, nil
+}
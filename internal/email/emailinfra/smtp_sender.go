@@ -0,0 +1,94 @@
+package emailinfra
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/email"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/google/uuid"
+)
+
+// SMTPSender sends replies through an SMTP server. Without a username it sends
+// unauthenticated, which is what local sinks such as MailHog expect.
+type SMTPSender struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPSender(host, port, username, password, from string) *SMTPSender {
+	return &SMTPSender{
+		addr:     net.JoinHostPort(host, port),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send delivers the reply, threaded with In-Reply-To and References headers
+func (s *SMTPSender) Send(ctx context.Context, reply email.Reply) error {
+	msg, err := s.build(reply)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	if err := smtp.SendMail(s.addr, auth, s.from, []string{reply.To}, msg); err != nil {
+		return errors.ErrServiceUnavailable("failed to send email: " + err.Error())
+	}
+	return nil
+}
+
+func (s *SMTPSender) build(reply email.Reply) ([]byte, error) {
+	domain := "localhost"
+	if i := strings.LastIndex(s.from, "@"); i >= 0 {
+		domain = s.from[i+1:]
+	}
+
+	var buf bytes.Buffer
+	headers := [][2]string{
+		{"From", s.from},
+		{"To", reply.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", reply.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", uuid.New().String(), domain)},
+		{"In-Reply-To", reply.InReplyTo},
+		{"References", strings.Join(reply.References, " ")},
+		{"Auto-Submitted", "auto-replied"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	for _, h := range headers {
+		if h[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "%s: %s\r\n", h[0], h[1])
+	}
+	buf.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(strings.ReplaceAll(reply.Text, "\n", "\r\n"))); err != nil {
+		return nil, errors.ErrUnexpected("failed to encode email body: " + err.Error())
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.ErrUnexpected("failed to encode email body: " + err.Error())
+	}
+
+	return buf.Bytes(), nil
+}
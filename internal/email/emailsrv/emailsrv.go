@@ -0,0 +1,151 @@
+package emailsrv
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/chatuser"
+	"github.com/Abraxas-365/opd/internal/chatuser/chatusersrv"
+	"github.com/Abraxas-365/opd/internal/email"
+	kbsrv "github.com/Abraxas-365/opd/internal/kb/kbasesrv"
+	"github.com/Abraxas-365/opd/internal/usage"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+const (
+	fallbackAnswer    = "Sorry, we could not answer your question right now. Please try again later."
+	agentReplySubject = "Reply from our team"
+	exportSubject     = "Your database export is ready"
+	budgetSubject     = "Model spend crossed %d%% of the monthly budget"
+)
+
+type Service struct {
+	sender          email.Sender
+	kbService       *kbsrv.Service
+	chatUserService *chatusersrv.Service
+	fromAddress     string
+	webhookToken    string
+}
+
+func New(sender email.Sender,
+	kbService *kbsrv.Service,
+	chatUserService *chatusersrv.Service,
+	fromAddress string,
+	webhookToken string,
+) *Service {
+	return &Service{
+		sender:          sender,
+		kbService:       kbService,
+		chatUserService: chatUserService,
+		fromAddress:     strings.ToLower(fromAddress),
+		webhookToken:    webhookToken,
+	}
+}
+
+// VerifyWebhookToken checks the bearer token sent by the inbound mail provider
+func (s *Service) VerifyWebhookToken(token string) error {
+	if s.webhookToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.webhookToken)) != 1 {
+		return errors.ErrUnauthorized("invalid webhook token")
+	}
+	return nil
+}
+
+// HandleMessage answers a raw RFC 5322 message, each sender keeps its own conversation
+func (s *Service) HandleMessage(ctx context.Context, raw []byte) error {
+	m, err := email.ParseMessage(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+
+	// Never answer ourselves or automatic mail, that is how reply loops start
+	if m.AutoSubmitted || m.From == s.fromAddress || m.Text == "" {
+		return nil
+	}
+
+	u, err := s.chatUserService.GetOrCreateChannelChatUser(ctx, chatuser.ChannelEmail, m.From)
+	if err != nil {
+		return err
+	}
+
+	text := fallbackAnswer
+	output, err := s.kbService.CompleteAnswerWithMetadata(ctx, m.Text, nil, *u.ID, kbsrv.FromChannel(chatuser.ChannelEmail))
+	if err != nil {
+		log.Printf("email: completion failed for chat user %s: %v", *u.ID, err)
+	} else if output.Output != nil && output.Output.Text != nil {
+		citations, err := s.kbService.GetCitations(ctx, output)
+		if err != nil {
+			log.Printf("email: failed to resolve citations: %v", err)
+		}
+
+		var b strings.Builder
+		b.WriteString(*output.Output.Text)
+		if len(citations) > 0 {
+			b.WriteString("\n\nSources:")
+			for _, c := range citations {
+				fmt.Fprintf(&b, "\n- %s: %s", c.Filename, c.URL)
+			}
+		}
+		text = b.String()
+	}
+
+	return s.sender.Send(ctx, email.NewReply(*m, text))
+}
+
+// Deliver emails an agent reply to the sender, implementing handoff.Deliverer
+func (s *Service) Deliver(ctx context.Context, address string, text string) error {
+	return s.sender.Send(ctx, email.Reply{
+		To:      address,
+		Subject: agentReplySubject,
+		Text:    text,
+	})
+}
+
+// NotifyExportReady emails the download link of a database export to the admin who requested it,
+// implementing analitics.Notifier
+func (s *Service) NotifyExportReady(ctx context.Context, address string, downloadURL string) error {
+	return s.sender.Send(ctx, email.Reply{
+		To:      address,
+		Subject: exportSubject,
+		Text:    "Your database export is ready, download it from:\n\n" + downloadURL,
+	})
+}
+
+// NotifyBudgetAlert emails an admin that the model spend of the month crossed a budget threshold,
+// implementing usage.Notifier
+func (s *Service) NotifyBudgetAlert(ctx context.Context, address string, alert usage.BudgetAlert) error {
+	return s.sender.Send(ctx, email.Reply{
+		To:      address,
+		Subject: fmt.Sprintf(budgetSubject, alert.Percent),
+		Text: fmt.Sprintf("The model spend of %s is %.2f USD, %d%% of the monthly budget of %.2f USD.",
+			alert.Month.Format("January 2006"), alert.Spent, alert.Percent, alert.Budget),
+	})
+}
+
+// Poll answers the unread messages of mailbox every interval until ctx is done
+func (s *Service) Poll(ctx context.Context, mailbox email.Mailbox, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		messages, err := mailbox.FetchUnseen(ctx)
+		if err != nil {
+			log.Printf("email: failed to fetch messages: %v", err)
+		}
+		for _, raw := range messages {
+			if err := s.HandleMessage(ctx, raw); err != nil {
+				log.Printf("email: failed to answer message: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
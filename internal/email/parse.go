@@ -0,0 +1,143 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// maxBodySize bounds how much of a message body is read
+const maxBodySize = 1 << 20
+
+var (
+	htmlTagPattern = regexp.MustCompile(`(?s)<[^>]*>`)
+	// Lines that introduce the quoted previous message in replies
+	quoteHeaderPattern = regexp.MustCompile(`^(On .+ wrote:|El .+ escribió:|-+ ?Original Message ?-+)$`)
+)
+
+// ParseMessage reads an RFC 5322 message and extracts the question in its text body
+func ParseMessage(r io.Reader) (*Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, errors.ErrBadRequest("invalid email message: " + err.Error())
+	}
+
+	from, err := msg.Header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return nil, errors.ErrBadRequest("email message has no valid From address")
+	}
+
+	decoder := new(mime.WordDecoder)
+	subject, err := decoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	text, err := readText(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	autoSubmitted := msg.Header.Get("Auto-Submitted")
+	precedence := strings.ToLower(msg.Header.Get("Precedence"))
+
+	return &Message{
+		MessageID:     strings.TrimSpace(msg.Header.Get("Message-ID")),
+		References:    strings.Fields(msg.Header.Get("References")),
+		From:          strings.ToLower(from[0].Address),
+		Subject:       subject,
+		Text:          stripQuotedReply(text),
+		AutoSubmitted: (autoSubmitted != "" && autoSubmitted != "no") || precedence == "bulk" || precedence == "list" || precedence == "junk",
+	}, nil
+}
+
+// readText returns the text/plain content of a body, falling back to text/html without tags
+func readText(contentType, transferEncoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		var html string
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", errors.ErrBadRequest("invalid multipart email body: " + err.Error())
+			}
+
+			partType := part.Header.Get("Content-Type")
+			text, err := readText(partType, part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return "", err
+			}
+			if text == "" {
+				continue
+			}
+			if strings.HasPrefix(partType, "text/html") {
+				html = text
+				continue
+			}
+			return text, nil
+		}
+		return html, nil
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return "", nil
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(decodeTransfer(transferEncoding, body), maxBodySize))
+	if err != nil {
+		return "", errors.ErrBadRequest("invalid email body: " + err.Error())
+	}
+
+	text := string(raw)
+	if mediaType == "text/html" {
+		text = htmlTagPattern.ReplaceAllString(text, " ")
+	}
+	return strings.TrimSpace(text), nil
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	case "base64":
+		// The decoder skips the line breaks of wrapped base64 bodies
+		return base64.NewDecoder(base64.StdEncoding, body)
+	}
+	return body
+}
+
+// stripQuotedReply removes the previous message quoted below a reply
+func stripQuotedReply(text string) string {
+	var kept bytes.Buffer
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBodySize)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if quoteHeaderPattern.MatchString(strings.TrimSpace(line)) {
+			break
+		}
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+		kept.WriteString(line)
+		kept.WriteByte('\n')
+	}
+	return strings.TrimSpace(kept.String())
+}
@@ -0,0 +1,14 @@
+package email
+
+import "context"
+
+// Sender delivers replies, over SMTP in production
+type Sender interface {
+	Send(ctx context.Context, reply Reply) error
+}
+
+// Mailbox is an inbox polled for new questions
+type Mailbox interface {
+	// FetchUnseen returns the raw RFC 5322 messages not read yet and marks them as read
+	FetchUnseen(ctx context.Context) ([][]byte, error)
+}
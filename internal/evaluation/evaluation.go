@@ -0,0 +1,127 @@
+package evaluation
+
+import "time"
+
+// Graders, how answers are scored against the expected answer
+const (
+	// GraderSimilarity compares the text of the answers, it needs no model call
+	GraderSimilarity = "similarity"
+	// GraderLLMJudge asks a model to grade the answer against the expected one
+	GraderLLMJudge = "llm_judge"
+)
+
+// Run statuses
+const (
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+)
+
+// Dataset is a set of questions with known good answers
+type Dataset struct {
+	ID          int       `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Cases       []Case    `json:"cases,omitempty" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Case is a question, the answer expected for it and the files that answer should come from.
+// ExpectedSources are file names as uploaded or S3 keys.
+type Case struct {
+	ID              int      `json:"id" db:"id"`
+	DatasetID       int      `json:"dataset_id" db:"dataset_id"`
+	Question        string   `json:"question" db:"question"`
+	ExpectedAnswer  string   `json:"expected_answer" db:"expected_answer"`
+	ExpectedSources []string `json:"expected_sources" db:"expected_sources"`
+}
+
+// Target is the knowledge base configuration a dataset is run against, empty fields use the current one
+type Target struct {
+	ModelID         string `json:"modelId" db:"model_id"`
+	Prompt          string `json:"prompt" db:"prompt"`
+	NumberOfResults int    `json:"numberOfResults" db:"number_of_results"`
+}
+
+// Run is a dataset answered with a target. RetrievalHitRate is the share of cases with expected
+// sources where one of them was retrieved, AnswerScore the mean case score from 0 to 1.
+type Run struct {
+	ID               int        `json:"id" db:"id"`
+	DatasetID        int        `json:"dataset_id" db:"dataset_id"`
+	Status           string     `json:"status" db:"status"`
+	Grader           string     `json:"grader" db:"grader"`
+	Target           Target     `json:"target"`
+	CaseCount        int        `json:"case_count" db:"case_count"`
+	RetrievalHitRate *float64   `json:"retrieval_hit_rate" db:"retrieval_hit_rate"`
+	AnswerScore      *float64   `json:"answer_score" db:"answer_score"`
+	Error            *string    `json:"error" db:"error"`
+	CreatedBy        *string    `json:"created_by" db:"created_by"`
+	StartedAt        time.Time  `json:"started_at" db:"started_at"`
+	CompletedAt      *time.Time `json:"completed_at" db:"completed_at"`
+}
+
+// Result is how a run answered one case. RetrievalHit is nil when the case expects no sources,
+// Score is the judge score with GraderLLMJudge and the similarity otherwise.
+type Result struct {
+	ID               int      `json:"id" db:"id"`
+	RunID            int      `json:"run_id" db:"run_id"`
+	Question         string   `json:"question" db:"question"`
+	ExpectedAnswer   string   `json:"expected_answer" db:"expected_answer"`
+	ExpectedSources  []string `json:"expected_sources" db:"expected_sources"`
+	Answer           string   `json:"answer" db:"answer"`
+	RetrievedSources []string `json:"retrieved_sources" db:"retrieved_sources"`
+	CitedSources     []string `json:"cited_sources" db:"cited_sources"`
+	RetrievalHit     *bool    `json:"retrieval_hit" db:"retrieval_hit"`
+	Similarity       float64  `json:"similarity" db:"similarity"`
+	JudgeScore       *float64 `json:"judge_score" db:"judge_score"`
+	JudgeReasoning   string   `json:"judge_reasoning" db:"judge_reasoning"`
+	Score            float64  `json:"score" db:"score"`
+	Error            *string  `json:"error" db:"error"`
+}
+
+type RunWithResults struct {
+	Run
+	Results []Result `json:"results"`
+}
+
+// Comparison shows how a candidate run did against a base run, deltas are candidate minus base
+type Comparison struct {
+	Base                  Run              `json:"base"`
+	Candidate             Run              `json:"candidate"`
+	RetrievalHitRateDelta *float64         `json:"retrieval_hit_rate_delta"`
+	AnswerScoreDelta      *float64         `json:"answer_score_delta"`
+	Improved              int              `json:"improved"`
+	Regressed             int              `json:"regressed"`
+	Cases                 []CaseComparison `json:"cases"`
+}
+
+// CaseComparison compares the results of a question present in both runs
+type CaseComparison struct {
+	Question              string  `json:"question"`
+	BaseScore             float64 `json:"base_score"`
+	CandidateScore        float64 `json:"candidate_score"`
+	Delta                 float64 `json:"delta"`
+	BaseRetrievalHit      *bool   `json:"base_retrieval_hit"`
+	CandidateRetrievalHit *bool   `json:"candidate_retrieval_hit"`
+}
+
+// Source is a knowledge base file used for an answer
+type Source struct {
+	Filename string `json:"filename"`
+	S3Key    string `json:"s3_key"`
+}
+
+// GeneratedAnswer is the knowledge base answer to a case question, with the files
+// retrieval returned and the files the answer cited
+type GeneratedAnswer struct {
+	Text      string
+	Retrieved []Source
+	Cited     []Source
+}
+
+// Grade is a judge's score of an answer from 0 to 1 and why
+type Grade struct {
+	Score     float64
+	Reasoning string
+}
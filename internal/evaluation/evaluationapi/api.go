@@ -0,0 +1,187 @@
+package evaluationapi
+
+import (
+	"strconv"
+
+	"github.com/Abraxas-365/opd/internal/evaluation"
+	"github.com/Abraxas-365/opd/internal/evaluation/evaluationsrv"
+	"github.com/Abraxas-365/opd/internal/user"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/Abraxas-365/toolkit/pkg/lucia"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the admin routes to manage evaluation datasets, run them and compare runs
+func SetupRoutes(app *fiber.App, service *evaluationsrv.Service, authMiddleware *lucia.AuthMiddleware[*user.User]) {
+	evaluations := app.Group("/evaluations", authMiddleware.RequireAuth())
+
+	evaluations.Get("/datasets", func(c *fiber.Ctx) error {
+		page, pageSize, err := parsePagination(c)
+		if err != nil {
+			return err
+		}
+
+		datasets, err := service.GetDatasets(c.Context(), page, pageSize)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(datasets)
+	})
+
+	evaluations.Get("/datasets/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Dataset id must be a number")
+		}
+
+		d, err := service.GetDataset(c.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(d)
+	})
+
+	evaluations.Post("/datasets", func(c *fiber.Ctx) error {
+		var d evaluation.Dataset
+		if err := c.BodyParser(&d); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		created, err := service.CreateDataset(c.Context(), d)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(created)
+	})
+
+	evaluations.Put("/datasets/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Dataset id must be a number")
+		}
+
+		var d evaluation.Dataset
+		if err := c.BodyParser(&d); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+		d.ID = id
+
+		updated, err := service.UpdateDataset(c.Context(), d)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(updated)
+	})
+
+	evaluations.Delete("/datasets/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Dataset id must be a number")
+		}
+
+		if err := service.DeleteDataset(c.Context(), id); err != nil {
+			return err
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	evaluations.Get("/datasets/:id/runs", func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Dataset id must be a number")
+		}
+
+		page, pageSize, err := parsePagination(c)
+		if err != nil {
+			return err
+		}
+
+		runs, err := service.GetRuns(c.Context(), id, page, pageSize)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(runs)
+	})
+
+	// Start a run in the background, poll /evaluations/runs/:id for its results
+	evaluations.Post("/datasets/:id/runs", func(c *fiber.Ctx) error {
+		type Request struct {
+			Grader string            `json:"grader"`
+			Target evaluation.Target `json:"target"`
+		}
+
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Dataset id must be a number")
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		userID, err := lucia.GetSession(c).UserIDToString()
+		if err != nil {
+			return err
+		}
+
+		run, err := service.StartRun(c.Context(), id, req.Target, req.Grader, &userID)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusAccepted).JSON(run)
+	})
+
+	evaluations.Get("/runs/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Run id must be a number")
+		}
+
+		run, err := service.GetRun(c.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(run)
+	})
+
+	evaluations.Get("/compare", func(c *fiber.Ctx) error {
+		base, err := strconv.Atoi(c.Query("base"))
+		if err != nil {
+			return errors.ErrBadRequest("base must be a run id")
+		}
+
+		candidate, err := strconv.Atoi(c.Query("candidate"))
+		if err != nil {
+			return errors.ErrBadRequest("candidate must be a run id")
+		}
+
+		comparison, err := service.Compare(c.Context(), base, candidate)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(comparison)
+	})
+}
+
+func parsePagination(c *fiber.Ctx) (int, int, error) {
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return 0, 0, errors.ErrBadRequest("Invalid page number")
+	}
+
+	pageSize, err := strconv.Atoi(c.Query("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		return 0, 0, errors.ErrBadRequest("Invalid page size")
+	}
+	return page, pageSize, nil
+}
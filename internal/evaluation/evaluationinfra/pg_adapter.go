@@ -0,0 +1,396 @@
+package evaluationinfra
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Abraxas-365/opd/internal/evaluation"
+	"github.com/Abraxas-365/toolkit/pkg/database"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	datasetColumns = `id, name, description, created_at, updated_at`
+	caseColumns    = `id, dataset_id, question, expected_answer, expected_sources`
+	runColumns     = `id, dataset_id, status, grader, model_id, prompt, number_of_results, case_count, retrieval_hit_rate, answer_score, error, created_by, started_at, completed_at`
+	resultColumns  = `id, run_id, question, expected_answer, expected_sources, answer, retrieved_sources, cited_sources, retrieval_hit, similarity, judge_score, judge_reasoning, score, error`
+)
+
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewEvaluationStore creates a new PostgresStore for evaluation repository
+func NewEvaluationStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDataset(row rowScanner) (*evaluation.Dataset, error) {
+	var d evaluation.Dataset
+	if err := row.Scan(&d.ID, &d.Name, &d.Description, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func scanCase(row rowScanner) (*evaluation.Case, error) {
+	var c evaluation.Case
+	if err := row.Scan(&c.ID, &c.DatasetID, &c.Question, &c.ExpectedAnswer, pq.Array(&c.ExpectedSources)); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func scanRun(row rowScanner) (*evaluation.Run, error) {
+	var r evaluation.Run
+	err := row.Scan(
+		&r.ID,
+		&r.DatasetID,
+		&r.Status,
+		&r.Grader,
+		&r.Target.ModelID,
+		&r.Target.Prompt,
+		&r.Target.NumberOfResults,
+		&r.CaseCount,
+		&r.RetrievalHitRate,
+		&r.AnswerScore,
+		&r.Error,
+		&r.CreatedBy,
+		&r.StartedAt,
+		&r.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func scanResult(row rowScanner) (*evaluation.Result, error) {
+	var r evaluation.Result
+	err := row.Scan(
+		&r.ID,
+		&r.RunID,
+		&r.Question,
+		&r.ExpectedAnswer,
+		pq.Array(&r.ExpectedSources),
+		&r.Answer,
+		pq.Array(&r.RetrievedSources),
+		pq.Array(&r.CitedSources),
+		&r.RetrievalHit,
+		&r.Similarity,
+		&r.JudgeScore,
+		&r.JudgeReasoning,
+		&r.Score,
+		&r.Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateDataset inserts a dataset and its cases
+func (s *PostgresStore) CreateDataset(ctx context.Context, d evaluation.Dataset) (*evaluation.Dataset, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to start transaction: %v", err))
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO eval_datasets (name, description)
+		VALUES ($1, $2)
+		RETURNING ` + datasetColumns
+
+	created, err := scanDataset(tx.QueryRowContext(ctx, query, d.Name, d.Description))
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create dataset: %v", err))
+	}
+
+	if created.Cases, err = insertCases(ctx, tx, created.ID, d.Cases); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to commit dataset: %v", err))
+	}
+	return created, nil
+}
+
+func insertCases(ctx context.Context, tx *sqlx.Tx, datasetID int, cases []evaluation.Case) ([]evaluation.Case, error) {
+	query := `
+		INSERT INTO eval_cases (dataset_id, question, expected_answer, expected_sources)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + caseColumns
+
+	inserted := make([]evaluation.Case, 0, len(cases))
+	for _, c := range cases {
+		created, err := scanCase(tx.QueryRowContext(ctx, query, datasetID, c.Question, c.ExpectedAnswer, pq.Array(c.ExpectedSources)))
+		if err != nil {
+			return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create case: %v", err))
+		}
+		inserted = append(inserted, *created)
+	}
+	return inserted, nil
+}
+
+// GetDataset retrieves a dataset by ID with its cases
+func (s *PostgresStore) GetDataset(ctx context.Context, id int) (*evaluation.Dataset, error) {
+	query := `SELECT ` + datasetColumns + ` FROM eval_datasets WHERE id = $1`
+
+	d, err := scanDataset(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("Dataset not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get dataset: %v", err))
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+caseColumns+` FROM eval_cases WHERE dataset_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get cases: %v", err))
+	}
+	defer rows.Close()
+
+	d.Cases = []evaluation.Case{}
+	for rows.Next() {
+		c, err := scanCase(rows)
+		if err != nil {
+			return nil, errors.ErrDatabase(fmt.Sprintf("Failed to scan case: %v", err))
+		}
+		d.Cases = append(d.Cases, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Error iterating cases: %v", err))
+	}
+	return d, nil
+}
+
+// GetDatasets retrieves a paginated list of datasets, without their cases
+func (s *PostgresStore) GetDatasets(ctx context.Context, page, pageSize int) (database.PaginatedRecord[evaluation.Dataset], error) {
+	offset := (page - 1) * pageSize
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+datasetColumns+` FROM eval_datasets ORDER BY id DESC LIMIT $1 OFFSET $2`, pageSize, offset)
+	if err != nil {
+		return database.PaginatedRecord[evaluation.Dataset]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get datasets: %v", err))
+	}
+	defer rows.Close()
+
+	datasets := []evaluation.Dataset{}
+	for rows.Next() {
+		d, err := scanDataset(rows)
+		if err != nil {
+			return database.PaginatedRecord[evaluation.Dataset]{}, errors.ErrDatabase(fmt.Sprintf("Failed to scan dataset: %v", err))
+		}
+		datasets = append(datasets, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return database.PaginatedRecord[evaluation.Dataset]{}, errors.ErrDatabase(fmt.Sprintf("Error iterating datasets: %v", err))
+	}
+
+	var total int
+	if err := s.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM eval_datasets`); err != nil {
+		return database.PaginatedRecord[evaluation.Dataset]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get total count: %v", err))
+	}
+
+	return database.PaginatedRecord[evaluation.Dataset]{
+		Data:       datasets,
+		PageNumber: page,
+		PageSize:   pageSize,
+		Total:      total,
+	}, nil
+}
+
+// UpdateDataset replaces the name, description and cases of a dataset
+func (s *PostgresStore) UpdateDataset(ctx context.Context, d evaluation.Dataset) (*evaluation.Dataset, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to start transaction: %v", err))
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE eval_datasets
+		SET name = $2, description = $3
+		WHERE id = $1
+		RETURNING ` + datasetColumns
+
+	updated, err := scanDataset(tx.QueryRowContext(ctx, query, d.ID, d.Name, d.Description))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("Dataset not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to update dataset: %v", err))
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM eval_cases WHERE dataset_id = $1`, d.ID); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to delete cases: %v", err))
+	}
+	if updated.Cases, err = insertCases(ctx, tx, d.ID, d.Cases); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to commit dataset: %v", err))
+	}
+	return updated, nil
+}
+
+// DeleteDataset deletes a dataset with its cases and runs
+func (s *PostgresStore) DeleteDataset(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM eval_datasets WHERE id = $1`, id)
+	if err != nil {
+		return errors.ErrDatabase(fmt.Sprintf("Failed to delete dataset: %v", err))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.ErrDatabase(fmt.Sprintf("Failed to get affected rows: %v", err))
+	}
+	if rows == 0 {
+		return errors.ErrNotFound("Dataset not found")
+	}
+	return nil
+}
+
+// CreateRun inserts a running run
+func (s *PostgresStore) CreateRun(ctx context.Context, r evaluation.Run) (*evaluation.Run, error) {
+	query := `
+		INSERT INTO eval_runs (dataset_id, status, grader, model_id, prompt, number_of_results, case_count, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + runColumns
+
+	created, err := scanRun(s.db.QueryRowContext(ctx, query,
+		r.DatasetID, r.Status, r.Grader, r.Target.ModelID, r.Target.Prompt, r.Target.NumberOfResults, r.CaseCount, r.CreatedBy))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return nil, errors.ErrNotFound("Dataset not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create run: %v", err))
+	}
+	return created, nil
+}
+
+// FinishRun saves the outcome of a run
+func (s *PostgresStore) FinishRun(ctx context.Context, r evaluation.Run) (*evaluation.Run, error) {
+	query := `
+		UPDATE eval_runs
+		SET status = $2, retrieval_hit_rate = $3, answer_score = $4, error = $5, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + runColumns
+
+	updated, err := scanRun(s.db.QueryRowContext(ctx, query, r.ID, r.Status, r.RetrievalHitRate, r.AnswerScore, r.Error))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("Run not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to finish run: %v", err))
+	}
+	return updated, nil
+}
+
+// GetRun retrieves a run by ID
+func (s *PostgresStore) GetRun(ctx context.Context, id int) (*evaluation.Run, error) {
+	r, err := scanRun(s.db.QueryRowContext(ctx, `SELECT `+runColumns+` FROM eval_runs WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("Run not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get run: %v", err))
+	}
+	return r, nil
+}
+
+// GetRuns retrieves the runs of a dataset, newest first
+func (s *PostgresStore) GetRuns(ctx context.Context, datasetID, page, pageSize int) (database.PaginatedRecord[evaluation.Run], error) {
+	offset := (page - 1) * pageSize
+
+	query := `SELECT ` + runColumns + ` FROM eval_runs WHERE dataset_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`
+	rows, err := s.db.QueryContext(ctx, query, datasetID, pageSize, offset)
+	if err != nil {
+		return database.PaginatedRecord[evaluation.Run]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get runs: %v", err))
+	}
+	defer rows.Close()
+
+	runs := []evaluation.Run{}
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return database.PaginatedRecord[evaluation.Run]{}, errors.ErrDatabase(fmt.Sprintf("Failed to scan run: %v", err))
+		}
+		runs = append(runs, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return database.PaginatedRecord[evaluation.Run]{}, errors.ErrDatabase(fmt.Sprintf("Error iterating runs: %v", err))
+	}
+
+	var total int
+	if err := s.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM eval_runs WHERE dataset_id = $1`, datasetID); err != nil {
+		return database.PaginatedRecord[evaluation.Run]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get total count: %v", err))
+	}
+
+	return database.PaginatedRecord[evaluation.Run]{
+		Data:       runs,
+		PageNumber: page,
+		PageSize:   pageSize,
+		Total:      total,
+	}, nil
+}
+
+// CreateResult inserts the result of a case
+func (s *PostgresStore) CreateResult(ctx context.Context, r evaluation.Result) (*evaluation.Result, error) {
+	query := `
+		INSERT INTO eval_results (run_id, question, expected_answer, expected_sources, answer, retrieved_sources, cited_sources,
+			retrieval_hit, similarity, judge_score, judge_reasoning, score, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING ` + resultColumns
+
+	created, err := scanResult(s.db.QueryRowContext(ctx, query,
+		r.RunID,
+		r.Question,
+		r.ExpectedAnswer,
+		pq.Array(r.ExpectedSources),
+		r.Answer,
+		pq.Array(r.RetrievedSources),
+		pq.Array(r.CitedSources),
+		r.RetrievalHit,
+		r.Similarity,
+		r.JudgeScore,
+		r.JudgeReasoning,
+		r.Score,
+		r.Error,
+	))
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create result: %v", err))
+	}
+	return created, nil
+}
+
+// GetResults retrieves the results of a run in case order
+func (s *PostgresStore) GetResults(ctx context.Context, runID int) ([]evaluation.Result, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+resultColumns+` FROM eval_results WHERE run_id = $1 ORDER BY id`, runID)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get results: %v", err))
+	}
+	defer rows.Close()
+
+	results := []evaluation.Result{}
+	for rows.Next() {
+		r, err := scanResult(rows)
+		if err != nil {
+			return nil, errors.ErrDatabase(fmt.Sprintf("Failed to scan result: %v", err))
+		}
+		results = append(results, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Error iterating results: %v", err))
+	}
+	return results, nil
+}
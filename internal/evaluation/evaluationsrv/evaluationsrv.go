@@ -0,0 +1,375 @@
+package evaluationsrv
+
+import (
+	"context"
+	"log"
+	"math"
+	"path"
+	"strings"
+
+	"github.com/Abraxas-365/opd/internal/evaluation"
+	"github.com/Abraxas-365/opd/pkg/textsim"
+	"github.com/Abraxas-365/toolkit/pkg/database"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// changeMargin is how much a case score has to move between runs to count as improved or regressed
+const changeMargin = 0.05
+
+type Service struct {
+	repo     evaluation.Repository
+	answerer evaluation.Answerer
+	judge    evaluation.Judge
+}
+
+func New(repo evaluation.Repository, answerer evaluation.Answerer, judge evaluation.Judge) *Service {
+	return &Service{
+		repo:     repo,
+		answerer: answerer,
+		judge:    judge,
+	}
+}
+
+func (s *Service) CreateDataset(ctx context.Context, d evaluation.Dataset) (*evaluation.Dataset, error) {
+	if err := validate(&d); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateDataset(ctx, d)
+}
+
+func (s *Service) GetDataset(ctx context.Context, id int) (*evaluation.Dataset, error) {
+	return s.repo.GetDataset(ctx, id)
+}
+
+func (s *Service) GetDatasets(ctx context.Context, page, pageSize int) (database.PaginatedRecord[evaluation.Dataset], error) {
+	return s.repo.GetDatasets(ctx, page, pageSize)
+}
+
+func (s *Service) UpdateDataset(ctx context.Context, d evaluation.Dataset) (*evaluation.Dataset, error) {
+	if err := validate(&d); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateDataset(ctx, d)
+}
+
+func (s *Service) DeleteDataset(ctx context.Context, id int) error {
+	return s.repo.DeleteDataset(ctx, id)
+}
+
+func (s *Service) GetRuns(ctx context.Context, datasetID, page, pageSize int) (database.PaginatedRecord[evaluation.Run], error) {
+	return s.repo.GetRuns(ctx, datasetID, page, pageSize)
+}
+
+// GetRun returns a run with the result of every case answered so far
+func (s *Service) GetRun(ctx context.Context, id int) (*evaluation.RunWithResults, error) {
+	r, err := s.repo.GetRun(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.repo.GetResults(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &evaluation.RunWithResults{Run: *r, Results: results}, nil
+}
+
+// StartRun creates a run of the dataset and answers its cases in the background,
+// poll GetRun until the status is no longer running
+func (s *Service) StartRun(ctx context.Context, datasetID int, target evaluation.Target, grader string, createdBy *string) (*evaluation.Run, error) {
+	run, dataset, err := s.createRun(ctx, datasetID, target, grader, createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		if _, err := s.execute(context.Background(), *run, dataset.Cases); err != nil {
+			log.Printf("evaluation: run %d failed: %v", run.ID, err)
+		}
+	}()
+
+	return run, nil
+}
+
+// Run answers every case of the dataset and returns the finished run
+func (s *Service) Run(ctx context.Context, datasetID int, target evaluation.Target, grader string, createdBy *string) (*evaluation.RunWithResults, error) {
+	run, dataset, err := s.createRun(ctx, datasetID, target, grader, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	return s.execute(ctx, *run, dataset.Cases)
+}
+
+func (s *Service) createRun(ctx context.Context, datasetID int, target evaluation.Target, grader string, createdBy *string) (*evaluation.Run, *evaluation.Dataset, error) {
+	switch grader {
+	case "":
+		grader = evaluation.GraderSimilarity
+	case evaluation.GraderSimilarity, evaluation.GraderLLMJudge:
+	default:
+		return nil, nil, errors.ErrBadRequest("grader must be " + evaluation.GraderSimilarity + " or " + evaluation.GraderLLMJudge)
+	}
+	if target.NumberOfResults < 0 {
+		return nil, nil, errors.ErrBadRequest("numberOfResults must be positive")
+	}
+
+	dataset, err := s.repo.GetDataset(ctx, datasetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(dataset.Cases) == 0 {
+		return nil, nil, errors.ErrBadRequest("dataset has no cases")
+	}
+
+	// Runs record the configuration they ran with, so later changes do not blur comparisons
+	target, err = s.answerer.ResolveTarget(ctx, target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	run, err := s.repo.CreateRun(ctx, evaluation.Run{
+		DatasetID: datasetID,
+		Status:    evaluation.RunRunning,
+		Grader:    grader,
+		Target:    target,
+		CaseCount: len(dataset.Cases),
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return run, dataset, nil
+}
+
+// execute answers and grades the cases one at a time, to stay under the Bedrock quotas.
+// A case that fails is recorded with its error and left out of the run metrics.
+func (s *Service) execute(ctx context.Context, run evaluation.Run, cases []evaluation.Case) (*evaluation.RunWithResults, error) {
+	results := make([]evaluation.Result, 0, len(cases))
+	for _, c := range cases {
+		result, err := s.repo.CreateResult(ctx, s.evaluateCase(ctx, run, c))
+		if err != nil {
+			return nil, s.fail(ctx, run, err)
+		}
+		results = append(results, *result)
+	}
+
+	run.Status = evaluation.RunCompleted
+	run.RetrievalHitRate, run.AnswerScore = metrics(results)
+	finished, err := s.repo.FinishRun(ctx, run)
+	if err != nil {
+		return nil, err
+	}
+
+	return &evaluation.RunWithResults{Run: *finished, Results: results}, nil
+}
+
+// fail marks the run as failed and returns the error that stopped it
+func (s *Service) fail(ctx context.Context, run evaluation.Run, cause error) error {
+	message := cause.Error()
+	run.Status = evaluation.RunFailed
+	run.Error = &message
+	if _, err := s.repo.FinishRun(ctx, run); err != nil {
+		log.Printf("evaluation: failed to mark run %d as failed: %v", run.ID, err)
+	}
+	return cause
+}
+
+func (s *Service) evaluateCase(ctx context.Context, run evaluation.Run, c evaluation.Case) evaluation.Result {
+	result := evaluation.Result{
+		RunID:            run.ID,
+		Question:         c.Question,
+		ExpectedAnswer:   c.ExpectedAnswer,
+		ExpectedSources:  c.ExpectedSources,
+		RetrievedSources: []string{},
+		CitedSources:     []string{},
+	}
+
+	answer, err := s.answerer.AnswerForEvaluation(ctx, run.Target, c.Question)
+	if err != nil {
+		message := err.Error()
+		result.Error = &message
+		return result
+	}
+
+	result.Answer = answer.Text
+	result.RetrievedSources = filenames(answer.Retrieved)
+	result.CitedSources = filenames(answer.Cited)
+	if len(c.ExpectedSources) > 0 {
+		hit := anySourceMatches(c.ExpectedSources, answer.Retrieved)
+		result.RetrievalHit = &hit
+	}
+
+	// Cases that only check retrieval have no answer to grade
+	if c.ExpectedAnswer == "" {
+		return result
+	}
+
+	result.Similarity = textsim.Similarity(c.ExpectedAnswer, answer.Text)
+	result.Score = result.Similarity
+
+	if run.Grader == evaluation.GraderLLMJudge {
+		grade, err := s.judge.Grade(ctx, c.Question, c.ExpectedAnswer, answer.Text)
+		if err != nil {
+			message := err.Error()
+			result.Error = &message
+			return result
+		}
+		result.JudgeScore = &grade.Score
+		result.JudgeReasoning = grade.Reasoning
+		result.Score = grade.Score
+	}
+
+	return result
+}
+
+// Compare shows how the candidate run did against the base run on the questions both answered
+func (s *Service) Compare(ctx context.Context, baseID, candidateID int) (*evaluation.Comparison, error) {
+	base, err := s.GetRun(ctx, baseID)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := s.GetRun(ctx, candidateID)
+	if err != nil {
+		return nil, err
+	}
+	if base.Status != evaluation.RunCompleted || candidate.Status != evaluation.RunCompleted {
+		return nil, errors.ErrConflict("Both runs must be completed")
+	}
+
+	comparison := &evaluation.Comparison{
+		Base:                  base.Run,
+		Candidate:             candidate.Run,
+		RetrievalHitRateDelta: delta(base.RetrievalHitRate, candidate.RetrievalHitRate),
+		AnswerScoreDelta:      delta(base.AnswerScore, candidate.AnswerScore),
+		Cases:                 []evaluation.CaseComparison{},
+	}
+
+	// Cases are matched by question, dataset edits between runs renumber them
+	baseResults := make(map[string]evaluation.Result)
+	for _, r := range base.Results {
+		if r.Error == nil {
+			baseResults[textsim.Normalize(r.Question)] = r
+		}
+	}
+	for _, r := range candidate.Results {
+		b, ok := baseResults[textsim.Normalize(r.Question)]
+		if !ok || r.Error != nil {
+			continue
+		}
+
+		c := evaluation.CaseComparison{
+			Question:              r.Question,
+			BaseScore:             b.Score,
+			CandidateScore:        r.Score,
+			Delta:                 r.Score - b.Score,
+			BaseRetrievalHit:      b.RetrievalHit,
+			CandidateRetrievalHit: r.RetrievalHit,
+		}
+		switch {
+		case c.Delta >= changeMargin:
+			comparison.Improved++
+		case c.Delta <= -changeMargin:
+			comparison.Regressed++
+		}
+		comparison.Cases = append(comparison.Cases, c)
+	}
+
+	return comparison, nil
+}
+
+// metrics returns the retrieval hit rate and the mean score of the cases that did not fail,
+// nil when no case counts towards them. Only cases with an expected answer are scored.
+func metrics(results []evaluation.Result) (*float64, *float64) {
+	var hits, withSources, scored int
+	var total float64
+	for _, r := range results {
+		if r.Error != nil {
+			continue
+		}
+		if r.ExpectedAnswer != "" {
+			scored++
+			total += r.Score
+		}
+		if r.RetrievalHit != nil {
+			withSources++
+			if *r.RetrievalHit {
+				hits++
+			}
+		}
+	}
+
+	var hitRate, score *float64
+	if withSources > 0 {
+		rate := float64(hits) / float64(withSources)
+		hitRate = &rate
+	}
+	if scored > 0 {
+		mean := total / float64(scored)
+		score = &mean
+	}
+	return hitRate, score
+}
+
+func delta(base, candidate *float64) *float64 {
+	if base == nil || candidate == nil {
+		return nil
+	}
+	d := math.Round((*candidate-*base)*10000) / 10000
+	return &d
+}
+
+func filenames(sources []evaluation.Source) []string {
+	names := make([]string, 0, len(sources))
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		if seen[source.Filename] {
+			continue
+		}
+		seen[source.Filename] = true
+		names = append(names, source.Filename)
+	}
+	return names
+}
+
+// anySourceMatches reports whether one of the expected files, by name or S3 key, was used
+func anySourceMatches(expected []string, sources []evaluation.Source) bool {
+	for _, e := range expected {
+		for _, source := range sources {
+			if strings.EqualFold(e, source.Filename) || strings.EqualFold(e, source.S3Key) || strings.EqualFold(e, path.Base(source.S3Key)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validate checks the required fields and drops blank cases and sources
+func validate(d *evaluation.Dataset) error {
+	d.Name = strings.TrimSpace(d.Name)
+	if d.Name == "" {
+		return errors.ErrBadRequest("name is required")
+	}
+
+	cases := make([]evaluation.Case, 0, len(d.Cases))
+	for _, c := range d.Cases {
+		c.Question = strings.TrimSpace(c.Question)
+		c.ExpectedAnswer = strings.TrimSpace(c.ExpectedAnswer)
+		if c.Question == "" {
+			continue
+		}
+		if c.ExpectedAnswer == "" && len(c.ExpectedSources) == 0 {
+			return errors.ErrBadRequest("case \"" + c.Question + "\" needs an expected answer or expected sources")
+		}
+
+		sources := make([]string, 0, len(c.ExpectedSources))
+		for _, source := range c.ExpectedSources {
+			if source = strings.TrimSpace(source); source != "" {
+				sources = append(sources, source)
+			}
+		}
+		c.ExpectedSources = sources
+		cases = append(cases, c)
+	}
+	d.Cases = cases
+	return nil
+}
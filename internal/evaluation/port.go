@@ -0,0 +1,38 @@
+package evaluation
+
+import (
+	"context"
+
+	"github.com/Abraxas-365/toolkit/pkg/database"
+)
+
+type Repository interface {
+	// CreateDataset inserts the dataset with its cases
+	CreateDataset(ctx context.Context, d Dataset) (*Dataset, error)
+	// GetDataset returns the dataset with its cases
+	GetDataset(ctx context.Context, id int) (*Dataset, error)
+	GetDatasets(ctx context.Context, page, pageSize int) (database.PaginatedRecord[Dataset], error)
+	// UpdateDataset replaces the dataset and its cases
+	UpdateDataset(ctx context.Context, d Dataset) (*Dataset, error)
+	DeleteDataset(ctx context.Context, id int) error
+
+	CreateRun(ctx context.Context, r Run) (*Run, error)
+	// FinishRun saves the status, metrics and error of a run and sets its completion time
+	FinishRun(ctx context.Context, r Run) (*Run, error)
+	GetRun(ctx context.Context, id int) (*Run, error)
+	GetRuns(ctx context.Context, datasetID, page, pageSize int) (database.PaginatedRecord[Run], error)
+	CreateResult(ctx context.Context, r Result) (*Result, error)
+	GetResults(ctx context.Context, runID int) ([]Result, error)
+}
+
+// Answerer answers case questions with the knowledge base, outside any chat conversation
+type Answerer interface {
+	// ResolveTarget fills the empty fields of a target with the current knowledge base configuration
+	ResolveTarget(ctx context.Context, target Target) (Target, error)
+	AnswerForEvaluation(ctx context.Context, target Target, question string) (*GeneratedAnswer, error)
+}
+
+// Judge grades an answer against the expected answer
+type Judge interface {
+	Grade(ctx context.Context, question, expectedAnswer, answer string) (*Grade, error)
+}
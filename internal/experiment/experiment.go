@@ -0,0 +1,61 @@
+package experiment
+
+import (
+	"hash/fnv"
+	"strconv"
+	"time"
+)
+
+// Experiment statuses
+const (
+	StatusRunning = "running"
+	StatusStopped = "stopped"
+)
+
+// Experiment splits chat users across variants of the knowledge base configuration
+type Experiment struct {
+	ID              int        `json:"id" db:"id"`
+	Name            string     `json:"name" db:"name"`
+	Status          string     `json:"status" db:"status"`
+	WinnerVariantID *int       `json:"winner_variant_id" db:"winner_variant_id"`
+	Variants        []Variant  `json:"variants" db:"-"`
+	CreatedBy       *string    `json:"created_by" db:"created_by"`
+	StartedAt       time.Time  `json:"started_at" db:"started_at"`
+	StoppedAt       *time.Time `json:"stopped_at" db:"stopped_at"`
+}
+
+// Variant is a knowledge base configuration under test, empty fields keep the current one.
+// Weight is the share of chat users it gets relative to the other variants.
+type Variant struct {
+	ID              int    `json:"id" db:"id"`
+	ExperimentID    int    `json:"experiment_id" db:"experiment_id"`
+	Name            string `json:"name" db:"name"`
+	ModelID         string `json:"modelId" db:"model_id"`
+	Prompt          string `json:"prompt" db:"prompt"`
+	NumberOfResults int    `json:"numberOfResults" db:"number_of_results"`
+	Weight          int    `json:"weight" db:"weight"`
+}
+
+// Assign picks the variant of a chat user. The same chat user always gets the same
+// variant of an experiment, so a conversation does not switch models halfway.
+func (e Experiment) Assign(chatUserID string) *Variant {
+	total := 0
+	for _, v := range e.Variants {
+		total += v.Weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(strconv.Itoa(e.ID) + ":" + chatUserID))
+	bucket := int(h.Sum32() % uint32(total))
+
+	for i, v := range e.Variants {
+		if bucket < v.Weight {
+			return &e.Variants[i]
+		}
+		bucket -= v.Weight
+	}
+	return nil
+}
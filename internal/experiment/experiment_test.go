@@ -0,0 +1,63 @@
+package experiment
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestAssign(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []Variant
+		want     map[int]bool
+	}{
+		{"no variants", nil, map[int]bool{}},
+		{"zero weights", []Variant{{ID: 1}, {ID: 2}}, map[int]bool{}},
+		{"single variant", []Variant{{ID: 1, Weight: 3}}, map[int]bool{1: true}},
+		{"zero weight variant is never picked", []Variant{{ID: 1, Weight: 1}, {ID: 2}, {ID: 3, Weight: 1}}, map[int]bool{1: true, 3: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Experiment{ID: 7, Variants: tt.variants}
+			for i := 0; i < 200; i++ {
+				v := e.Assign("chat-user-" + strconv.Itoa(i))
+				if v == nil {
+					if len(tt.want) > 0 {
+						t.Fatalf("Assign returned no variant, want one of %v", tt.want)
+					}
+					continue
+				}
+				if !tt.want[v.ID] {
+					t.Fatalf("Assign returned variant %d, want one of %v", v.ID, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAssignIsStable(t *testing.T) {
+	e := Experiment{ID: 1, Variants: []Variant{{ID: 1, Weight: 1}, {ID: 2, Weight: 1}}}
+	for i := 0; i < 50; i++ {
+		chatUserID := "chat-user-" + strconv.Itoa(i)
+		first := e.Assign(chatUserID)
+		for j := 0; j < 5; j++ {
+			if got := e.Assign(chatUserID); got.ID != first.ID {
+				t.Fatalf("chat user %s switched from variant %d to %d", chatUserID, first.ID, got.ID)
+			}
+		}
+	}
+}
+
+func TestAssignFollowsWeights(t *testing.T) {
+	e := Experiment{ID: 3, Variants: []Variant{{ID: 1, Weight: 1}, {ID: 2, Weight: 3}}}
+	counts := make(map[int]int)
+	const chatUsers = 10000
+	for i := 0; i < chatUsers; i++ {
+		counts[e.Assign("chat-user-"+strconv.Itoa(i)).ID]++
+	}
+
+	// 25% and 75%, with room for the hash not being perfectly uniform
+	if share := float64(counts[1]) / chatUsers; share < 0.22 || share > 0.28 {
+		t.Errorf("variant 1 got %.2f of the chat users, want about 0.25", share)
+	}
+}
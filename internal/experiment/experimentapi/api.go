@@ -0,0 +1,99 @@
+package experimentapi
+
+import (
+	"strconv"
+
+	"github.com/Abraxas-365/opd/internal/experiment"
+	"github.com/Abraxas-365/opd/internal/experiment/experimentsrv"
+	"github.com/Abraxas-365/opd/internal/user"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/Abraxas-365/toolkit/pkg/lucia"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the admin routes to run A/B experiments
+func SetupRoutes(app *fiber.App, service *experimentsrv.Service, authMiddleware *lucia.AuthMiddleware[*user.User]) {
+	app.Get("/experiments", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		page, err := strconv.Atoi(c.Query("page", "1"))
+		if err != nil || page < 1 {
+			return errors.ErrBadRequest("Invalid page number")
+		}
+
+		pageSize, err := strconv.Atoi(c.Query("pageSize", "10"))
+		if err != nil || pageSize < 1 {
+			return errors.ErrBadRequest("Invalid page size")
+		}
+
+		experiments, err := service.GetExperiments(c.Context(), page, pageSize)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(experiments)
+	})
+
+	app.Get("/experiments/:id", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Experiment id must be a number")
+		}
+
+		e, err := service.GetExperiment(c.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(e)
+	})
+
+	// Create and start an experiment
+	app.Post("/experiments", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		var e experiment.Experiment
+		if err := c.BodyParser(&e); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		userID, err := lucia.GetSession(c).UserIDToString()
+		if err != nil {
+			return err
+		}
+
+		created, err := service.CreateExperiment(c.Context(), e, userID)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(created)
+	})
+
+	// Stop an experiment, promoting the winner variant when one is given
+	app.Post("/experiments/:id/stop", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		type Request struct {
+			WinnerVariantID *int `json:"winnerVariantId"`
+		}
+
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Experiment id must be a number")
+		}
+
+		var req Request
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+			}
+		}
+
+		userID, err := lucia.GetSession(c).UserIDToString()
+		if err != nil {
+			return err
+		}
+
+		e, err := service.Stop(c.Context(), id, req.WinnerVariantID, userID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(e)
+	})
+}
@@ -0,0 +1,181 @@
+package experimentinfra
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Abraxas-365/opd/internal/experiment"
+	"github.com/Abraxas-365/opd/internal/kb"
+	"github.com/Abraxas-365/toolkit/pkg/database"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	experimentColumns = `id, name, status, winner_variant_id, created_by, started_at, stopped_at`
+	variantColumns    = `id, experiment_id, name, model_id, prompt, number_of_results, weight`
+)
+
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewExperimentStore creates a new PostgresStore for experiment repository
+func NewExperimentStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// CreateExperiment inserts a running experiment and its variants
+func (s *PostgresStore) CreateExperiment(ctx context.Context, e experiment.Experiment) (*experiment.Experiment, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to start transaction: %v", err))
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO experiments (name, status, created_by)
+		VALUES ($1, $2, $3)
+		RETURNING ` + experimentColumns
+
+	var created experiment.Experiment
+	if err := tx.QueryRowxContext(ctx, query, e.Name, experiment.StatusRunning, e.CreatedBy).StructScan(&created); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, errors.ErrConflict("Another experiment is running, stop it first")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create experiment: %v", err))
+	}
+
+	variantQuery := `
+		INSERT INTO experiment_variants (experiment_id, name, model_id, prompt, number_of_results, weight)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + variantColumns
+
+	for _, v := range e.Variants {
+		var variant experiment.Variant
+		err := tx.QueryRowxContext(ctx, variantQuery, created.ID, v.Name, v.ModelID, v.Prompt, v.NumberOfResults, v.Weight).StructScan(&variant)
+		if err != nil {
+			return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create variant: %v", err))
+		}
+		created.Variants = append(created.Variants, variant)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to commit experiment: %v", err))
+	}
+	return &created, nil
+}
+
+// GetExperiment retrieves an experiment by ID with its variants
+func (s *PostgresStore) GetExperiment(ctx context.Context, id int) (*experiment.Experiment, error) {
+	return s.getExperiment(ctx, `SELECT `+experimentColumns+` FROM experiments WHERE id = $1`, id)
+}
+
+// GetRunningExperiment retrieves the running experiment with its variants
+func (s *PostgresStore) GetRunningExperiment(ctx context.Context) (*experiment.Experiment, error) {
+	return s.getExperiment(ctx, `SELECT `+experimentColumns+` FROM experiments WHERE status = $1`, experiment.StatusRunning)
+}
+
+func (s *PostgresStore) getExperiment(ctx context.Context, query string, args ...interface{}) (*experiment.Experiment, error) {
+	var e experiment.Experiment
+	if err := s.db.GetContext(ctx, &e, query, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("Experiment not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get experiment: %v", err))
+	}
+
+	variants, err := s.getVariants(ctx, e.ID)
+	if err != nil {
+		return nil, err
+	}
+	e.Variants = variants
+	return &e, nil
+}
+
+func (s *PostgresStore) getVariants(ctx context.Context, experimentID int) ([]experiment.Variant, error) {
+	variants := []experiment.Variant{}
+	query := `SELECT ` + variantColumns + ` FROM experiment_variants WHERE experiment_id = $1 ORDER BY id`
+	if err := s.db.SelectContext(ctx, &variants, query, experimentID); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get variants: %v", err))
+	}
+	return variants, nil
+}
+
+// GetExperiments retrieves a paginated list of experiments with their variants, newest first
+func (s *PostgresStore) GetExperiments(ctx context.Context, page, pageSize int) (database.PaginatedRecord[experiment.Experiment], error) {
+	offset := (page - 1) * pageSize
+
+	experiments := []experiment.Experiment{}
+	query := `SELECT ` + experimentColumns + ` FROM experiments ORDER BY id DESC LIMIT $1 OFFSET $2`
+	if err := s.db.SelectContext(ctx, &experiments, query, pageSize, offset); err != nil {
+		return database.PaginatedRecord[experiment.Experiment]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get experiments: %v", err))
+	}
+
+	for i := range experiments {
+		variants, err := s.getVariants(ctx, experiments[i].ID)
+		if err != nil {
+			return database.PaginatedRecord[experiment.Experiment]{}, err
+		}
+		experiments[i].Variants = variants
+	}
+
+	var total int
+	if err := s.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM experiments`); err != nil {
+		return database.PaginatedRecord[experiment.Experiment]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get total count: %v", err))
+	}
+
+	return database.PaginatedRecord[experiment.Experiment]{
+		Data:       experiments,
+		PageNumber: page,
+		PageSize:   pageSize,
+		Total:      total,
+	}, nil
+}
+
+// StopExperiment stops a running experiment and records its winner, the promoted settings are saved in the same transaction
+func (s *PostgresStore) StopExperiment(ctx context.Context, id int, winnerVariantID *int, promoted []kb.Setting) (*experiment.Experiment, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to start transaction: %v", err))
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE experiments
+		SET status = $2, winner_variant_id = $3, stopped_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $4
+		RETURNING ` + experimentColumns
+
+	var e experiment.Experiment
+	if err := tx.GetContext(ctx, &e, query, id, experiment.StatusStopped, winnerVariantID, experiment.StatusRunning); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrConflict("Experiment is not running")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to stop experiment: %v", err))
+	}
+
+	settingQuery := `
+		INSERT INTO settings (key, value, updated_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by`
+
+	for _, setting := range promoted {
+		if _, err := tx.ExecContext(ctx, settingQuery, setting.Key, setting.Value, setting.UpdatedBy); err != nil {
+			return nil, errors.ErrDatabase(fmt.Sprintf("Failed to promote setting %s: %v", setting.Key, err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to commit experiment: %v", err))
+	}
+
+	variants, err := s.getVariants(ctx, e.ID)
+	if err != nil {
+		return nil, err
+	}
+	e.Variants = variants
+	return &e, nil
+}
@@ -0,0 +1,147 @@
+package experimentsrv
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/Abraxas-365/opd/internal/experiment"
+	"github.com/Abraxas-365/opd/internal/kb"
+	"github.com/Abraxas-365/opd/internal/user/usersrv"
+	"github.com/Abraxas-365/toolkit/pkg/database"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+type Service struct {
+	repo        experiment.Repository
+	userService *usersrv.Service
+}
+
+func New(repo experiment.Repository, userService *usersrv.Service) *Service {
+	return &Service{
+		repo:        repo,
+		userService: userService,
+	}
+}
+
+// CreateExperiment starts splitting chat users across the variants, only one experiment runs at a time.
+// Only admins can start one.
+func (s *Service) CreateExperiment(ctx context.Context, e experiment.Experiment, userID string) (*experiment.Experiment, error) {
+	if err := s.requireAdmin(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := validate(&e); err != nil {
+		return nil, err
+	}
+	e.CreatedBy = &userID
+	return s.repo.CreateExperiment(ctx, e)
+}
+
+func (s *Service) GetExperiment(ctx context.Context, id int) (*experiment.Experiment, error) {
+	return s.repo.GetExperiment(ctx, id)
+}
+
+func (s *Service) GetExperiments(ctx context.Context, page, pageSize int) (database.PaginatedRecord[experiment.Experiment], error) {
+	return s.repo.GetExperiments(ctx, page, pageSize)
+}
+
+// Assign returns the variant that answers the chat user, or nil when no experiment is running
+func (s *Service) Assign(ctx context.Context, chatUserID string) (*experiment.Variant, error) {
+	e, err := s.repo.GetRunningExperiment(ctx)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return e.Assign(chatUserID), nil
+}
+
+// Stop ends the experiment, every chat user gets the knowledge base configuration again.
+// With a winner, its non empty fields become the knowledge base configuration. Only admins can stop one.
+func (s *Service) Stop(ctx context.Context, id int, winnerVariantID *int, userID string) (*experiment.Experiment, error) {
+	if err := s.requireAdmin(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	e, err := s.repo.GetExperiment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var promoted []kb.Setting
+	if winnerVariantID != nil {
+		var winner *experiment.Variant
+		for i, v := range e.Variants {
+			if v.ID == *winnerVariantID {
+				winner = &e.Variants[i]
+			}
+		}
+		if winner == nil {
+			return nil, errors.ErrBadRequest("winner is not a variant of the experiment")
+		}
+		promoted = promotedSettings(*winner, userID)
+	}
+
+	return s.repo.StopExperiment(ctx, id, winnerVariantID, promoted)
+}
+
+func (s *Service) requireAdmin(ctx context.Context, userID string) error {
+	u, err := s.userService.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !u.IsAdmin {
+		return errors.ErrForbidden("only admins can start and stop experiments")
+	}
+	return nil
+}
+
+// promotedSettings are the knowledge base settings a winner variant overrides
+func promotedSettings(v experiment.Variant, userID string) []kb.Setting {
+	var settings []kb.Setting
+	if v.ModelID != "" {
+		settings = append(settings, kb.Setting{Key: kb.SettingModelID, Value: v.ModelID, UpdatedBy: &userID})
+	}
+	if v.Prompt != "" {
+		settings = append(settings, kb.Setting{Key: kb.SettingPrompt, Value: v.Prompt, UpdatedBy: &userID})
+	}
+	if v.NumberOfResults > 0 {
+		settings = append(settings, kb.Setting{Key: kb.SettingNumberOfResults, Value: strconv.Itoa(v.NumberOfResults), UpdatedBy: &userID})
+	}
+	return settings
+}
+
+// validate checks the name and the variants, a missing weight counts as 1
+func validate(e *experiment.Experiment) error {
+	e.Name = strings.TrimSpace(e.Name)
+	if e.Name == "" {
+		return errors.ErrBadRequest("name is required")
+	}
+	if len(e.Variants) < 2 {
+		return errors.ErrBadRequest("an experiment needs at least two variants")
+	}
+
+	names := make(map[string]bool)
+	for i := range e.Variants {
+		v := &e.Variants[i]
+		v.Name = strings.TrimSpace(v.Name)
+		v.ModelID = strings.TrimSpace(v.ModelID)
+		v.Prompt = strings.TrimSpace(v.Prompt)
+		if v.Name == "" {
+			return errors.ErrBadRequest("every variant needs a name")
+		}
+		if names[v.Name] {
+			return errors.ErrBadRequest("variant names must be unique")
+		}
+		names[v.Name] = true
+
+		if v.NumberOfResults < 0 || v.Weight < 0 {
+			return errors.ErrBadRequest("numberOfResults and weight must be positive")
+		}
+		if v.Weight == 0 {
+			v.Weight = 1
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package experiment
+
+import (
+	"context"
+
+	"github.com/Abraxas-365/opd/internal/kb"
+
+	"github.com/Abraxas-365/toolkit/pkg/database"
+)
+
+type Repository interface {
+	// CreateExperiment inserts a running experiment with its variants
+	CreateExperiment(ctx context.Context, e Experiment) (*Experiment, error)
+	// GetExperiment returns the experiment with its variants
+	GetExperiment(ctx context.Context, id int) (*Experiment, error)
+	// GetRunningExperiment returns the experiment splitting the traffic with its variants
+	GetRunningExperiment(ctx context.Context) (*Experiment, error)
+	GetExperiments(ctx context.Context, page, pageSize int) (database.PaginatedRecord[Experiment], error)
+	// StopExperiment stops a running experiment, winnerVariantID is nil when nothing is promoted.
+	// The promoted knowledge base settings are saved in the same transaction.
+	StopExperiment(ctx context.Context, id int, winnerVariantID *int, promoted []kb.Setting) (*Experiment, error)
+}
@@ -0,0 +1,26 @@
+package faq
+
+import "time"
+
+// FAQ is a curated answer, with the approved wording, for a question and its paraphrases
+type FAQ struct {
+	ID            int       `json:"id" db:"id"`
+	Question      string    `json:"question" db:"question"`
+	Variants      []string  `json:"variants" db:"variants"`
+	Answer        string    `json:"answer" db:"answer"`
+	CitationTitle string    `json:"citation_title" db:"citation_title"`
+	CitationURL   string    `json:"citation_url" db:"citation_url"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Match is the FAQ closest to a message and how similar they are, from 0 to 1
+type Match struct {
+	FAQ   FAQ     `json:"faq"`
+	Score float64 `json:"score"`
+}
+
+// Phrasings returns the question followed by its paraphrase variants
+func (f FAQ) Phrasings() []string {
+	return append([]string{f.Question}, f.Variants...)
+}
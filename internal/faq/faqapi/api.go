@@ -0,0 +1,114 @@
+package faqapi
+
+import (
+	"strconv"
+
+	"github.com/Abraxas-365/opd/internal/faq"
+	"github.com/Abraxas-365/opd/internal/faq/faqsrv"
+	"github.com/Abraxas-365/opd/internal/user"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/Abraxas-365/toolkit/pkg/lucia"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the admin routes to curate FAQs
+func SetupRoutes(app *fiber.App, service *faqsrv.Service, authMiddleware *lucia.AuthMiddleware[*user.User]) {
+	app.Get("/faqs", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		page, err := strconv.Atoi(c.Query("page", "1"))
+		if err != nil || page < 1 {
+			return errors.ErrBadRequest("Invalid page number")
+		}
+
+		pageSize, err := strconv.Atoi(c.Query("pageSize", "10"))
+		if err != nil || pageSize < 1 {
+			return errors.ErrBadRequest("Invalid page size")
+		}
+
+		faqs, err := service.GetFAQs(c.Context(), page, pageSize)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(faqs)
+	})
+
+	app.Get("/faqs/:id", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("FAQ id must be a number")
+		}
+
+		f, err := service.GetFAQ(c.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(f)
+	})
+
+	app.Post("/faqs", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		var f faq.FAQ
+		if err := c.BodyParser(&f); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		created, err := service.CreateFAQ(c.Context(), f)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(created)
+	})
+
+	app.Put("/faqs/:id", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("FAQ id must be a number")
+		}
+
+		var f faq.FAQ
+		if err := c.BodyParser(&f); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+		f.ID = id
+
+		updated, err := service.UpdateFAQ(c.Context(), f)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(updated)
+	})
+
+	app.Delete("/faqs/:id", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("FAQ id must be a number")
+		}
+
+		if err := service.DeleteFAQ(c.Context(), id); err != nil {
+			return err
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	// Try a message against the FAQs without answering it, to tune variants
+	app.Post("/faqs/match", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		type Request struct {
+			Message string `json:"message"`
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		match, err := service.Match(c.Context(), req.Message)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{"match": match})
+	})
+}
@@ -0,0 +1,160 @@
+package faqinfra
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Abraxas-365/opd/internal/faq"
+	"github.com/Abraxas-365/toolkit/pkg/database"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const faqColumns = `id, question, variants, answer, citation_title, citation_url, created_at, updated_at`
+
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewFAQStore creates a new PostgresStore for faq repository
+func NewFAQStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanFAQ(row rowScanner) (*faq.FAQ, error) {
+	var f faq.FAQ
+	err := row.Scan(
+		&f.ID,
+		&f.Question,
+		pq.Array(&f.Variants),
+		&f.Answer,
+		&f.CitationTitle,
+		&f.CitationURL,
+		&f.CreatedAt,
+		&f.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// CreateFAQ inserts a new FAQ
+func (s *PostgresStore) CreateFAQ(ctx context.Context, f faq.FAQ) (*faq.FAQ, error) {
+	query := `
+		INSERT INTO faqs (question, variants, answer, citation_title, citation_url)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + faqColumns
+
+	created, err := scanFAQ(s.db.QueryRowContext(ctx, query, f.Question, pq.Array(f.Variants), f.Answer, f.CitationTitle, f.CitationURL))
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create FAQ: %v", err))
+	}
+	return created, nil
+}
+
+// GetFAQ retrieves a FAQ by ID
+func (s *PostgresStore) GetFAQ(ctx context.Context, id int) (*faq.FAQ, error) {
+	query := `SELECT ` + faqColumns + ` FROM faqs WHERE id = $1`
+
+	f, err := scanFAQ(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("FAQ not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get FAQ: %v", err))
+	}
+	return f, nil
+}
+
+// GetFAQs retrieves a paginated list of FAQs
+func (s *PostgresStore) GetFAQs(ctx context.Context, page, pageSize int) (database.PaginatedRecord[faq.FAQ], error) {
+	offset := (page - 1) * pageSize
+
+	query := `SELECT ` + faqColumns + ` FROM faqs ORDER BY id LIMIT $1 OFFSET $2`
+
+	faqs, err := s.selectFAQs(ctx, query, pageSize, offset)
+	if err != nil {
+		return database.PaginatedRecord[faq.FAQ]{}, err
+	}
+
+	var total int
+	if err := s.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM faqs`); err != nil {
+		return database.PaginatedRecord[faq.FAQ]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get total count: %v", err))
+	}
+
+	return database.PaginatedRecord[faq.FAQ]{
+		Data:       faqs,
+		PageNumber: page,
+		PageSize:   pageSize,
+		Total:      total,
+	}, nil
+}
+
+// GetAllFAQs retrieves every FAQ, used to match incoming messages
+func (s *PostgresStore) GetAllFAQs(ctx context.Context) ([]faq.FAQ, error) {
+	return s.selectFAQs(ctx, `SELECT `+faqColumns+` FROM faqs ORDER BY id`)
+}
+
+func (s *PostgresStore) selectFAQs(ctx context.Context, query string, args ...interface{}) ([]faq.FAQ, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get FAQs: %v", err))
+	}
+	defer rows.Close()
+
+	faqs := []faq.FAQ{}
+	for rows.Next() {
+		f, err := scanFAQ(rows)
+		if err != nil {
+			return nil, errors.ErrDatabase(fmt.Sprintf("Failed to scan FAQ: %v", err))
+		}
+		faqs = append(faqs, *f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Error iterating FAQs: %v", err))
+	}
+	return faqs, nil
+}
+
+// UpdateFAQ replaces the content of a FAQ
+func (s *PostgresStore) UpdateFAQ(ctx context.Context, f faq.FAQ) (*faq.FAQ, error) {
+	query := `
+		UPDATE faqs
+		SET question = $2, variants = $3, answer = $4, citation_title = $5, citation_url = $6
+		WHERE id = $1
+		RETURNING ` + faqColumns
+
+	updated, err := scanFAQ(s.db.QueryRowContext(ctx, query, f.ID, f.Question, pq.Array(f.Variants), f.Answer, f.CitationTitle, f.CitationURL))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("FAQ not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to update FAQ: %v", err))
+	}
+	return updated, nil
+}
+
+// DeleteFAQ deletes a FAQ, interactions it answered keep their record without it
+func (s *PostgresStore) DeleteFAQ(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM faqs WHERE id = $1`, id)
+	if err != nil {
+		return errors.ErrDatabase(fmt.Sprintf("Failed to delete FAQ: %v", err))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.ErrDatabase(fmt.Sprintf("Failed to get affected rows: %v", err))
+	}
+	if rows == 0 {
+		return errors.ErrNotFound("FAQ not found")
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package faqsrv
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Abraxas-365/opd/internal/faq"
+	"github.com/Abraxas-365/opd/pkg/textsim"
+	"github.com/Abraxas-365/toolkit/pkg/database"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// DefaultMatchThreshold is the similarity a message needs to be answered by a FAQ. Curated answers
+// must not reach a different question, so close wordings with another meaning need to be rejected.
+const DefaultMatchThreshold = 0.9
+
+type Service struct {
+	repo      faq.Repository
+	threshold float64
+}
+
+func New(repo faq.Repository, threshold float64) *Service {
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultMatchThreshold
+	}
+	return &Service{
+		repo:      repo,
+		threshold: threshold,
+	}
+}
+
+// Match returns the FAQ whose question or variants are closest to the message, or nil when none is
+// similar enough to answer it with confidence. The message must use the words of the question or of a
+// variant, apart from misspellings, paraphrases are matched by adding them as variants.
+func (s *Service) Match(ctx context.Context, message string) (*faq.Match, error) {
+	faqs, err := s.repo.GetAllFAQs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *faq.Match
+	for _, f := range faqs {
+		for _, phrasing := range f.Phrasings() {
+			score := textsim.Similarity(message, phrasing)
+			if score >= s.threshold && (best == nil || score > best.Score) && textsim.SameWords(message, phrasing) {
+				best = &faq.Match{FAQ: f, Score: score}
+			}
+		}
+	}
+	return best, nil
+}
+
+func (s *Service) CreateFAQ(ctx context.Context, f faq.FAQ) (*faq.FAQ, error) {
+	if err := validate(&f); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateFAQ(ctx, f)
+}
+
+func (s *Service) GetFAQ(ctx context.Context, id int) (*faq.FAQ, error) {
+	return s.repo.GetFAQ(ctx, id)
+}
+
+func (s *Service) GetFAQs(ctx context.Context, page, pageSize int) (database.PaginatedRecord[faq.FAQ], error) {
+	return s.repo.GetFAQs(ctx, page, pageSize)
+}
+
+func (s *Service) UpdateFAQ(ctx context.Context, f faq.FAQ) (*faq.FAQ, error) {
+	if err := validate(&f); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateFAQ(ctx, f)
+}
+
+func (s *Service) DeleteFAQ(ctx context.Context, id int) error {
+	return s.repo.DeleteFAQ(ctx, id)
+}
+
+// validate checks the required fields and drops blank variants
+func validate(f *faq.FAQ) error {
+	f.Question = strings.TrimSpace(f.Question)
+	f.Answer = strings.TrimSpace(f.Answer)
+	if f.Question == "" || f.Answer == "" {
+		return errors.ErrBadRequest("question and answer are required")
+	}
+	if f.CitationURL != "" && f.CitationTitle == "" {
+		return errors.ErrBadRequest("citation_title is required with citation_url")
+	}
+
+	variants := make([]string, 0, len(f.Variants))
+	for _, v := range f.Variants {
+		if v = strings.TrimSpace(v); v != "" {
+			variants = append(variants, v)
+		}
+	}
+	f.Variants = variants
+	return nil
+}
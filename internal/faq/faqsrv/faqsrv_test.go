@@ -0,0 +1,60 @@
+package faqsrv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Abraxas-365/opd/internal/faq"
+)
+
+// fakeRepo serves a fixed list of FAQs to Match
+type fakeRepo struct {
+	faq.Repository
+	faqs []faq.FAQ
+}
+
+func (r fakeRepo) GetAllFAQs(ctx context.Context) ([]faq.FAQ, error) {
+	return r.faqs, nil
+}
+
+func TestMatch(t *testing.T) {
+	service := New(fakeRepo{faqs: []faq.FAQ{
+		{ID: 1, Question: "Can I cancel my order?", Variants: []string{"how do I cancel an order"}, Answer: "Yes, from your account."},
+		{ID: 2, Question: "Can I get a refund after 30 days?", Answer: "No, refunds are only given within 30 days."},
+	}}, 0)
+
+	tests := []struct {
+		name    string
+		message string
+		wantID  int
+	}{
+		{"same question", "can i cancel my order", 1},
+		{"punctuation and case", "CAN I CANCEL MY ORDER???", 1},
+		{"word order", "my order, can I cancel?", 1},
+		{"typo", "Can I cancel my ordder?", 1},
+		{"plural", "Can I cancel my orders?", 1},
+		{"variant", "How do I cancel an order?", 1},
+		{"negation", "Can't I cancel my order?", 0},
+		{"negation with not", "Can I not cancel my order?", 0},
+		{"cannot", "I cannot cancel my order?", 0},
+		{"opposite word", "Can I get a refund before 30 days?", 0},
+		{"different number", "Can I get a refund after 60 days?", 0},
+		{"added word", "Can I cancel my whole order?", 0},
+		{"unrelated", "What are your opening hours?", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := service.Match(context.Background(), tt.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotID := 0
+			if match != nil {
+				gotID = match.FAQ.ID
+			}
+			if gotID != tt.wantID {
+				t.Errorf("Match(%q) = FAQ %d, want %d", tt.message, gotID, tt.wantID)
+			}
+		})
+	}
+}
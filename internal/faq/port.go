@@ -0,0 +1,16 @@
+package faq
+
+import (
+	"context"
+
+	"github.com/Abraxas-365/toolkit/pkg/database"
+)
+
+type Repository interface {
+	CreateFAQ(ctx context.Context, f FAQ) (*FAQ, error)
+	GetFAQ(ctx context.Context, id int) (*FAQ, error)
+	GetFAQs(ctx context.Context, page, pageSize int) (database.PaginatedRecord[FAQ], error)
+	GetAllFAQs(ctx context.Context) ([]FAQ, error)
+	UpdateFAQ(ctx context.Context, f FAQ) (*FAQ, error)
+	DeleteFAQ(ctx context.Context, id int) error
+}
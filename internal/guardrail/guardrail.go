@@ -0,0 +1,156 @@
+package guardrail
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Directions a guardrail checks
+const (
+	DirectionInput  = "input"
+	DirectionOutput = "output"
+)
+
+// Rule names recorded with violations
+const (
+	RuleMaxLength        = "max_length"
+	RuleBlockedTopic     = "blocked_topic"
+	RuleDenyPattern      = "deny_pattern"
+	RulePromptInjection  = "prompt_injection"
+	RuleBedrockGuardrail = "bedrock_guardrail"
+)
+
+// Refusal is sent instead of the answer when a rule is violated
+const Refusal = "I can't help with that request. Please ask a question about the topics covered by our documentation."
+
+// Violation is a message or answer a rule stopped
+type Violation struct {
+	ID         int       `json:"id" db:"id"`
+	ChatUserID *string   `json:"chat_user_id" db:"user_chat_id"`
+	Direction  string    `json:"direction" db:"direction"`
+	Rule       string    `json:"rule" db:"rule"`
+	Detail     string    `json:"detail" db:"detail"`
+	Text       string    `json:"text" db:"text"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	// Refusal is what the chat user gets instead
+	Refusal string `json:"-" db:"-"`
+}
+
+// Rule is a check of the guardrail pipeline
+type Rule interface {
+	// Name identifies the rule in violations
+	Name() string
+	// Check returns why text violates the rule, or ok when it does not
+	Check(text string) (detail string, ok bool)
+}
+
+// MaxLength rejects messages longer than the given number of characters, 0 disables it
+type MaxLength int
+
+func (m MaxLength) Name() string { return RuleMaxLength }
+
+func (m MaxLength) Check(text string) (string, bool) {
+	if m <= 0 {
+		return "", true
+	}
+	if n := utf8.RuneCountInString(text); n > int(m) {
+		return fmt.Sprintf("%d characters, limit is %d", n, m), false
+	}
+	return "", true
+}
+
+// BlockedTopics rejects text that mentions any of the topics, matched as whole words ignoring case
+type BlockedTopics struct {
+	topics   []string
+	patterns []*regexp.Regexp
+}
+
+func NewBlockedTopics(topics []string) BlockedTopics {
+	var b BlockedTopics
+	for _, t := range topics {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+		b.topics = append(b.topics, t)
+		b.patterns = append(b.patterns, WordPattern(t))
+	}
+	return b
+}
+
+// WordPattern matches phrase as whole words ignoring case and the spacing between its words.
+// \b only knows ASCII word characters, so "año" would match inside "añorar".
+func WordPattern(phrase string) *regexp.Regexp {
+	words := strings.Fields(phrase)
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	return regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{M}\p{N}_])` + strings.Join(words, `\s+`) + `(?:$|[^\p{L}\p{M}\p{N}_])`)
+}
+
+func (b BlockedTopics) Name() string { return RuleBlockedTopic }
+
+func (b BlockedTopics) Check(text string) (string, bool) {
+	for i, p := range b.patterns {
+		if p.MatchString(text) {
+			return b.topics[i], false
+		}
+	}
+	return "", true
+}
+
+// DenyPatterns rejects text matching any of the regular expressions
+type DenyPatterns []*regexp.Regexp
+
+// NewDenyPatterns compiles the deny-list, blank patterns are skipped
+func NewDenyPatterns(patterns []string) (DenyPatterns, error) {
+	var d DenyPatterns
+	for _, p := range patterns {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny pattern %q: %w", p, err)
+		}
+		d = append(d, re)
+	}
+	return d, nil
+}
+
+func (d DenyPatterns) Name() string { return RuleDenyPattern }
+
+func (d DenyPatterns) Check(text string) (string, bool) {
+	for _, re := range d {
+		if re.MatchString(text) {
+			return re.String(), false
+		}
+	}
+	return "", true
+}
+
+// injectionPatterns are phrasings common in attempts to override the model instructions
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\s+(all\s+|any\s+)?(of\s+)?(the\s+|your\s+|these\s+)?((previous|prior|above|earlier|system|original)\s+)?(instructions|prompts?|rules|directions)\b`),
+	regexp.MustCompile(`(?i)\b(reveal|show|print|repeat|leak)\b.{0,30}\b(system|initial|hidden|original)\s+(prompt|instructions?|message)\b`),
+	regexp.MustCompile(`(?i)\b(you are now|act as|pretend to be) (an? )?(DAN|unrestricted|unfiltered|jailbroken|uncensored)\b|\b(DAN|developer) mode\b|\bjailbreak\b`),
+	regexp.MustCompile(`(?i)</?\s*(system|instructions?|prompt)\s*>|^\s*#{2,}\s*(system|instruction)`),
+	regexp.MustCompile(`(?i)\b(ignora|olvida|omite)\s+(todas\s+)?(las\s+|tus\s+)?(instrucciones|indicaciones|reglas)\b`),
+	regexp.MustCompile(`(?i)\$(conversation_history|output_format_instructions|search_results)\$`),
+}
+
+// PromptInjection rejects messages that try to override the model instructions
+type PromptInjection struct{}
+
+func (PromptInjection) Name() string { return RulePromptInjection }
+
+func (PromptInjection) Check(text string) (string, bool) {
+	for _, re := range injectionPatterns {
+		if m := re.FindString(text); m != "" {
+			return m, false
+		}
+	}
+	return "", true
+}
@@ -0,0 +1,42 @@
+package guardrail
+
+import "testing"
+
+func TestBlockedTopics(t *testing.T) {
+	rule := NewBlockedTopics([]string{"politics", " año ", "", "c++", "crédito", "ética"})
+
+	tests := []struct {
+		name      string
+		text      string
+		wantTopic string
+		wantOK    bool
+	}{
+		{"whole word", "tell me about politics", "politics", false},
+		{"ignores case", "POLITICS today", "politics", false},
+		{"punctuation around", "(politics)?", "politics", false},
+		{"inside a word", "geopolitics are hard", "", true},
+		{"accented topic", "el año pasado", "año", false},
+		{"accented topic inside a word", "suelo añorar mi casa", "", true},
+		{"accented letters around", "créditos", "", true},
+		{"accented topic at the end", "quiero un crédito", "crédito", false},
+		{"topic starting with an accent", "hablemos de ética", "ética", false},
+		{"accented topic ending a word", "la genética", "", true},
+		{"topic ending in symbols", "do you know c++?", "c++", false},
+		{"unrelated", "how do I reset my password", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topic, ok := rule.Check(tt.text)
+			if topic != tt.wantTopic || ok != tt.wantOK {
+				t.Errorf("Check(%q) = %q, %v, want %q, %v", tt.text, topic, ok, tt.wantTopic, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestBlockedTopicsSkipsBlankTopics(t *testing.T) {
+	rule := NewBlockedTopics([]string{"", "  "})
+	if _, ok := rule.Check("anything"); !ok {
+		t.Error("blank topics should not block any text")
+	}
+}
@@ -0,0 +1,33 @@
+package guardrailapi
+
+import (
+	"strconv"
+
+	"github.com/Abraxas-365/opd/internal/guardrail/guardrailsrv"
+	"github.com/Abraxas-365/opd/internal/user"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/Abraxas-365/toolkit/pkg/lucia"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the admin routes to review guardrail violations
+func SetupRoutes(app *fiber.App, service *guardrailsrv.Service, authMiddleware *lucia.AuthMiddleware[*user.User]) {
+	app.Get("/guardrails/violations", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		page, err := strconv.Atoi(c.Query("page", "1"))
+		if err != nil || page < 1 {
+			return errors.ErrBadRequest("Invalid page number")
+		}
+
+		pageSize, err := strconv.Atoi(c.Query("pageSize", "10"))
+		if err != nil || pageSize < 1 {
+			return errors.ErrBadRequest("Invalid page size")
+		}
+
+		violations, err := service.GetViolations(c.Context(), page, pageSize)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(violations)
+	})
+}
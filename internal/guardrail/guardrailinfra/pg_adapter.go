@@ -0,0 +1,65 @@
+package guardrailinfra
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Abraxas-365/opd/internal/guardrail"
+	"github.com/Abraxas-365/toolkit/pkg/database"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+const violationColumns = `id, user_chat_id, direction, rule, detail, text, created_at`
+
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewGuardrailStore creates a new PostgresStore for guardrail repository
+func NewGuardrailStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// CreateViolation records a message or answer stopped by a rule
+func (s *PostgresStore) CreateViolation(ctx context.Context, v guardrail.Violation) (*guardrail.Violation, error) {
+	query := `
+		INSERT INTO guardrail_violations (user_chat_id, direction, rule, detail, text)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + violationColumns
+
+	var created guardrail.Violation
+	err := s.db.QueryRowxContext(ctx, query, v.ChatUserID, v.Direction, v.Rule, v.Detail, v.Text).StructScan(&created)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create guardrail violation: %v", err))
+	}
+	return &created, nil
+}
+
+// GetViolations retrieves a paginated list of violations, newest first
+func (s *PostgresStore) GetViolations(ctx context.Context, page, pageSize int) (database.PaginatedRecord[guardrail.Violation], error) {
+	offset := (page - 1) * pageSize
+
+	query := `
+		SELECT ` + violationColumns + `
+		FROM guardrail_violations
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	var violations []guardrail.Violation
+	if err := s.db.SelectContext(ctx, &violations, query, pageSize, offset); err != nil {
+		return database.PaginatedRecord[guardrail.Violation]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get guardrail violations: %v", err))
+	}
+
+	var total int
+	if err := s.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM guardrail_violations`); err != nil {
+		return database.PaginatedRecord[guardrail.Violation]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get total count: %v", err))
+	}
+
+	return database.PaginatedRecord[guardrail.Violation]{
+		Data:       violations,
+		PageNumber: page,
+		PageSize:   pageSize,
+		Total:      total,
+	}, nil
+}
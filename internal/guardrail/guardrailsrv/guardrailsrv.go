@@ -0,0 +1,102 @@
+package guardrailsrv
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Abraxas-365/opd/internal/guardrail"
+	"github.com/Abraxas-365/toolkit/pkg/database"
+)
+
+type Service struct {
+	repo        guardrail.Repository
+	inputRules  []guardrail.Rule
+	outputRules []guardrail.Rule
+}
+
+// New creates the guardrail pipeline, rules run in order and the first violation stops it
+func New(repo guardrail.Repository, inputRules []guardrail.Rule, outputRules []guardrail.Rule) *Service {
+	return &Service{
+		repo:        repo,
+		inputRules:  inputRules,
+		outputRules: outputRules,
+	}
+}
+
+// CheckInput runs the input rules on a chat user's message before it reaches the model.
+// It returns the recorded violation, or nil when the message can be answered.
+func (s *Service) CheckInput(ctx context.Context, chatUserID string, text string) (*guardrail.Violation, error) {
+	return s.check(ctx, chatUserID, guardrail.DirectionInput, s.inputRules, text)
+}
+
+// CheckOutput runs the output rules on a model answer before it reaches the chat user.
+// It returns the recorded violation, or nil when the answer can be sent.
+func (s *Service) CheckOutput(ctx context.Context, chatUserID string, text string) (*guardrail.Violation, error) {
+	return s.check(ctx, chatUserID, guardrail.DirectionOutput, s.outputRules, text)
+}
+
+// AllowsOutput runs the output rules on a partial answer without recording anything, so a streamed
+// answer stops before a violating chunk reaches the chat user. CheckOutput still runs on the full answer.
+func (s *Service) AllowsOutput(text string) bool {
+	for _, rule := range s.outputRules {
+		if _, ok := rule.Check(text); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// RecordBedrockIntervention records an answer the Bedrock Guardrail blocked or masked
+func (s *Service) RecordBedrockIntervention(ctx context.Context, chatUserID string, question string) (*guardrail.Violation, error) {
+	return s.record(ctx, guardrail.Violation{
+		ChatUserID: &chatUserID,
+		Direction:  guardrail.DirectionOutput,
+		Rule:       guardrail.RuleBedrockGuardrail,
+		Detail:     "guardrail intervened",
+		Text:       question,
+		Refusal:    guardrail.Refusal,
+	})
+}
+
+func (s *Service) GetViolations(ctx context.Context, page, pageSize int) (database.PaginatedRecord[guardrail.Violation], error) {
+	return s.repo.GetViolations(ctx, page, pageSize)
+}
+
+func (s *Service) check(ctx context.Context, chatUserID string, direction string, rules []guardrail.Rule, text string) (*guardrail.Violation, error) {
+	for _, rule := range rules {
+		detail, ok := rule.Check(text)
+		if ok {
+			continue
+		}
+
+		return s.record(ctx, guardrail.Violation{
+			ChatUserID: &chatUserID,
+			Direction:  direction,
+			Rule:       rule.Name(),
+			Detail:     detail,
+			Text:       text,
+			Refusal:    refusal(rule),
+		})
+	}
+	return nil, nil
+}
+
+func (s *Service) record(ctx context.Context, v guardrail.Violation) (*guardrail.Violation, error) {
+	log.Printf("guardrail %s violation (%s) for chat user %s: %s", v.Rule, v.Direction, *v.ChatUserID, v.Detail)
+
+	created, err := s.repo.CreateViolation(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	created.Refusal = v.Refusal
+	return created, nil
+}
+
+// refusal tells the chat user why the message was not answered, without revealing the rules
+func refusal(rule guardrail.Rule) string {
+	if max, ok := rule.(guardrail.MaxLength); ok {
+		return fmt.Sprintf("Your message is too long. Please keep it under %d characters.", max)
+	}
+	return guardrail.Refusal
+}
@@ -0,0 +1,12 @@
+package guardrail
+
+import (
+	"context"
+
+	"github.com/Abraxas-365/toolkit/pkg/database"
+)
+
+type Repository interface {
+	CreateViolation(ctx context.Context, v Violation) (*Violation, error)
+	GetViolations(ctx context.Context, page, pageSize int) (database.PaginatedRecord[Violation], error)
+}
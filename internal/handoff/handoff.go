@@ -0,0 +1,67 @@
+package handoff
+
+import "time"
+
+// Reasons a conversation is escalated
+const (
+	ReasonRequested = "requested"
+	ReasonNotFound  = "not_found"
+)
+
+// Handoff statuses
+const (
+	StatusOpen    = "open"
+	StatusClaimed = "claimed"
+	StatusClosed  = "closed"
+)
+
+// Message authors
+const (
+	AuthorChatUser = "chat_user"
+	AuthorAgent    = "agent"
+)
+
+// Event types recorded for analytics
+const (
+	EventEscalated = "escalated"
+	EventClaimed   = "claimed"
+	EventReplied   = "replied"
+	EventClosed    = "closed"
+)
+
+// Handoff is a conversation escalated from the bot to a human agent
+type Handoff struct {
+	ID         int        `json:"id" db:"id"`
+	ChatUserID string     `json:"chat_user_id" db:"user_chat_id"`
+	Channel    string     `json:"channel" db:"channel"`
+	Reason     string     `json:"reason" db:"reason"`
+	Status     string     `json:"status" db:"status"`
+	Question   string     `json:"question" db:"question"`
+	ClaimedBy  *string    `json:"claimed_by" db:"claimed_by"`
+	ClaimedAt  *time.Time `json:"claimed_at" db:"claimed_at"`
+	ClosedAt   *time.Time `json:"closed_at" db:"closed_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ForwardsMessages reports whether the chat user's messages go to the agent instead of the bot. A handoff
+// the chat user asked for or an agent claimed does, a not found escalation waits in the queue while the bot
+// keeps answering so one unanswered question does not silence it.
+func (h Handoff) ForwardsMessages() bool {
+	return h.Status == StatusClaimed || h.Reason == ReasonRequested
+}
+
+// Message is a message exchanged between the chat user and the agent during a handoff
+type Message struct {
+	ID        int       `json:"id" db:"id"`
+	HandoffID int       `json:"handoff_id" db:"handoff_id"`
+	Author    string    `json:"author" db:"author"`
+	AgentID   *string   `json:"agent_id" db:"agent_id"`
+	Text      string    `json:"text" db:"text"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// HandoffWithMessages is a handoff and its conversation, as shown in the agent inbox
+type HandoffWithMessages struct {
+	Handoff
+	Messages []Message `json:"messages"`
+}
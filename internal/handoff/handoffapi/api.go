@@ -0,0 +1,156 @@
+package handoffapi
+
+import (
+	"strconv"
+
+	"github.com/Abraxas-365/opd/internal/handoff"
+	"github.com/Abraxas-365/opd/internal/handoff/handoffsrv"
+	"github.com/Abraxas-365/opd/internal/user"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/Abraxas-365/toolkit/pkg/lucia"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the agent inbox and the chat user handoff routes
+func SetupRoutes(app *fiber.App, service *handoffsrv.Service, authMiddleware *lucia.AuthMiddleware[*user.User]) {
+	// Chat user asks to talk to a person
+	app.Post("/chat/handoff", func(c *fiber.Ctx) error {
+		type Request struct {
+			UserChatID string `json:"userChatID"`
+			Message    string `json:"message"`
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+		if req.UserChatID == "" || req.Message == "" {
+			return errors.ErrBadRequest("userChatID and message are required")
+		}
+
+		h, err := service.Escalate(c.Context(), req.UserChatID, req.Message, handoff.ReasonRequested)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"handoff": h,
+			"message": handoffsrv.EscalatedMessage,
+		})
+	})
+
+	// Agent replies for web chat users, poll with the last message id seen
+	app.Get("/chat/handoff/messages", func(c *fiber.Ctx) error {
+		userChatID := c.Query("userChatID")
+		if userChatID == "" {
+			return errors.ErrBadRequest("userChatID is required")
+		}
+
+		after, err := strconv.Atoi(c.Query("after", "0"))
+		if err != nil || after < 0 {
+			return errors.ErrBadRequest("Invalid after id")
+		}
+
+		messages, err := service.GetAgentMessages(c.Context(), userChatID, after)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{"data": messages})
+	})
+
+	// Agent inbox
+	app.Get("/handoffs", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		page, err := strconv.Atoi(c.Query("page", "1"))
+		if err != nil || page < 1 {
+			return errors.ErrBadRequest("Invalid page number")
+		}
+
+		pageSize, err := strconv.Atoi(c.Query("pageSize", "10"))
+		if err != nil || pageSize < 1 {
+			return errors.ErrBadRequest("Invalid page size")
+		}
+
+		handoffs, err := service.GetHandoffs(c.Context(), c.Query("status"), page, pageSize)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(handoffs)
+	})
+
+	app.Get("/handoffs/:id", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Handoff id must be a number")
+		}
+
+		h, err := service.GetHandoff(c.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(h)
+	})
+
+	app.Post("/handoffs/:id/claim", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Handoff id must be a number")
+		}
+
+		agentID, err := lucia.GetSession(c).UserIDToString()
+		if err != nil {
+			return err
+		}
+
+		h, err := service.Claim(c.Context(), id, agentID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(h)
+	})
+
+	app.Post("/handoffs/:id/reply", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		type Request struct {
+			Message string `json:"message"`
+		}
+
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Handoff id must be a number")
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		agentID, err := lucia.GetSession(c).UserIDToString()
+		if err != nil {
+			return err
+		}
+
+		m, err := service.Reply(c.Context(), id, agentID, req.Message)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(m)
+	})
+
+	app.Post("/handoffs/:id/close", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Handoff id must be a number")
+		}
+
+		h, err := service.Close(c.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(h)
+	})
+}
@@ -0,0 +1,217 @@
+package handoffinfra
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Abraxas-365/opd/internal/handoff"
+	"github.com/Abraxas-365/toolkit/pkg/database"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const handoffColumns = `id, user_chat_id, channel, reason, status, question, claimed_by, claimed_at, closed_at, created_at`
+
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewHandoffStore creates a new PostgresStore for handoff repository
+func NewHandoffStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// CreateHandoff inserts a new open handoff
+func (s *PostgresStore) CreateHandoff(ctx context.Context, h handoff.Handoff) (*handoff.Handoff, error) {
+	query := `
+		INSERT INTO handoffs (user_chat_id, channel, reason, status, question)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + handoffColumns
+
+	var created handoff.Handoff
+	err := s.db.QueryRowxContext(ctx, query, h.ChatUserID, h.Channel, h.Reason, handoff.StatusOpen, h.Question).StructScan(&created)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23503":
+				return nil, errors.ErrNotFound("Referenced chat user not found")
+			case "23505":
+				return nil, errors.ErrConflict("Chat user already has an active handoff")
+			}
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create handoff: %v", err))
+	}
+
+	return &created, nil
+}
+
+// GetHandoff retrieves a handoff by ID
+func (s *PostgresStore) GetHandoff(ctx context.Context, id int) (*handoff.Handoff, error) {
+	query := `SELECT ` + handoffColumns + ` FROM handoffs WHERE id = $1`
+
+	var h handoff.Handoff
+	if err := s.db.GetContext(ctx, &h, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("Handoff not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get handoff: %v", err))
+	}
+	return &h, nil
+}
+
+// GetActiveHandoff retrieves the open or claimed handoff of a chat user
+func (s *PostgresStore) GetActiveHandoff(ctx context.Context, chatUserID string) (*handoff.Handoff, error) {
+	query := `SELECT ` + handoffColumns + ` FROM handoffs WHERE user_chat_id = $1 AND status <> $2`
+
+	var h handoff.Handoff
+	if err := s.db.GetContext(ctx, &h, query, chatUserID, handoff.StatusClosed); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("No active handoff for chat user")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get active handoff: %v", err))
+	}
+	return &h, nil
+}
+
+// GetHandoffs retrieves a paginated list of handoffs, oldest first, optionally filtered by status
+func (s *PostgresStore) GetHandoffs(ctx context.Context, status string, page, pageSize int) (database.PaginatedRecord[handoff.Handoff], error) {
+	offset := (page - 1) * pageSize
+
+	query := `
+		SELECT ` + handoffColumns + `
+		FROM handoffs
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at ASC
+		LIMIT $2 OFFSET $3`
+
+	var handoffs []handoff.Handoff
+	if err := s.db.SelectContext(ctx, &handoffs, query, status, pageSize, offset); err != nil {
+		return database.PaginatedRecord[handoff.Handoff]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get handoffs: %v", err))
+	}
+
+	var total int
+	if err := s.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM handoffs WHERE $1 = '' OR status = $1`, status); err != nil {
+		return database.PaginatedRecord[handoff.Handoff]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get total count: %v", err))
+	}
+
+	return database.PaginatedRecord[handoff.Handoff]{
+		Data:       handoffs,
+		PageNumber: page,
+		PageSize:   pageSize,
+		Total:      total,
+	}, nil
+}
+
+// SetHandoffReason changes why an active handoff was escalated
+func (s *PostgresStore) SetHandoffReason(ctx context.Context, id int, reason string) (*handoff.Handoff, error) {
+	query := `
+		UPDATE handoffs
+		SET reason = $2
+		WHERE id = $1 AND status <> $3
+		RETURNING ` + handoffColumns
+
+	var h handoff.Handoff
+	err := s.db.QueryRowxContext(ctx, query, id, reason, handoff.StatusClosed).StructScan(&h)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrConflict("Handoff is closed")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to set handoff reason: %v", err))
+	}
+	return &h, nil
+}
+
+// ClaimHandoff assigns an open handoff to an agent
+func (s *PostgresStore) ClaimHandoff(ctx context.Context, id int, agentID string) (*handoff.Handoff, error) {
+	query := `
+		UPDATE handoffs
+		SET status = $3, claimed_by = $2, claimed_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $4
+		RETURNING ` + handoffColumns
+
+	var h handoff.Handoff
+	err := s.db.QueryRowxContext(ctx, query, id, agentID, handoff.StatusClaimed, handoff.StatusOpen).StructScan(&h)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrConflict("Handoff is not open")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to claim handoff: %v", err))
+	}
+	return &h, nil
+}
+
+// CloseHandoff closes a handoff that is not closed yet
+func (s *PostgresStore) CloseHandoff(ctx context.Context, id int) (*handoff.Handoff, error) {
+	query := `
+		UPDATE handoffs
+		SET status = $2, closed_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status <> $2
+		RETURNING ` + handoffColumns
+
+	var h handoff.Handoff
+	err := s.db.QueryRowxContext(ctx, query, id, handoff.StatusClosed).StructScan(&h)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrConflict("Handoff is already closed")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to close handoff: %v", err))
+	}
+	return &h, nil
+}
+
+// AddMessage inserts a message in a handoff conversation
+func (s *PostgresStore) AddMessage(ctx context.Context, m handoff.Message) (*handoff.Message, error) {
+	query := `
+		INSERT INTO handoff_messages (handoff_id, author, agent_id, text)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, handoff_id, author, agent_id, text, created_at`
+
+	var created handoff.Message
+	err := s.db.QueryRowxContext(ctx, query, m.HandoffID, m.Author, m.AgentID, m.Text).StructScan(&created)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to add handoff message: %v", err))
+	}
+	return &created, nil
+}
+
+// GetMessages retrieves the messages of a handoff with an ID greater than afterID
+func (s *PostgresStore) GetMessages(ctx context.Context, handoffID int, afterID int) ([]handoff.Message, error) {
+	query := `
+		SELECT id, handoff_id, author, agent_id, text, created_at
+		FROM handoff_messages
+		WHERE handoff_id = $1 AND id > $2
+		ORDER BY id`
+
+	messages := []handoff.Message{}
+	if err := s.db.SelectContext(ctx, &messages, query, handoffID, afterID); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get handoff messages: %v", err))
+	}
+	return messages, nil
+}
+
+// GetAgentMessages retrieves the agent replies sent to a chat user, across all its handoffs, with an ID greater than afterID
+func (s *PostgresStore) GetAgentMessages(ctx context.Context, chatUserID string, afterID int) ([]handoff.Message, error) {
+	query := `
+		SELECT m.id, m.handoff_id, m.author, m.agent_id, m.text, m.created_at
+		FROM handoff_messages m
+		JOIN handoffs h ON h.id = m.handoff_id
+		WHERE h.user_chat_id = $1 AND m.author = $2 AND m.id > $3
+		ORDER BY m.id`
+
+	messages := []handoff.Message{}
+	if err := s.db.SelectContext(ctx, &messages, query, chatUserID, handoff.AuthorAgent, afterID); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get agent messages: %v", err))
+	}
+	return messages, nil
+}
+
+// RecordEvent stores a handoff lifecycle event for analytics
+func (s *PostgresStore) RecordEvent(ctx context.Context, h handoff.Handoff, eventType string) error {
+	query := `INSERT INTO handoff_events (handoff_id, event_type, reason) VALUES ($1, $2, $3)`
+	if _, err := s.db.ExecContext(ctx, query, h.ID, eventType, h.Reason); err != nil {
+		return errors.ErrDatabase(fmt.Sprintf("Failed to record handoff event: %v", err))
+	}
+	return nil
+}
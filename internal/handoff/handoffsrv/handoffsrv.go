@@ -0,0 +1,305 @@
+package handoffsrv
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/Abraxas-365/opd/internal/chatuser"
+	"github.com/Abraxas-365/opd/internal/chatuser/chatusersrv"
+	"github.com/Abraxas-365/opd/internal/guardrail"
+	"github.com/Abraxas-365/opd/internal/handoff"
+	"github.com/Abraxas-365/opd/internal/pii/piisrv"
+	"github.com/Abraxas-365/toolkit/pkg/database"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// Messages sent to the chat user instead of, or after, the bot answer
+const (
+	EscalatedMessage = "We have passed your conversation to our team. An agent will reply to you here shortly."
+	ForwardedMessage = "Your message was sent to the agent handling your conversation."
+	NotFoundNote     = "We have also passed your question to our team, an agent will reply to you here."
+)
+
+// maxRequestExtraWords is how many words besides a trigger a request to talk to a human has, like
+// "I want to ... please". Longer messages mentioning a trigger are questions for the knowledge base.
+const maxRequestExtraWords = 4
+
+// DefaultTriggers are the phrases that make a chat user's message a request to talk to a human,
+// triggers starting with "/" are commands that only count at the start of the message
+var DefaultTriggers = []string{
+	"/human",
+	"talk to a human",
+	"speak to a human",
+	"human agent",
+	"hablar con un humano",
+	"hablar con una persona",
+	"hablar con un agente",
+	"agente humano",
+}
+
+type Service struct {
+	repo            handoff.Repository
+	chatUserService *chatusersrv.Service
+	piiService      *piisrv.Service
+	deliverers      map[string]handoff.Deliverer
+	triggers        []trigger
+}
+
+type trigger struct {
+	phrase  string
+	words   int
+	pattern *regexp.Regexp
+}
+
+func New(repo handoff.Repository, chatUserService *chatusersrv.Service, piiService *piisrv.Service, triggers []string) *Service {
+	if len(triggers) == 0 {
+		triggers = DefaultTriggers
+	}
+	normalized := make([]trigger, 0, len(triggers))
+	for _, t := range triggers {
+		if t = strings.ToLower(strings.TrimSpace(t)); t == "" {
+			continue
+		}
+		tr := trigger{phrase: t, words: len(strings.Fields(t))}
+		if !strings.HasPrefix(t, "/") {
+			tr.pattern = guardrail.WordPattern(t)
+		}
+		normalized = append(normalized, tr)
+	}
+
+	return &Service{
+		repo:            repo,
+		chatUserService: chatUserService,
+		piiService:      piiService,
+		deliverers:      make(map[string]handoff.Deliverer),
+		triggers:        normalized,
+	}
+}
+
+// RegisterChannel sets how agent replies reach chat users of a channel.
+// Chat users of chatuser.ChannelWeb fetch replies with GetAgentMessages instead.
+func (s *Service) RegisterChannel(channel string, deliverer handoff.Deliverer) {
+	s.deliverers[channel] = deliverer
+}
+
+// IsHumanRequest reports whether the chat user is asking to talk to a person: the message starts with
+// a command trigger, or is a short message with a trigger phrase as whole words
+func (s *Service) IsHumanRequest(text string) bool {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) == 0 {
+		return false
+	}
+	command := strings.SplitN(fields[0], "@", 2)[0]
+
+	for _, t := range s.triggers {
+		if t.pattern == nil {
+			if command == t.phrase {
+				return true
+			}
+			continue
+		}
+		if len(fields)-t.words <= maxRequestExtraWords && t.pattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetActiveHandoff returns the open or claimed handoff of the chat user, or nil if the bot is answering
+func (s *Service) GetActiveHandoff(ctx context.Context, chatUserID string) (*handoff.Handoff, error) {
+	h, err := s.repo.GetActiveHandoff(ctx, chatUserID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return h, nil
+}
+
+// Escalate puts the chat user's conversation in the agent queue. When a handoff is already queued the
+// question is added to it, and a request to talk to a human turns a not found escalation into a requested one.
+// The question is stored redacted.
+func (s *Service) Escalate(ctx context.Context, chatUserID, question, reason string) (*handoff.Handoff, error) {
+	question, _, err := s.piiService.Protect(ctx, chatUserID, question)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.chatUserService.GetChannelIdentity(ctx, chatUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := s.repo.CreateHandoff(ctx, handoff.Handoff{
+		ChatUserID: chatUserID,
+		Channel:    identity.Channel,
+		Reason:     reason,
+		Question:   question,
+	})
+	if errors.IsConflict(err) {
+		return s.escalateActive(ctx, chatUserID, question, reason)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.addChatUserMessage(ctx, *h, question); err != nil {
+		return nil, err
+	}
+	if err := s.repo.RecordEvent(ctx, *h, handoff.EventEscalated); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (s *Service) escalateActive(ctx context.Context, chatUserID, question, reason string) (*handoff.Handoff, error) {
+	h, err := s.repo.GetActiveHandoff(ctx, chatUserID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.addChatUserMessage(ctx, *h, question); err != nil {
+		return nil, err
+	}
+	if reason == handoff.ReasonRequested && h.Reason != handoff.ReasonRequested {
+		return s.repo.SetHandoffReason(ctx, h.ID, reason)
+	}
+	return h, nil
+}
+
+// AddChatUserMessage adds a message from the chat user to the handoff conversation, redacted
+func (s *Service) AddChatUserMessage(ctx context.Context, h handoff.Handoff, text string) (*handoff.Message, error) {
+	text, _, err := s.piiService.Protect(ctx, h.ChatUserID, text)
+	if err != nil {
+		return nil, err
+	}
+	return s.addChatUserMessage(ctx, h, text)
+}
+
+func (s *Service) addChatUserMessage(ctx context.Context, h handoff.Handoff, text string) (*handoff.Message, error) {
+	return s.repo.AddMessage(ctx, handoff.Message{
+		HandoffID: h.ID,
+		Author:    handoff.AuthorChatUser,
+		Text:      text,
+	})
+}
+
+func (s *Service) GetHandoffs(ctx context.Context, status string, page, pageSize int) (database.PaginatedRecord[handoff.Handoff], error) {
+	switch status {
+	case "", handoff.StatusOpen, handoff.StatusClaimed, handoff.StatusClosed:
+	default:
+		return database.PaginatedRecord[handoff.Handoff]{}, errors.ErrBadRequest("invalid status")
+	}
+	return s.repo.GetHandoffs(ctx, status, page, pageSize)
+}
+
+// GetHandoff returns a handoff with its whole conversation
+func (s *Service) GetHandoff(ctx context.Context, id int) (*handoff.HandoffWithMessages, error) {
+	h, err := s.repo.GetHandoff(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.repo.GetMessages(ctx, id, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &handoff.HandoffWithMessages{Handoff: *h, Messages: messages}, nil
+}
+
+// Claim assigns an open handoff to the agent
+func (s *Service) Claim(ctx context.Context, id int, agentID string) (*handoff.Handoff, error) {
+	h, err := s.repo.ClaimHandoff(ctx, id, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RecordEvent(ctx, *h, handoff.EventClaimed); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Reply sends an agent message to the chat user on the channel the conversation started on.
+// Replying to an open handoff claims it.
+func (s *Service) Reply(ctx context.Context, id int, agentID string, text string) (*handoff.Message, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, errors.ErrBadRequest("message is required")
+	}
+
+	h, err := s.repo.GetHandoff(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch h.Status {
+	case handoff.StatusClosed:
+		return nil, errors.ErrConflict("Handoff is closed")
+	case handoff.StatusOpen:
+		if h, err = s.Claim(ctx, id, agentID); err != nil {
+			return nil, err
+		}
+	}
+	if h.ClaimedBy == nil || *h.ClaimedBy != agentID {
+		return nil, errors.ErrForbidden("Handoff is claimed by another agent")
+	}
+
+	// Delivered first, so a reply that did not reach the chat user is not shown as sent and can be retried
+	if err := s.deliver(ctx, *h, text); err != nil {
+		return nil, err
+	}
+
+	m, err := s.repo.AddMessage(ctx, handoff.Message{
+		HandoffID: h.ID,
+		Author:    handoff.AuthorAgent,
+		AgentID:   &agentID,
+		Text:      text,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RecordEvent(ctx, *h, handoff.EventReplied); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Close ends the handoff, the bot answers the chat user's next messages again
+func (s *Service) Close(ctx context.Context, id int) (*handoff.Handoff, error) {
+	h, err := s.repo.CloseHandoff(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RecordEvent(ctx, *h, handoff.EventClosed); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// GetAgentMessages returns the agent replies to a web chat user newer than afterID
+func (s *Service) GetAgentMessages(ctx context.Context, chatUserID string, afterID int) ([]handoff.Message, error) {
+	return s.repo.GetAgentMessages(ctx, chatUserID, afterID)
+}
+
+func (s *Service) deliver(ctx context.Context, h handoff.Handoff, text string) error {
+	if h.Channel == chatuser.ChannelWeb {
+		return nil
+	}
+
+	deliverer, ok := s.deliverers[h.Channel]
+	if !ok {
+		log.Printf("handoff: no deliverer registered for channel %s", h.Channel)
+		return errors.ErrServiceUnavailable("channel " + h.Channel + " is not enabled")
+	}
+
+	identity, err := s.chatUserService.GetChannelIdentity(ctx, h.ChatUserID)
+	if err != nil {
+		return err
+	}
+
+	return deliverer.Deliver(ctx, identity.ExternalID, text)
+}
@@ -0,0 +1,35 @@
+package handoffsrv
+
+import "testing"
+
+func TestIsHumanRequest(t *testing.T) {
+	service := New(nil, nil, nil, nil)
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"/human", true},
+		{"/human@my_bot", true},
+		{"/HUMAN please", true},
+		{"what does /human do?", false},
+		{"human agent", true},
+		{"Human agent!", true},
+		{"I want to talk to a human please", true},
+		{"can I speak  to a human?", true},
+		{"quiero hablar con un agente", true},
+		{"Agente humano, por favor", true},
+		{"does the policy cover human agent commissions?", false},
+		{"¿la póliza cubre comisiones de un agente humano externo?", false},
+		{"superhuman agent", false},
+		{"how do I reset my password?", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := service.IsHumanRequest(tt.text); got != tt.want {
+				t.Errorf("IsHumanRequest(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package handoff
+
+import (
+	"context"
+
+	"github.com/Abraxas-365/toolkit/pkg/database"
+)
+
+type Repository interface {
+	CreateHandoff(ctx context.Context, h Handoff) (*Handoff, error)
+	GetHandoff(ctx context.Context, id int) (*Handoff, error)
+	GetActiveHandoff(ctx context.Context, chatUserID string) (*Handoff, error)
+	GetHandoffs(ctx context.Context, status string, page, pageSize int) (database.PaginatedRecord[Handoff], error)
+	ClaimHandoff(ctx context.Context, id int, agentID string) (*Handoff, error)
+	SetHandoffReason(ctx context.Context, id int, reason string) (*Handoff, error)
+	CloseHandoff(ctx context.Context, id int) (*Handoff, error)
+
+	AddMessage(ctx context.Context, m Message) (*Message, error)
+	GetMessages(ctx context.Context, handoffID int, afterID int) ([]Message, error)
+	GetAgentMessages(ctx context.Context, chatUserID string, afterID int) ([]Message, error)
+
+	RecordEvent(ctx context.Context, h Handoff, eventType string) error
+}
+
+// Deliverer sends an agent reply to a chat user on the channel the conversation started on
+type Deliverer interface {
+	// Deliver sends text to externalID, the chat user's identity on the channel
+	Deliver(ctx context.Context, externalID string, text string) error
+}
@@ -1,7 +1,48 @@
 package interaction
 
+import "regexp"
+
+// Answer statuses, how well the knowledge base answered the question
+const (
+	StatusAnswered = "answered"
+	StatusPartial  = "partial"
+	StatusNotFound = "not_found"
+)
+
 type Interaction struct {
 	ID                 int      `json:"id" db:"id"`
 	UserChatID         string   `json:"user_chat_id" db:"user_chat_id"`
 	ContextInteraction []string `json:"context_interaction" db:"context_interaction"`
+	FAQID              *int     `json:"faq_id" db:"faq_id"`
+	FromSuggestion     bool     `json:"from_suggestion" db:"from_suggestion"`
+	Question           string   `json:"question" db:"question"`
+	AnswerStatus       string   `json:"answer_status" db:"answer_status"`
+	PIIVaultID         *int     `json:"pii_vault_id" db:"pii_vault_id"`
+	// Cached is set when the answer came from the answer cache instead of the model
+	Cached bool `json:"cached" db:"cached"`
+	// VariantID is the experiment variant that answered, nil outside experiments
+	VariantID *int `json:"variant_id" db:"variant_id"`
+	LatencyMs *int `json:"latency_ms" db:"latency_ms"`
+	// Helpful is the chat user's feedback on the answer, nil until they give it
+	Helpful *bool `json:"helpful" db:"helpful"`
+}
+
+// notFoundPattern matches the answers the model gives when the knowledge base has nothing on the question,
+// following the instructions in KB_MODEL_PROMPT
+var notFoundPattern = regexp.MustCompile(`(?i)(could not find an exact answer|couldn't find an exact answer|no (pude|pudimos) encontrar una respuesta|no encontr[ée] una respuesta)`)
+
+// partialPattern matches answers where the model says the documents only cover part of the question
+var partialPattern = regexp.MustCompile(`(?i)(only (partial|limited) information|does not (fully|specifically) (answer|cover|address)|doesn't (fully|specifically) (answer|cover|address)|not enough information|informaci[óo]n (parcial|limitada|insuficiente)|no (cubre|responde) (completamente|del todo))`)
+
+// Classify tells how well an answer covers the question. Answers that cite no documents
+// are not grounded in the knowledge base, so at best they are partial.
+func Classify(answer string, cited bool) string {
+	switch {
+	case answer == "" || notFoundPattern.MatchString(answer):
+		return StatusNotFound
+	case !cited || partialPattern.MatchString(answer):
+		return StatusPartial
+	default:
+		return StatusAnswered
+	}
 }
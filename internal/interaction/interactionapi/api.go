@@ -0,0 +1,33 @@
+package interactionapi
+
+import (
+	"github.com/Abraxas-365/opd/internal/interaction/interactionsrv"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the chat user routes about their interactions
+func SetupRoutes(app *fiber.App, service *interactionsrv.Service) {
+	// Chat user rates the last answer they got
+	app.Post("/chat/feedback", func(c *fiber.Ctx) error {
+		type Request struct {
+			UserChatID string `json:"userChatID"`
+			Helpful    *bool  `json:"helpful"`
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+		if req.UserChatID == "" || req.Helpful == nil {
+			return errors.ErrBadRequest("userChatID and helpful are required")
+		}
+
+		i, err := service.RecordFeedback(c.Context(), req.UserChatID, *req.Helpful)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(i)
+	})
+}
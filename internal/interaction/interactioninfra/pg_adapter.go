@@ -2,6 +2,7 @@ package interactioninfra
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/Abraxas-365/opd/internal/interaction"
@@ -10,6 +11,8 @@ import (
 	"github.com/lib/pq"
 )
 
+const interactionColumns = `id, user_chat_id, context_interaction, faq_id, from_suggestion, question, answer_status, pii_vault_id, cached, variant_id, latency_ms, helpful`
+
 type PostgresStore struct {
 	db *sqlx.DB
 }
@@ -22,19 +25,36 @@ func NewInteractionStore(db *sqlx.DB) *PostgresStore {
 // CreateInteraction inserts a new interaction
 func (s *PostgresStore) CreateInteraction(ctx context.Context, i interaction.Interaction) (*interaction.Interaction, error) {
 	query := `
-		INSERT INTO interactions (user_chat_id, context_interaction) 
-		VALUES ($1, $2) 
-		RETURNING id, user_chat_id, context_interaction`
+		INSERT INTO interactions (user_chat_id, context_interaction, faq_id, from_suggestion, question, answer_status, pii_vault_id, cached, variant_id, latency_ms) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) 
+		RETURNING ` + interactionColumns
 
 	err := s.db.QueryRowContext(
 		ctx,
 		query,
 		i.UserChatID,
 		pq.Array(i.ContextInteraction),
+		i.FAQID,
+		i.FromSuggestion,
+		i.Question,
+		i.AnswerStatus,
+		i.PIIVaultID,
+		i.Cached,
+		i.VariantID,
+		i.LatencyMs,
 	).Scan(
 		&i.ID,
 		&i.UserChatID,
 		pq.Array(&i.ContextInteraction),
+		&i.FAQID,
+		&i.FromSuggestion,
+		&i.Question,
+		&i.AnswerStatus,
+		&i.PIIVaultID,
+		&i.Cached,
+		&i.VariantID,
+		&i.LatencyMs,
+		&i.Helpful,
 	)
 
 	if err != nil {
@@ -47,3 +67,36 @@ func (s *PostgresStore) CreateInteraction(ctx context.Context, i interaction.Int
 
 	return &i, nil
 }
+
+// SetLatestFeedback records the feedback on the chat user's last interaction
+func (s *PostgresStore) SetLatestFeedback(ctx context.Context, userChatID string, helpful bool) (*interaction.Interaction, error) {
+	query := `
+		UPDATE interactions
+		SET helpful = $2
+		WHERE id = (SELECT MAX(id) FROM interactions WHERE user_chat_id = $1)
+		RETURNING ` + interactionColumns
+
+	var i interaction.Interaction
+	err := s.db.QueryRowContext(ctx, query, userChatID, helpful).Scan(
+		&i.ID,
+		&i.UserChatID,
+		pq.Array(&i.ContextInteraction),
+		&i.FAQID,
+		&i.FromSuggestion,
+		&i.Question,
+		&i.AnswerStatus,
+		&i.PIIVaultID,
+		&i.Cached,
+		&i.VariantID,
+		&i.LatencyMs,
+		&i.Helpful,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("The chat user has no answer to give feedback on")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to save feedback: %v", err))
+	}
+
+	return &i, nil
+}
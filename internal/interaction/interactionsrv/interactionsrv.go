@@ -19,3 +19,8 @@ func New(repo interaction.Repository) *Service {
 func (s *Service) CreateInteraction(ctx context.Context, cu interaction.Interaction) (*interaction.Interaction, error) {
 	return s.repo.CreateInteraction(ctx, cu)
 }
+
+// RecordFeedback saves whether the chat user found their last answer helpful
+func (s *Service) RecordFeedback(ctx context.Context, userChatID string, helpful bool) (*interaction.Interaction, error) {
+	return s.repo.SetLatestFeedback(ctx, userChatID, helpful)
+}
@@ -4,4 +4,6 @@ import "context"
 
 type Repository interface {
 	CreateInteraction(ctx context.Context, i Interaction) (*Interaction, error)
+	// SetLatestFeedback records the feedback on the last answer the chat user got
+	SetLatestFeedback(ctx context.Context, userChatID string, helpful bool) (*Interaction, error)
 }
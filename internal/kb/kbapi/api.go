@@ -2,6 +2,7 @@ package kbapi
 
 import (
 	"context"
+	"log"
 	"strconv"
 	"time"
 
@@ -9,28 +10,30 @@ import (
 	"github.com/Abraxas-365/opd/internal/user"
 	"github.com/Abraxas-365/toolkit/pkg/errors"
 	"github.com/Abraxas-365/toolkit/pkg/lucia"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+const (
+	chatRateLimitMax    = 100           // Maximum number of requests per IP
+	chatRateLimitWindow = 8 * time.Hour // Time window for rate limiting
 )
 
 // SetupRoutes sets up the API routes for the knowledge base service
 func SetupRoutes(app *fiber.App, service *kbsrv.Service, authMiddleware *lucia.AuthMiddleware[*user.User]) {
+	limiter := newChatLimiter(chatRateLimitMax, chatRateLimitWindow)
+	limiterGroup := app.Group("/chat", limiter.Handler())
+
 	// Define the route for completing answers with metadata
-	limiterGroup := app.Group("/chat", limiter.New(limiter.Config{
-		Max:        100,           // Maximum number of requests per IP
-		Expiration: 8 * time.Hour, // Time window for rate limiting
-		KeyGenerator: func(c *fiber.Ctx) string {
-			return c.IP() // Limit by IP
-		},
-		LimitReached: func(c *fiber.Ctx) error {
-			return c.Status(fiber.StatusTooManyRequests).SendString("Rate limit exceeded")
-		},
-	}))
 	limiterGroup.Post("/complete-answer", func(c *fiber.Ctx) error {
 		type Request struct {
 			UserMessage string  `json:"userMessage"`
 			SessionID   *string `json:"sessionID,omitempty"`
 			UserChatID  string  `json:"userChatID,omitempty"`
+			// FromSuggestion is set when the question is one of the suggested follow-ups
+			FromSuggestion bool `json:"fromSuggestion,omitempty"`
+			// Standalone answers the question outside the conversation, it may come from the answer cache
+			Standalone bool `json:"standalone,omitempty"`
 		}
 
 		var req Request
@@ -38,12 +41,76 @@ func SetupRoutes(app *fiber.App, service *kbsrv.Service, authMiddleware *lucia.A
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 		}
 
-		output, err := service.CompleteAnswerWithMetadata(context.TODO(), req.UserMessage, req.SessionID, req.UserChatID)
+		var opts []kbsrv.AnswerOption
+		if req.FromSuggestion {
+			opts = append(opts, kbsrv.FromSuggestion())
+		}
+		if req.Standalone {
+			opts = append(opts, kbsrv.Standalone())
+		}
+
+		output, err := service.CompleteAnswerWithMetadata(context.TODO(), req.UserMessage, req.SessionID, req.UserChatID, opts...)
 		if err != nil {
 			return err
 		}
 
-		return c.JSON(output)
+		return c.JSON(answerWithSuggestions(context.TODO(), service, req.UserChatID, req.UserMessage, output, opts...))
+	})
+
+	setupWebSocketRoutes(limiterGroup, service, limiter)
+
+	// Forget the stored Bedrock session so the next question starts a new conversation
+	limiterGroup.Post("/new-conversation", func(c *fiber.Ctx) error {
+		type Request struct {
+			UserChatID string `json:"userChatID"`
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+		if req.UserChatID == "" {
+			return errors.ErrBadRequest("userChatID is required")
+		}
+
+		if err := service.StartNewConversation(c.Context(), req.UserChatID); err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{"message": "New conversation started"})
+	})
+
+	// Prompt for the follow-up suggestions offered after each answer
+	app.Get("/settings/suggestions-prompt", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		prompt, err := service.GetSuggestionsPrompt(c.Context())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{"prompt": prompt})
+	})
+
+	app.Put("/settings/suggestions-prompt", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		type Request struct {
+			Prompt string `json:"prompt"`
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		userID, err := lucia.GetSession(c).UserIDToString()
+		if err != nil {
+			return err
+		}
+
+		prompt, err := service.SaveSuggestionsPrompt(c.Context(), req.Prompt, userID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{"prompt": prompt})
 	})
 
 	// Route to generate a presigned PUT URL
@@ -168,3 +235,18 @@ func SetupRoutes(app *fiber.App, service *kbsrv.Service, authMiddleware *lucia.A
 	//		})
 	//	})
 }
+
+// answerWithSuggestions adds the follow-up suggestions to an answer. Suggestions are optional,
+// when they fail the answer is still returned without them.
+func answerWithSuggestions(ctx context.Context, service *kbsrv.Service, userChatID string, question string, output *bedrockagentruntime.RetrieveAndGenerateOutput, opts ...kbsrv.AnswerOption) kbsrv.Answer {
+	suggestions, err := service.SuggestFollowUps(ctx, userChatID, question, output, opts...)
+	if err != nil {
+		log.Printf("failed to suggest follow-ups: %v", err)
+		suggestions = []string{}
+	}
+
+	return kbsrv.Answer{
+		RetrieveAndGenerateOutput: output,
+		Suggestions:               suggestions,
+	}
+}
@@ -0,0 +1,69 @@
+package kbapi
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// chatLimiter is the /chat rate limit by IP. WebSocket messages take hits from the same
+// windows as HTTP requests, the route middleware only sees the upgrade request.
+type chatLimiter struct {
+	mu      sync.Mutex
+	max     int
+	window  time.Duration
+	windows map[string]*limitWindow
+}
+
+type limitWindow struct {
+	hits      int
+	expiresAt time.Time
+}
+
+func newChatLimiter(max int, window time.Duration) *chatLimiter {
+	return &chatLimiter{
+		max:     max,
+		window:  window,
+		windows: make(map[string]*limitWindow),
+	}
+}
+
+// Handler rejects requests over the limit of their IP
+func (l *chatLimiter) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !l.Allow(c.IP()) {
+			return c.Status(fiber.StatusTooManyRequests).SendString("Rate limit exceeded")
+		}
+		return c.Next()
+	}
+}
+
+// Allow records a hit for key and reports whether it is still within the limit
+func (l *chatLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	w, ok := l.windows[key]
+	if !ok || now.After(w.expiresAt) {
+		l.purge(now)
+		w = &limitWindow{expiresAt: now.Add(l.window)}
+		l.windows[key] = w
+	}
+
+	if w.hits >= l.max {
+		return false
+	}
+	w.hits++
+	return true
+}
+
+// purge drops expired windows so idle clients do not accumulate
+func (l *chatLimiter) purge(now time.Time) {
+	for key, w := range l.windows {
+		if now.After(w.expiresAt) {
+			delete(l.windows, key)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package kbapi
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	kbsrv "github.com/Abraxas-365/opd/internal/kb/kbasesrv"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+// wsMessage is the frame sent to WebSocket clients.
+// Type is "chunk" for partial answers, "answer" for the full answer and "error" on failures.
+// Clients show the "answer" text in place of the chunks, it is a refusal when a guardrail stopped the answer.
+// Chunks never break the output guardrails, they stop before the first one that would.
+type wsMessage struct {
+	Type  string      `json:"type"`
+	Text  string      `json:"text,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// setupWebSocketRoutes adds /chat/ws, a persistent connection that takes the same
+// requests as /chat/complete-answer and streams the answers back
+func setupWebSocketRoutes(chat fiber.Router, service *kbsrv.Service, limiter *chatLimiter) {
+	// Authenticate the chat user once, before upgrading the connection
+	chat.Use("/ws", func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return fiber.ErrUpgradeRequired
+		}
+
+		userChatID := c.Query("userChatID")
+		if userChatID == "" {
+			return errors.ErrBadRequest("userChatID is required")
+		}
+		if _, err := service.GetChatUser(c.Context(), userChatID); err != nil {
+			return err
+		}
+
+		c.Locals("userChatID", userChatID)
+		c.Locals("ip", c.IP())
+		return c.Next()
+	})
+
+	chat.Get("/ws", websocket.New(func(conn *websocket.Conn) {
+		type Request struct {
+			UserMessage string  `json:"userMessage"`
+			SessionID   *string `json:"sessionID,omitempty"`
+			UserChatID  string  `json:"userChatID,omitempty"`
+			// FromSuggestion is set when the question is one of the suggested follow-ups
+			FromSuggestion bool `json:"fromSuggestion,omitempty"`
+			// Standalone answers the question outside the conversation, it may come from the answer cache
+			Standalone bool `json:"standalone,omitempty"`
+		}
+
+		userChatID := conn.Locals("userChatID").(string)
+		ip := conn.Locals("ip").(string)
+
+		// Messages are read in the background so closing the connection cancels the answer in progress
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		incoming := make(chan []byte)
+		go func() {
+			defer cancel()
+			for {
+				_, raw, err := conn.ReadMessage()
+				if err != nil {
+					if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+						log.Printf("websocket read error: %v", err)
+					}
+					return
+				}
+				select {
+				case incoming <- raw:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		for {
+			var raw []byte
+			select {
+			case raw = <-incoming:
+			case <-ctx.Done():
+				return
+			}
+
+			var req Request
+			if err := json.Unmarshal(raw, &req); err != nil {
+				if err := conn.WriteJSON(wsMessage{Type: "error", Error: "Invalid request body"}); err != nil {
+					return
+				}
+				continue
+			}
+			if req.UserChatID != "" && req.UserChatID != userChatID {
+				if err := conn.WriteJSON(wsMessage{Type: "error", Error: "userChatID does not match the connection"}); err != nil {
+					return
+				}
+				continue
+			}
+
+			if !limiter.Allow(ip) {
+				if err := conn.WriteJSON(wsMessage{Type: "error", Error: "Rate limit exceeded"}); err != nil {
+					return
+				}
+				continue
+			}
+
+			var opts []kbsrv.AnswerOption
+			if req.FromSuggestion {
+				opts = append(opts, kbsrv.FromSuggestion())
+			}
+			if req.Standalone {
+				opts = append(opts, kbsrv.Standalone())
+			}
+
+			output, err := service.StreamAnswerWithMetadata(ctx, req.UserMessage, req.SessionID, userChatID, func(text string) error {
+				return conn.WriteJSON(wsMessage{Type: "chunk", Text: text})
+			}, opts...)
+			if err != nil {
+				if err := conn.WriteJSON(wsMessage{Type: "error", Error: err.Error()}); err != nil {
+					return
+				}
+				continue
+			}
+
+			answer := answerWithSuggestions(ctx, service, userChatID, req.UserMessage, output, opts...)
+			if err := conn.WriteJSON(wsMessage{Type: "answer", Data: answer}); err != nil {
+				return
+			}
+		}
+	}))
+}
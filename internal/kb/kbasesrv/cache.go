@@ -0,0 +1,135 @@
+package kbsrv
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/answercache"
+	"github.com/Abraxas-365/opd/internal/kb"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime/types"
+	"github.com/aws/aws-sdk-go/service/bedrockagent"
+)
+
+// promptVersion identifies the settings that shape an answer, so cached answers
+// are only reused while the model, prompts and retrieval stay the same
+func promptVersion(kbConf *kb.KnowlegeBaseConfig) string {
+	h := sha256.New()
+	for _, part := range []string{
+		kbConf.Model.ModelId,
+		kbConf.Model.Prompt,
+		orchestrationPrompt,
+		strconv.Itoa(kbConf.NumberOfResults),
+		kbConf.GuardrailID,
+		kbConf.GuardrailVersion,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// cacheReferences keeps the S3 references cited by an answer
+func cacheReferences(output *bedrockagentruntime.RetrieveAndGenerateOutput) []answercache.Reference {
+	var refs []answercache.Reference
+	for _, citation := range output.Citations {
+		for _, ref := range citation.RetrievedReferences {
+			if ref.Location == nil || ref.Location.S3Location == nil || ref.Location.S3Location.Uri == nil {
+				continue
+			}
+			r := answercache.Reference{URI: *ref.Location.S3Location.Uri}
+			if ref.Content != nil && ref.Content.Text != nil {
+				r.Content = *ref.Content.Text
+			}
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+// cachedOutput answers with a cached answer in the same shape as a model answer,
+// every reference is cited by the whole answer
+func cachedOutput(e answercache.Entry) *bedrockagentruntime.RetrieveAndGenerateOutput {
+	output := &bedrockagentruntime.RetrieveAndGenerateOutput{
+		Output:          &types.RetrieveAndGenerateOutput{Text: aws.String(e.Answer)},
+		GuardrailAction: types.GuadrailActionNone,
+	}
+	if len(e.References) == 0 {
+		return output
+	}
+
+	citation := types.Citation{
+		GeneratedResponsePart: &types.GeneratedResponsePart{
+			TextResponsePart: &types.TextResponsePart{
+				Text: aws.String(e.Answer),
+			},
+		},
+	}
+	for _, ref := range e.References {
+		citation.RetrievedReferences = append(citation.RetrievedReferences, types.RetrievedReference{
+			Content: &types.RetrievalResultContent{Text: aws.String(ref.Content)},
+			Location: &types.RetrievalResultLocation{
+				Type:       types.RetrievalResultLocationTypeS3,
+				S3Location: &types.RetrievalResultS3Location{Uri: aws.String(ref.URI)},
+			},
+		})
+	}
+	output.Citations = []types.Citation{citation}
+	return output
+}
+
+// WatchIngestions invalidates the cached answers every time an ingestion job of the knowledge base
+// completes, checking every interval until ctx is done. Jobs started from the AWS console count too.
+func (s *Service) WatchIngestions(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.invalidateIngested(ctx); err != nil {
+			log.Printf("answer cache: failed to check ingestion jobs: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// invalidateIngested drops the answers cached before the last completed ingestion job
+func (s *Service) invalidateIngested(ctx context.Context) error {
+	kbConf, err := s.repo.GetKnowlegeBaseConfig()
+	if err != nil {
+		return err
+	}
+
+	jobs, err := s.brClient.ListIngestionJobsWithContext(ctx, &bedrockagent.ListIngestionJobsInput{
+		KnowledgeBaseId: aws.String(kbConf.ID),
+		DataSourceId:    aws.String(kbConf.S3DataSurce),
+		Filters: []*bedrockagent.IngestionJobFilter{{
+			Attribute: aws.String(bedrockagent.IngestionJobFilterAttributeStatus),
+			Operator:  aws.String(bedrockagent.IngestionJobFilterOperatorEq),
+			Values:    []*string{aws.String(bedrockagent.IngestionJobStatusComplete)},
+		}},
+		SortBy: &bedrockagent.IngestionJobSortBy{
+			Attribute: aws.String(bedrockagent.IngestionJobSortByAttributeStartedAt),
+			Order:     aws.String(bedrockagent.SortOrderDescending),
+		},
+		MaxResults: aws.Int64(1),
+	})
+	if err != nil {
+		return errors.ErrServiceUnavailable(err.Error())
+	}
+	if len(jobs.IngestionJobSummaries) == 0 || jobs.IngestionJobSummaries[0].UpdatedAt == nil {
+		return nil
+	}
+
+	return s.answerCacheService.InvalidateBefore(ctx, kbConf.ID, *jobs.IngestionJobSummaries[0].UpdatedAt)
+}
@@ -0,0 +1,210 @@
+package kbsrv
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Abraxas-365/opd/internal/evaluation"
+	"github.com/Abraxas-365/opd/internal/kb"
+	"github.com/Abraxas-365/opd/internal/usage/usagesrv"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime/types"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	runtimetypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// judgePrompt asks for a 1 to 5 grade, models grade more consistently on a short scale
+const judgePrompt = `You grade answers of a knowledge base assistant. Compare the answer with the expected answer for the question.
+Grade from 1 to 5 how well the answer gives the same information as the expected answer:
+5 all the information and nothing that contradicts it, 4 almost all, 3 part of it, 2 little of it, 1 none or contradicts it.
+Ignore differences in wording, language, length and format.
+Reply only with JSON like {"grade": 4, "reasoning": "one sentence"}.
+
+<question>%question%</question>
+<expected_answer>%expected%</expected_answer>
+<answer>%answer%</answer>`
+
+// judgeReply matches the JSON object in the judge reply, models sometimes wrap it in text
+var judgeReply = regexp.MustCompile(`(?s)\{.*\}`)
+
+// Evaluator answers evaluation cases with the knowledge base and grades them, implementing
+// evaluation.Answerer and evaluation.Judge. It skips everything a chat answer goes through
+// besides the model, like PII redaction, guardrails, FAQs and the answer cache.
+type Evaluator struct {
+	kbClient     *bedrockagentruntime.Client
+	modelClient  *bedrockruntime.Client
+	repo         kb.Repository
+	usageService *usagesrv.Service
+}
+
+func NewEvaluator(kbClient *bedrockagentruntime.Client, modelClient *bedrockruntime.Client, repo kb.Repository, usageService *usagesrv.Service) *Evaluator {
+	return &Evaluator{
+		kbClient:     kbClient,
+		modelClient:  modelClient,
+		repo:         repo,
+		usageService: usageService,
+	}
+}
+
+// ResolveTarget fills the empty fields of target with the knowledge base configuration
+func (e *Evaluator) ResolveTarget(ctx context.Context, target evaluation.Target) (evaluation.Target, error) {
+	kbConf, err := e.repo.GetKnowlegeBaseConfig()
+	if err != nil {
+		return target, err
+	}
+	if target.ModelID == "" {
+		target.ModelID = kbConf.Model.ModelId
+	}
+	if target.Prompt == "" {
+		target.Prompt = kbConf.Model.Prompt
+	}
+	if target.NumberOfResults == 0 {
+		target.NumberOfResults = kbConf.NumberOfResults
+	}
+	return target, nil
+}
+
+// AnswerForEvaluation retrieves and answers the question without a session,
+// returning the files retrieval found besides the ones the answer cited
+func (e *Evaluator) AnswerForEvaluation(ctx context.Context, target evaluation.Target, question string) (*evaluation.GeneratedAnswer, error) {
+	kbConf, err := e.repo.GetKnowlegeBaseConfig()
+	if err != nil {
+		return nil, err
+	}
+	if target.ModelID != "" {
+		kbConf.Model.ModelId = target.ModelID
+	}
+	if target.Prompt != "" {
+		kbConf.Model.Prompt = target.Prompt
+	}
+	if target.NumberOfResults > 0 {
+		kbConf.NumberOfResults = target.NumberOfResults
+	}
+
+	retrieved, err := e.kbClient.Retrieve(ctx, &bedrockagentruntime.RetrieveInput{
+		KnowledgeBaseId:        aws.String(kbConf.ID),
+		RetrievalQuery:         &types.KnowledgeBaseQuery{Text: aws.String(question)},
+		RetrievalConfiguration: retrievalConfiguration(kbConf),
+	})
+	if err != nil {
+		return nil, errors.ErrServiceUnavailable("failed to retrieve: " + err.Error())
+	}
+
+	output, err := e.kbClient.RetrieveAndGenerate(ctx, &bedrockagentruntime.RetrieveAndGenerateInput{
+		Input:                            &types.RetrieveAndGenerateInput{Text: aws.String(question)},
+		RetrieveAndGenerateConfiguration: retrieveAndGenerateConfiguration(kbConf),
+	})
+	if err != nil {
+		return nil, errors.ErrServiceUnavailable("failed to generate: " + err.Error())
+	}
+
+	answer := &evaluation.GeneratedAnswer{}
+	if output.Output != nil && output.Output.Text != nil {
+		answer.Text = *output.Output.Text
+	}
+	e.recordUsage(ctx, evaluationCompletion(kbConf, question, answer.Text, output))
+
+	var retrievedURIs []string
+	for _, r := range retrieved.RetrievalResults {
+		if r.Location != nil && r.Location.S3Location != nil && r.Location.S3Location.Uri != nil {
+			retrievedURIs = append(retrievedURIs, *r.Location.S3Location.Uri)
+		}
+	}
+	if answer.Retrieved, err = e.sources(ctx, retrievedURIs); err != nil {
+		return nil, err
+	}
+	if answer.Cited, err = e.sources(ctx, citedURIs(output)); err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
+
+// sources resolves S3 URIs into the uploaded file names, once per file
+func (e *Evaluator) sources(ctx context.Context, uris []string) ([]evaluation.Source, error) {
+	var sources []evaluation.Source
+	seen := make(map[string]bool)
+	for _, uri := range uris {
+		key := s3KeyFromURI(uri)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		source := evaluation.Source{Filename: path.Base(key), S3Key: key}
+		file, err := e.repo.GetDataByS3Key(ctx, key)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		if file != nil {
+			source.Filename = file.Filename
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// Grade asks the judge model how close the answer is to the expected one, scaled from 0 to 1
+func (e *Evaluator) Grade(ctx context.Context, question, expectedAnswer, answer string) (*evaluation.Grade, error) {
+	kbConf, err := e.repo.GetKnowlegeBaseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := strings.NewReplacer(
+		"%question%", question,
+		"%expected%", expectedAnswer,
+		"%answer%", answer,
+	).Replace(judgePrompt)
+
+	resp, err := e.modelClient.Converse(ctx, &bedrockruntime.ConverseInput{
+		ModelId: aws.String(kbConf.Model.JudgeModelId),
+		Messages: []runtimetypes.Message{{
+			Role:    runtimetypes.ConversationRoleUser,
+			Content: []runtimetypes.ContentBlock{&runtimetypes.ContentBlockMemberText{Value: prompt}},
+		}},
+		InferenceConfig: &runtimetypes.InferenceConfiguration{
+			Temperature: aws.Float32(0),
+			MaxTokens:   aws.Int32(256),
+		},
+	})
+	if err != nil {
+		return nil, errors.ErrServiceUnavailable("failed to grade answer: " + err.Error())
+	}
+	e.recordUsage(ctx, judgeCompletion(kbConf, prompt, resp))
+
+	message, ok := resp.Output.(*runtimetypes.ConverseOutputMemberMessage)
+	if !ok {
+		return nil, errors.ErrUnexpected("judge returned no message")
+	}
+	var text strings.Builder
+	for _, block := range message.Value.Content {
+		if t, ok := block.(*runtimetypes.ContentBlockMemberText); ok {
+			text.WriteString(t.Value)
+		}
+	}
+
+	return parseGrade(text.String())
+}
+
+func parseGrade(reply string) (*evaluation.Grade, error) {
+	var parsed struct {
+		Grade     float64 `json:"grade"`
+		Reasoning string  `json:"reasoning"`
+	}
+	if err := json.Unmarshal([]byte(judgeReply.FindString(reply)), &parsed); err != nil {
+		return nil, errors.ErrUnexpected("judge reply is not valid JSON: " + reply)
+	}
+	if parsed.Grade < 1 || parsed.Grade > 5 {
+		return nil, errors.ErrUnexpected("judge grade out of range: " + reply)
+	}
+
+	return &evaluation.Grade{
+		Score:     (parsed.Grade - 1) / 4,
+		Reasoning: parsed.Reasoning,
+	}, nil
+}
@@ -0,0 +1,38 @@
+package kbsrv
+
+import "testing"
+
+func TestParseGrade(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     string
+		wantScore float64
+		wantErr   bool
+	}{
+		{"best grade", `{"grade": 5, "reasoning": "same information"}`, 1, false},
+		{"worst grade", `{"grade": 1, "reasoning": "contradicts it"}`, 0, false},
+		{"middle grade", `{"grade": 3, "reasoning": "part of it"}`, 0.5, false},
+		{"wrapped in text", "Here is my grade:\n{\"grade\": 4, \"reasoning\": \"almost all\"}\nThanks", 0.75, false},
+		{"out of range", `{"grade": 6, "reasoning": "great"}`, 0, true},
+		{"zero", `{"grade": 0, "reasoning": "missing"}`, 0, true},
+		{"no JSON", "I would give it a 4", 0, true},
+		{"grade as text", `{"grade": "4"}`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grade, err := parseGrade(tt.reply)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseGrade(%q) = %+v, want an error", tt.reply, grade)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseGrade(%q) returned error: %v", tt.reply, err)
+			}
+			if grade.Score != tt.wantScore {
+				t.Errorf("parseGrade(%q) score = %v, want %v", tt.reply, grade.Score, tt.wantScore)
+			}
+		})
+	}
+}
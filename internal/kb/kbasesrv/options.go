@@ -0,0 +1,49 @@
+package kbsrv
+
+import "github.com/Abraxas-365/opd/internal/chatuser"
+
+type answerOptions struct {
+	fromSuggestion bool
+	channel        string
+	standalone     bool
+}
+
+// AnswerOption changes how a question is answered and recorded
+type AnswerOption func(*answerOptions)
+
+func newAnswerOptions(opts []AnswerOption) answerOptions {
+	var options answerOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// FromSuggestion records that the chat user picked the question from the suggested follow-ups
+func FromSuggestion() AnswerOption {
+	return func(o *answerOptions) {
+		o.fromSuggestion = true
+	}
+}
+
+// Standalone answers the question on its own, outside the chat user's conversation: no session is
+// resumed or stored, so the answer can come from the answer cache without losing a conversation's context
+func Standalone() AnswerOption {
+	return func(o *answerOptions) {
+		o.standalone = true
+	}
+}
+
+// FromChannel records the channel the question arrived through, chatuser.ChannelWeb when not given
+func FromChannel(channel string) AnswerOption {
+	return func(o *answerOptions) {
+		o.channel = channel
+	}
+}
+
+func (o answerOptions) completionChannel() string {
+	if o.channel == "" {
+		return chatuser.ChannelWeb
+	}
+	return o.channel
+}
@@ -2,13 +2,28 @@ package kbsrv
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"log"
+	"path"
+	"strings"
 	"time"
 
+	"github.com/Abraxas-365/opd/internal/answercache/answercachesrv"
+	"github.com/Abraxas-365/opd/internal/chatuser"
 	"github.com/Abraxas-365/opd/internal/chatuser/chatusersrv"
+	"github.com/Abraxas-365/opd/internal/experiment"
+	"github.com/Abraxas-365/opd/internal/experiment/experimentsrv"
+	"github.com/Abraxas-365/opd/internal/faq"
+	"github.com/Abraxas-365/opd/internal/faq/faqsrv"
+	"github.com/Abraxas-365/opd/internal/guardrail/guardrailsrv"
+	"github.com/Abraxas-365/opd/internal/handoff"
+	"github.com/Abraxas-365/opd/internal/handoff/handoffsrv"
 	"github.com/Abraxas-365/opd/internal/interaction"
 	"github.com/Abraxas-365/opd/internal/interaction/interactionsrv"
 	"github.com/Abraxas-365/opd/internal/kb"
+	"github.com/Abraxas-365/opd/internal/pii/piisrv"
+	"github.com/Abraxas-365/opd/internal/usage/usagesrv"
 	"github.com/Abraxas-365/opd/internal/user/usersrv"
 	"github.com/Abraxas-365/toolkit/pkg/database"
 	"github.com/Abraxas-365/toolkit/pkg/errors"
@@ -16,6 +31,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime/types"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/bedrockagent"
 	"github.com/google/uuid"
@@ -24,43 +40,60 @@ import (
 type Service struct {
 	kbClient           *bedrockagentruntime.Client
 	brClient           *bedrockagent.BedrockAgent
+	modelClient        *bedrockruntime.Client
 	repo               kb.Repository
 	userService        usersrv.Service
 	userChatService    chatusersrv.Service
 	interactionService interactionsrv.Service
+	handoffService     *handoffsrv.Service
+	faqService         *faqsrv.Service
+	guardrailService   *guardrailsrv.Service
+	piiService         *piisrv.Service
+	answerCacheService *answercachesrv.Service
+	experimentService  *experimentsrv.Service
+	usageService       *usagesrv.Service
 	s3Client           s3client.Client
 }
 
 func New(kbClient *bedrockagentruntime.Client,
 	brClient *bedrockagent.BedrockAgent,
+	modelClient *bedrockruntime.Client,
 	repo kb.Repository,
 	s3 s3client.Client,
 	userService usersrv.Service,
 	userChatService chatusersrv.Service,
 	InteractionService interactionsrv.Service,
+	handoffService *handoffsrv.Service,
+	faqService *faqsrv.Service,
+	guardrailService *guardrailsrv.Service,
+	piiService *piisrv.Service,
+	answerCacheService *answercachesrv.Service,
+	experimentService *experimentsrv.Service,
+	usageService *usagesrv.Service,
 ) *Service {
 	return &Service{
 		kbClient:           kbClient,
 		repo:               repo,
 		brClient:           brClient,
+		modelClient:        modelClient,
 		s3Client:           s3,
 		userService:        userService,
 		userChatService:    userChatService,
 		interactionService: InteractionService,
+		handoffService:     handoffService,
+		faqService:         faqService,
+		guardrailService:   guardrailService,
+		piiService:         piiService,
+		answerCacheService: answerCacheService,
+		experimentService:  experimentService,
+		usageService:       usageService,
 	}
 }
 
-func (s *Service) CompleteAnswerWithMetadata(ctx context.Context, userMessage string, sessionID *string, userchatID string) (*bedrockagentruntime.RetrieveAndGenerateOutput, error) {
-	kbConf, err := s.repo.GetKnowlegeBaseConfig()
-	if err != nil {
-		return nil, err
-	}
+// citationLinkTTL is how long citation links shared on chat channels stay valid, the presigning maximum
+const citationLinkTTL = 7 * 24 * time.Hour
 
-	if _, err := s.userChatService.GetChatUserByID(ctx, userchatID); err != nil {
-		return nil, err
-	}
-
-	orchestrationPrompt := `You are a query creation agent. You will be provided with a function and a description of what it searches over. The user will provide you a question, and your job is to determine the optimal query to use based on the user's question.
+const orchestrationPrompt = `You are a query creation agent. You will be provided with a function and a description of what it searches over. The user will provide you a question, and your job is to determine the optimal query to use based on the user's question.
 Always create the questions in the lenguge of the user, in which he is interacting.
 Here are a few examples of queries formed by other search function selection and query creation agents: 
 
@@ -99,62 +132,409 @@ $conversation_history$
 
 $output_format_instructions$`
 
-	output, err := s.kbClient.RetrieveAndGenerate(
-		context.TODO(),
+// generateFunc calls Bedrock for a single question in the given session
+type generateFunc func(ctx context.Context, kbConf *kb.KnowlegeBaseConfig, userMessage string, sessionID *string) (*bedrockagentruntime.RetrieveAndGenerateOutput, error)
+
+func (s *Service) CompleteAnswerWithMetadata(ctx context.Context, userMessage string, sessionID *string, userchatID string, opts ...AnswerOption) (*bedrockagentruntime.RetrieveAndGenerateOutput, error) {
+	return s.completeAnswer(ctx, userMessage, sessionID, userchatID, s.retrieveAndGenerate, opts...)
+}
+
+// StreamAnswerWithMetadata works like CompleteAnswerWithMetadata but calls onText with every
+// partial answer as Bedrock generates it. The returned output holds the full answer.
+// Chunks stop as soon as the answer so far breaks an output rule, and with a Bedrock Guardrail
+// configured none are sent since its verdict only arrives with the answer.
+func (s *Service) StreamAnswerWithMetadata(ctx context.Context, userMessage string, sessionID *string, userchatID string, onText func(text string) error, opts ...AnswerOption) (*bedrockagentruntime.RetrieveAndGenerateOutput, error) {
+	generate := func(ctx context.Context, kbConf *kb.KnowlegeBaseConfig, userMessage string, sessionID *string) (*bedrockagentruntime.RetrieveAndGenerateOutput, error) {
+		var streamed strings.Builder
+		withheld := kbConf.GuardrailID != ""
+		forward := func(text string) error {
+			streamed.WriteString(text)
+			if withheld {
+				return nil
+			}
+			if !s.guardrailService.AllowsOutput(streamed.String()) {
+				withheld = true
+				return nil
+			}
+			return onText(text)
+		}
+		return s.retrieveAndGenerateStream(ctx, kbConf, userMessage, sessionID, forward)
+	}
+	return s.completeAnswer(ctx, userMessage, sessionID, userchatID, generate, opts...)
+}
+
+// GetChatUser checks that the chat user exists, used by transports that authenticate once per connection
+func (s *Service) GetChatUser(ctx context.Context, userchatID string) (*chatuser.ChatUser, error) {
+	return s.userChatService.GetChatUserByID(ctx, userchatID)
+}
+
+func (s *Service) completeAnswer(ctx context.Context, userMessage string, sessionID *string, userchatID string, generate generateFunc, opts ...AnswerOption) (*bedrockagentruntime.RetrieveAndGenerateOutput, error) {
+	started := time.Now()
+	options := newAnswerOptions(opts)
+
+	kbConf, err := s.repo.GetKnowlegeBaseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.userChatService.GetChatUserByID(ctx, userchatID); err != nil {
+		return nil, err
+	}
+
+	// Personal data never reaches the model or the database, the originals go to the vault
+	userMessage, vaultID, err := s.piiService.Protect(ctx, userchatID, userMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	violation, err := s.guardrailService.CheckInput(ctx, userchatID, userMessage)
+	if err != nil {
+		return nil, err
+	}
+	if violation != nil {
+		return noticeOutput(sessionID, violation.Refusal), nil
+	}
+
+	// While an agent handles the conversation, messages go to the agent instead of the model
+	activeHandoff, err := s.handoffService.GetActiveHandoff(ctx, userchatID)
+	if err != nil {
+		return nil, err
+	}
+	if activeHandoff != nil && activeHandoff.ForwardsMessages() {
+		if _, err := s.handoffService.AddChatUserMessage(ctx, *activeHandoff, userMessage); err != nil {
+			return nil, err
+		}
+		return noticeOutput(sessionID, handoffsrv.ForwardedMessage), nil
+	}
+
+	if s.handoffService.IsHumanRequest(userMessage) {
+		if _, err := s.handoffService.Escalate(ctx, userchatID, userMessage, handoff.ReasonRequested); err != nil {
+			return nil, err
+		}
+		return noticeOutput(sessionID, handoffsrv.EscalatedMessage), nil
+	}
+
+	// Curated answers take priority over generated ones
+	match, err := s.faqService.Match(ctx, userMessage)
+	if err != nil {
+		return nil, err
+	}
+	if match != nil {
+		i := interaction.Interaction{
+			UserChatID:     userchatID,
+			FAQID:          &match.FAQ.ID,
+			FromSuggestion: options.fromSuggestion,
+			Question:       userMessage,
+			AnswerStatus:   interaction.StatusAnswered,
+			PIIVaultID:     vaultID,
+			LatencyMs:      elapsedMs(started),
+		}
+		if _, err := s.interactionService.CreateInteraction(ctx, i); err != nil {
+			return nil, err
+		}
+		return faqOutput(sessionID, match.FAQ), nil
+	}
+
+	if options.standalone {
+		sessionID = nil
+	}
+
+	// Resume the chat user's conversation when the client does not send one
+	resumed := false
+	if sessionID == nil && !options.standalone {
+		sessionID, err = s.userChatService.GetActiveSessionID(ctx, userchatID)
+		if err != nil {
+			return nil, err
+		}
+		resumed = sessionID != nil
+	}
+
+	// Chat users in an experiment are answered with the configuration of their variant
+	variant, err := s.experimentService.Assign(ctx, userchatID)
+	if err != nil {
+		return nil, err
+	}
+	var variantID *int
+	if variant != nil {
+		applyVariant(kbConf, *variant)
+		variantID = &variant.ID
+	}
+
+	// Only questions without a conversation to continue are answered from the cache, follow-ups depend
+	// on the history. A cached answer has no Bedrock session, so the next question starts a new one.
+	version := promptVersion(kbConf)
+	var output *bedrockagentruntime.RetrieveAndGenerateOutput
+	cached := false
+	if sessionID == nil {
+		hit, err := s.answerCacheService.Lookup(ctx, kbConf.ID, version, userMessage)
+		if err != nil {
+			return nil, err
+		}
+		if hit != nil {
+			output = cachedOutput(hit.Entry)
+			cached = true
+		}
+	}
+
+	if output == nil {
+		output, err = generate(ctx, kbConf, userMessage, sessionID)
+		var validationErr *types.ValidationException
+		if err != nil && resumed && stderrors.As(err, &validationErr) {
+			// The stored session expired on Bedrock's side, start a new one
+			output, err = generate(ctx, kbConf, userMessage, nil)
+		}
+		if err != nil {
+			return nil, errors.ErrServiceUnavailable(err.Error())
+		}
+	}
+
+	if output.SessionId != nil && !options.standalone {
+		if _, err := s.userChatService.TouchSession(ctx, userchatID, *output.SessionId); err != nil {
+			return nil, err
+		}
+	}
+
+	var answer string
+	if output.Output != nil && output.Output.Text != nil {
+		answer = *output.Output.Text
+	}
+
+	if output.GuardrailAction == types.GuadrailActionIntervened {
+		violation, err = s.guardrailService.RecordBedrockIntervention(ctx, userchatID, userMessage)
+	} else {
+		violation, err = s.guardrailService.CheckOutput(ctx, userchatID, answer)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if violation != nil {
+		if !cached {
+			s.recordUsage(ctx, answerCompletion(kbConf, userchatID, options, userMessage, answer, output, nil))
+		}
+		return noticeOutput(output.SessionId, violation.Refusal), nil
+	}
+	cited := citedURIs(output)
+
+	i := interaction.Interaction{
+		UserChatID:         userchatID,
+		ContextInteraction: cited,
+		FromSuggestion:     options.fromSuggestion,
+		Question:           userMessage,
+		AnswerStatus:       interaction.Classify(answer, len(cited) > 0),
+		PIIVaultID:         vaultID,
+		Cached:             cached,
+		VariantID:          variantID,
+		LatencyMs:          elapsedMs(started),
+	}
+
+	created, err := s.interactionService.CreateInteraction(ctx, i)
+	if err != nil {
+		return nil, err
+	}
+	if !cached {
+		s.recordUsage(ctx, answerCompletion(kbConf, userchatID, options, userMessage, answer, output, &created.ID))
+	}
+
+	if !cached && sessionID == nil && i.AnswerStatus == interaction.StatusAnswered {
+		if err := s.answerCacheService.Store(ctx, kbConf.ID, version, userMessage, answer, cacheReferences(output)); err != nil {
+			log.Printf("answer cache: failed to store answer: %v", err)
+		}
+	}
+
+	if i.AnswerStatus == interaction.StatusNotFound {
+		if _, err := s.handoffService.Escalate(ctx, userchatID, userMessage, handoff.ReasonNotFound); err != nil {
+			return nil, err
+		}
+		output.Output = &types.RetrieveAndGenerateOutput{Text: aws.String(strings.TrimSpace(answer + "\n\n" + handoffsrv.NotFoundNote))}
+	}
+
+	return output, nil
+}
+
+// applyVariant overrides the knowledge base configuration with the non empty fields of an experiment variant
+func applyVariant(kbConf *kb.KnowlegeBaseConfig, v experiment.Variant) {
+	if v.ModelID != "" {
+		kbConf.Model.ModelId = v.ModelID
+	}
+	if v.Prompt != "" {
+		kbConf.Model.Prompt = v.Prompt
+	}
+	if v.NumberOfResults > 0 {
+		kbConf.NumberOfResults = v.NumberOfResults
+	}
+}
+
+func elapsedMs(started time.Time) *int {
+	ms := int(time.Since(started).Milliseconds())
+	return &ms
+}
+
+// noticeOutput answers with a notice in the same shape as a model answer
+func noticeOutput(sessionID *string, text string) *bedrockagentruntime.RetrieveAndGenerateOutput {
+	return &bedrockagentruntime.RetrieveAndGenerateOutput{
+		SessionId:       sessionID,
+		Output:          &types.RetrieveAndGenerateOutput{Text: aws.String(text)},
+		GuardrailAction: types.GuadrailActionNone,
+	}
+}
+
+// faqOutput answers with a curated FAQ answer in the same shape as a model answer,
+// the FAQ citation is a web reference whose content is the citation title
+func faqOutput(sessionID *string, f faq.FAQ) *bedrockagentruntime.RetrieveAndGenerateOutput {
+	output := &bedrockagentruntime.RetrieveAndGenerateOutput{
+		SessionId:       sessionID,
+		Output:          &types.RetrieveAndGenerateOutput{Text: aws.String(f.Answer)},
+		GuardrailAction: types.GuadrailActionNone,
+	}
+	if f.CitationURL == "" {
+		return output
+	}
+
+	output.Citations = []types.Citation{{
+		GeneratedResponsePart: &types.GeneratedResponsePart{
+			TextResponsePart: &types.TextResponsePart{
+				Text: aws.String(f.Answer),
+			},
+		},
+		RetrievedReferences: []types.RetrievedReference{{
+			Content: &types.RetrievalResultContent{Text: aws.String(f.CitationTitle)},
+			Location: &types.RetrievalResultLocation{
+				Type:        types.RetrievalResultLocationTypeWeb,
+				WebLocation: &types.RetrievalResultWebLocation{Url: aws.String(f.CitationURL)},
+			},
+		}},
+	}}
+	return output
+}
+
+// GetCitations resolves the S3 files cited by an answer into file names and download links
+func (s *Service) GetCitations(ctx context.Context, output *bedrockagentruntime.RetrieveAndGenerateOutput) ([]kb.Citation, error) {
+	var citations []kb.Citation
+	seen := make(map[string]bool)
+	for _, uri := range citedURIs(output) {
+		key := s3KeyFromURI(uri)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		citation := kb.Citation{
+			Filename: path.Base(key),
+			S3Key:    key,
+		}
+		file, err := s.repo.GetDataByS3Key(ctx, key)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		if file != nil {
+			citation.Filename = file.Filename
+		}
+
+		url, err := s.s3Client.GeneratePresignedGetURL(key, citationLinkTTL)
+		if err != nil {
+			return nil, errors.ErrServiceUnavailable("failed to generate citation link: " + err.Error())
+		}
+		citation.URL = url
+
+		citations = append(citations, citation)
+	}
+
+	// Web references come from curated FAQ answers and link to their source directly
+	for _, c := range output.Citations {
+		for _, ref := range c.RetrievedReferences {
+			if ref.Location == nil || ref.Location.WebLocation == nil || ref.Location.WebLocation.Url == nil {
+				continue
+			}
+			url := *ref.Location.WebLocation.Url
+			if seen[url] {
+				continue
+			}
+			seen[url] = true
+
+			citation := kb.Citation{Filename: url, URL: url}
+			if ref.Content != nil && ref.Content.Text != nil {
+				citation.Filename = *ref.Content.Text
+			}
+			citations = append(citations, citation)
+		}
+	}
+	return citations, nil
+}
+
+// StartNewConversation drops the chat user's stored session so the next answer starts fresh
+func (s *Service) StartNewConversation(ctx context.Context, userchatID string) error {
+	return s.userChatService.EndSession(ctx, userchatID)
+}
+
+// retrieveAndGenerate runs a RetrieveAndGenerate call against the configured knowledge base
+func (s *Service) retrieveAndGenerate(ctx context.Context, kbConf *kb.KnowlegeBaseConfig, userMessage string, sessionID *string) (*bedrockagentruntime.RetrieveAndGenerateOutput, error) {
+	return s.kbClient.RetrieveAndGenerate(
+		ctx,
 		&bedrockagentruntime.RetrieveAndGenerateInput{
 			SessionId: sessionID,
 			Input: &types.RetrieveAndGenerateInput{
 				Text: aws.String(userMessage),
 			},
-			RetrieveAndGenerateConfiguration: &types.RetrieveAndGenerateConfiguration{
-				Type: types.RetrieveAndGenerateTypeKnowledgeBase,
-				KnowledgeBaseConfiguration: &types.KnowledgeBaseRetrieveAndGenerateConfiguration{
-					KnowledgeBaseId: aws.String(kbConf.ID),
-					ModelArn:        aws.String(kbConf.Model.ModelId),
-					RetrievalConfiguration: &types.KnowledgeBaseRetrievalConfiguration{
-						VectorSearchConfiguration: &types.KnowledgeBaseVectorSearchConfiguration{
-							NumberOfResults:    aws.Int32(int32(kbConf.NumberOfResults)),
-							OverrideSearchType: types.SearchTypeHybrid,
-						},
-					},
-					GenerationConfiguration: &types.GenerationConfiguration{
-						PromptTemplate: &types.PromptTemplate{
-							TextPromptTemplate: aws.String(kbConf.Model.Prompt),
-						},
-						InferenceConfig: &types.InferenceConfig{
-							TextInferenceConfig: &types.TextInferenceConfig{
-								Temperature:   aws.Float32(0),
-								TopP:          aws.Float32(1),
-								MaxTokens:     aws.Int32(2048),
-								StopSequences: []string{"\nObservation"},
-							},
-						},
-					},
-					OrchestrationConfiguration: &types.OrchestrationConfiguration{
-						QueryTransformationConfiguration: &types.QueryTransformationConfiguration{
-							Type: types.QueryTransformationTypeQueryDecomposition,
-						},
-						PromptTemplate: &types.PromptTemplate{
-							TextPromptTemplate: aws.String(orchestrationPrompt),
-						},
-						InferenceConfig: &types.InferenceConfig{
-							TextInferenceConfig: &types.TextInferenceConfig{
-								Temperature:   aws.Float32(0),
-								TopP:          aws.Float32(1),
-								MaxTokens:     aws.Int32(2048),
-								StopSequences: []string{"\nObservation"},
-							},
-						},
-					},
-				},
+			RetrieveAndGenerateConfiguration: retrieveAndGenerateConfiguration(kbConf),
+		},
+	)
+}
+
+// retrieveAndGenerateStream runs a RetrieveAndGenerateStream call, forwarding every text chunk
+// to onText, and assembles the events into the same output RetrieveAndGenerate returns
+func (s *Service) retrieveAndGenerateStream(ctx context.Context, kbConf *kb.KnowlegeBaseConfig, userMessage string, sessionID *string, onText func(text string) error) (*bedrockagentruntime.RetrieveAndGenerateOutput, error) {
+	resp, err := s.kbClient.RetrieveAndGenerateStream(
+		ctx,
+		&bedrockagentruntime.RetrieveAndGenerateStreamInput{
+			SessionId: sessionID,
+			Input: &types.RetrieveAndGenerateInput{
+				Text: aws.String(userMessage),
 			},
+			RetrieveAndGenerateConfiguration: retrieveAndGenerateConfiguration(kbConf),
 		},
 	)
 	if err != nil {
-		return nil, errors.ErrServiceUnavailable(err.Error())
+		return nil, err
+	}
+
+	stream := resp.GetStream()
+	defer stream.Close()
+
+	output := &bedrockagentruntime.RetrieveAndGenerateOutput{
+		SessionId:       resp.SessionId,
+		GuardrailAction: types.GuadrailActionNone,
+	}
+
+	var text strings.Builder
+	for event := range stream.Events() {
+		switch e := event.(type) {
+		case *types.RetrieveAndGenerateStreamResponseOutputMemberOutput:
+			if e.Value.Text == nil {
+				continue
+			}
+			text.WriteString(*e.Value.Text)
+			if err := onText(*e.Value.Text); err != nil {
+				return nil, err
+			}
+		case *types.RetrieveAndGenerateStreamResponseOutputMemberCitation:
+			if e.Value.Citation != nil {
+				output.Citations = append(output.Citations, *e.Value.Citation)
+			}
+		case *types.RetrieveAndGenerateStreamResponseOutputMemberGuardrail:
+			output.GuardrailAction = e.Value.Action
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
 	}
 
-	var chatInformationContext []string
+	output.Output = &types.RetrieveAndGenerateOutput{Text: aws.String(text.String())}
+	return output, nil
+}
+
+// citedURIs returns the S3 URI of every reference cited in the answer
+func citedURIs(output *bedrockagentruntime.RetrieveAndGenerateOutput) []string {
+	var uris []string
 	for _, citation := range output.Citations {
 		for _, ref := range citation.RetrievedReferences {
 			if ref.Location == nil {
@@ -169,20 +549,80 @@ $output_format_instructions$`
 				continue
 			}
 
-			chatInformationContext = append(chatInformationContext, *ref.Location.S3Location.Uri)
+			uris = append(uris, *ref.Location.S3Location.Uri)
 		}
 	}
+	return uris
+}
 
-	i := interaction.Interaction{
-		UserChatID:         userchatID,
-		ContextInteraction: chatInformationContext,
+// s3KeyFromURI turns s3://bucket/data/file into data/file
+func s3KeyFromURI(uri string) string {
+	key := strings.TrimPrefix(uri, "s3://")
+	if i := strings.Index(key, "/"); i >= 0 {
+		return key[i+1:]
 	}
+	return key
+}
 
-	if _, err := s.interactionService.CreateInteraction(ctx, i); err != nil {
-		return nil, err
+func retrieveAndGenerateConfiguration(kbConf *kb.KnowlegeBaseConfig) *types.RetrieveAndGenerateConfiguration {
+	return &types.RetrieveAndGenerateConfiguration{
+		Type: types.RetrieveAndGenerateTypeKnowledgeBase,
+		KnowledgeBaseConfiguration: &types.KnowledgeBaseRetrieveAndGenerateConfiguration{
+			KnowledgeBaseId:        aws.String(kbConf.ID),
+			ModelArn:               aws.String(kbConf.Model.ModelId),
+			RetrievalConfiguration: retrievalConfiguration(kbConf),
+			GenerationConfiguration: &types.GenerationConfiguration{
+				GuardrailConfiguration: guardrailConfiguration(kbConf),
+				PromptTemplate: &types.PromptTemplate{
+					TextPromptTemplate: aws.String(kbConf.Model.Prompt),
+				},
+				InferenceConfig: &types.InferenceConfig{
+					TextInferenceConfig: &types.TextInferenceConfig{
+						Temperature:   aws.Float32(0),
+						TopP:          aws.Float32(1),
+						MaxTokens:     aws.Int32(2048),
+						StopSequences: []string{"\nObservation"},
+					},
+				},
+			},
+			OrchestrationConfiguration: &types.OrchestrationConfiguration{
+				QueryTransformationConfiguration: &types.QueryTransformationConfiguration{
+					Type: types.QueryTransformationTypeQueryDecomposition,
+				},
+				PromptTemplate: &types.PromptTemplate{
+					TextPromptTemplate: aws.String(orchestrationPrompt),
+				},
+				InferenceConfig: &types.InferenceConfig{
+					TextInferenceConfig: &types.TextInferenceConfig{
+						Temperature:   aws.Float32(0),
+						TopP:          aws.Float32(1),
+						MaxTokens:     aws.Int32(2048),
+						StopSequences: []string{"\nObservation"},
+					},
+				},
+			},
+		},
 	}
+}
 
-	return output, nil
+func retrievalConfiguration(kbConf *kb.KnowlegeBaseConfig) *types.KnowledgeBaseRetrievalConfiguration {
+	return &types.KnowledgeBaseRetrievalConfiguration{
+		VectorSearchConfiguration: &types.KnowledgeBaseVectorSearchConfiguration{
+			NumberOfResults:    aws.Int32(int32(kbConf.NumberOfResults)),
+			OverrideSearchType: types.SearchTypeHybrid,
+		},
+	}
+}
+
+// guardrailConfiguration applies the Bedrock Guardrail to generation when one is configured
+func guardrailConfiguration(kbConf *kb.KnowlegeBaseConfig) *types.GuardrailConfiguration {
+	if kbConf.GuardrailID == "" {
+		return nil
+	}
+	return &types.GuardrailConfiguration{
+		GuardrailId:      aws.String(kbConf.GuardrailID),
+		GuardrailVersion: aws.String(kbConf.GuardrailVersion),
+	}
 }
 
 func (s *Service) GeneratePutURL(userID string, file string) (string, error) {
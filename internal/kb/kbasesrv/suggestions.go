@@ -0,0 +1,176 @@
+package kbsrv
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/Abraxas-365/opd/internal/kb"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	runtimetypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// maxSuggestions is how many follow-up questions are offered after an answer
+const maxSuggestions = 3
+
+// Placeholders of the suggestions prompt
+const (
+	suggestionsQuestionVar      = "$question$"
+	suggestionsAnswerVar        = "$answer$"
+	suggestionsSearchResultsVar = "$search_results$"
+)
+
+// DefaultSuggestionsPrompt is used until an admin saves their own
+const DefaultSuggestionsPrompt = `You help users explore a knowledge base. A user asked a question and got an answer based on the search results below.
+Write up to 3 short follow-up questions the user could ask next. Each question must be answerable with the search results.
+Write the questions in the language of the user, one per line, without numbering or any other text.
+
+<search_results>
+$search_results$
+</search_results>
+
+<question>$question$</question>
+<answer>$answer$</answer>`
+
+// Answer is an answer with the follow-up questions offered to the chat user
+type Answer struct {
+	*bedrockagentruntime.RetrieveAndGenerateOutput
+	Suggestions []string `json:"suggestions"`
+}
+
+// SuggestFollowUps asks the model for follow-up questions grounded in the documents the answer
+// was based on. Answers not grounded in the knowledge base, like FAQ or handoff notices, get none.
+func (s *Service) SuggestFollowUps(ctx context.Context, userchatID string, question string, output *bedrockagentruntime.RetrieveAndGenerateOutput, opts ...AnswerOption) ([]string, error) {
+	options := newAnswerOptions(opts)
+
+	results := retrievedContents(output)
+	if len(results) == 0 || output.Output == nil || output.Output.Text == nil {
+		return []string{}, nil
+	}
+
+	kbConf, err := s.repo.GetKnowlegeBaseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, err := s.GetSuggestionsPrompt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prompt = strings.NewReplacer(
+		suggestionsSearchResultsVar, strings.Join(results, "\n\n"),
+		suggestionsQuestionVar, s.piiService.Redact(question),
+		suggestionsAnswerVar, *output.Output.Text,
+	).Replace(prompt)
+
+	resp, err := s.modelClient.Converse(ctx, &bedrockruntime.ConverseInput{
+		ModelId: aws.String(kbConf.Model.SuggestionsModelId),
+		Messages: []runtimetypes.Message{{
+			Role:    runtimetypes.ConversationRoleUser,
+			Content: []runtimetypes.ContentBlock{&runtimetypes.ContentBlockMemberText{Value: prompt}},
+		}},
+		InferenceConfig: &runtimetypes.InferenceConfiguration{
+			Temperature: aws.Float32(0.3),
+			MaxTokens:   aws.Int32(256),
+		},
+	})
+	if err != nil {
+		return nil, errors.ErrServiceUnavailable("failed to generate suggestions: " + err.Error())
+	}
+	s.recordUsage(ctx, suggestionsCompletion(kbConf, userchatID, options, prompt, resp))
+
+	message, ok := resp.Output.(*runtimetypes.ConverseOutputMemberMessage)
+	if !ok {
+		return []string{}, nil
+	}
+
+	var text strings.Builder
+	for _, block := range message.Value.Content {
+		if t, ok := block.(*runtimetypes.ContentBlockMemberText); ok {
+			text.WriteString(t.Value)
+		}
+	}
+	return parseSuggestions(text.String()), nil
+}
+
+// GetSuggestionsPrompt returns the prompt saved by an admin or DefaultSuggestionsPrompt
+func (s *Service) GetSuggestionsPrompt(ctx context.Context) (string, error) {
+	setting, err := s.repo.GetSetting(ctx, kb.SettingSuggestionsPrompt)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return DefaultSuggestionsPrompt, nil
+		}
+		return "", err
+	}
+	return setting.Value, nil
+}
+
+// SaveSuggestionsPrompt replaces the suggestions prompt, an empty prompt restores the default. Only admins can change it.
+func (s *Service) SaveSuggestionsPrompt(ctx context.Context, prompt string, userID string) (string, error) {
+	u, err := s.userService.GetUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAdmin {
+		return "", errors.ErrForbidden("only admins can change the suggestions prompt")
+	}
+
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		prompt = DefaultSuggestionsPrompt
+	}
+	if !strings.Contains(prompt, suggestionsSearchResultsVar) {
+		return "", errors.ErrBadRequest("prompt must include " + suggestionsSearchResultsVar + " to ground the suggestions")
+	}
+
+	setting, err := s.repo.SaveSetting(ctx, kb.Setting{
+		Key:       kb.SettingSuggestionsPrompt,
+		Value:     prompt,
+		UpdatedBy: &userID,
+	})
+	if err != nil {
+		return "", err
+	}
+	return setting.Value, nil
+}
+
+// retrievedContents returns the text of the knowledge base chunks cited by the answer
+func retrievedContents(output *bedrockagentruntime.RetrieveAndGenerateOutput) []string {
+	var contents []string
+	seen := make(map[string]bool)
+	for _, citation := range output.Citations {
+		for _, ref := range citation.RetrievedReferences {
+			if ref.Location == nil || ref.Location.S3Location == nil {
+				continue
+			}
+			if ref.Content == nil || ref.Content.Text == nil || seen[*ref.Content.Text] {
+				continue
+			}
+			seen[*ref.Content.Text] = true
+			contents = append(contents, *ref.Content.Text)
+		}
+	}
+	return contents
+}
+
+// suggestionPrefix matches list markers models add despite the instructions
+var suggestionPrefix = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s*`)
+
+// parseSuggestions takes one question per line, up to maxSuggestions
+func parseSuggestions(text string) []string {
+	suggestions := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(suggestionPrefix.ReplaceAllString(line, ""))
+		if line == "" {
+			continue
+		}
+		suggestions = append(suggestions, line)
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+	}
+	return suggestions
+}
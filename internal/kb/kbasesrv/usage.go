@@ -0,0 +1,100 @@
+package kbsrv
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/Abraxas-365/opd/internal/kb"
+	"github.com/Abraxas-365/opd/internal/usage"
+	"github.com/Abraxas-365/opd/internal/usage/usagesrv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	runtimetypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// recordUsage stores the tokens of a completion. The answer was already paid for,
+// so failing to record it does not fail the answer.
+func (s *Service) recordUsage(ctx context.Context, c usage.Completion) {
+	recordUsage(ctx, s.usageService, c)
+}
+
+// recordUsage stores the tokens of an evaluation completion without failing the evaluation
+func (e *Evaluator) recordUsage(ctx context.Context, c usage.Completion) {
+	recordUsage(ctx, e.usageService, c)
+}
+
+func recordUsage(ctx context.Context, usageService *usagesrv.Service, c usage.Completion) {
+	if _, err := usageService.Record(ctx, c); err != nil {
+		log.Printf("usage: failed to record %s completion: %v", c.Purpose, err)
+	}
+}
+
+// answerCompletion estimates the tokens of a knowledge base answer, RetrieveAndGenerate does not return
+// them. The input is the orchestration and generation prompts with the question and the cited chunks,
+// retrieved chunks the answer did not cite are not returned either so the estimate is a lower bound.
+func answerCompletion(kbConf *kb.KnowlegeBaseConfig, userchatID string, options answerOptions, question, answer string, output *bedrockagentruntime.RetrieveAndGenerateOutput, interactionID *int) usage.Completion {
+	return usage.Completion{
+		InteractionID:   interactionID,
+		UserChatID:      &userchatID,
+		KnowledgeBaseID: kbConf.ID,
+		Channel:         options.completionChannel(),
+		Purpose:         usage.PurposeAnswer,
+		ModelID:         kbConf.Model.ModelId,
+		InputTokens: usage.EstimateTokens(orchestrationPrompt, question,
+			kbConf.Model.Prompt, strings.Join(retrievedContents(output), "\n\n"), question),
+		OutputTokens: usage.EstimateTokens(answer),
+		Estimated:    true,
+	}
+}
+
+// evaluationCompletion estimates the tokens of an evaluation answer like answerCompletion, without a chat user
+func evaluationCompletion(kbConf *kb.KnowlegeBaseConfig, question, answer string, output *bedrockagentruntime.RetrieveAndGenerateOutput) usage.Completion {
+	c := answerCompletion(kbConf, "", answerOptions{channel: usage.ChannelEvaluation}, question, answer, output, nil)
+	c.UserChatID = nil
+	c.Purpose = usage.PurposeEvaluation
+	return c
+}
+
+// suggestionsCompletion reads the tokens of the suggestions from the Converse usage, estimating them when missing
+func suggestionsCompletion(kbConf *kb.KnowlegeBaseConfig, userchatID string, options answerOptions, prompt string, resp *bedrockruntime.ConverseOutput) usage.Completion {
+	return converseCompletion(usage.Completion{
+		UserChatID:      &userchatID,
+		KnowledgeBaseID: kbConf.ID,
+		Channel:         options.completionChannel(),
+		Purpose:         usage.PurposeSuggestions,
+		ModelID:         kbConf.Model.SuggestionsModelId,
+	}, prompt, resp)
+}
+
+// judgeCompletion reads the tokens of an evaluation grade from the Converse usage, estimating them when missing
+func judgeCompletion(kbConf *kb.KnowlegeBaseConfig, prompt string, resp *bedrockruntime.ConverseOutput) usage.Completion {
+	return converseCompletion(usage.Completion{
+		KnowledgeBaseID: kbConf.ID,
+		Channel:         usage.ChannelEvaluation,
+		Purpose:         usage.PurposeJudge,
+		ModelID:         kbConf.Model.JudgeModelId,
+	}, prompt, resp)
+}
+
+func converseCompletion(c usage.Completion, prompt string, resp *bedrockruntime.ConverseOutput) usage.Completion {
+	if resp.Usage != nil && resp.Usage.InputTokens != nil && resp.Usage.OutputTokens != nil {
+		c.InputTokens = int(aws.ToInt32(resp.Usage.InputTokens))
+		c.OutputTokens = int(aws.ToInt32(resp.Usage.OutputTokens))
+		return c
+	}
+
+	var text strings.Builder
+	if message, ok := resp.Output.(*runtimetypes.ConverseOutputMemberMessage); ok {
+		for _, block := range message.Value.Content {
+			if t, ok := block.(*runtimetypes.ContentBlockMemberText); ok {
+				text.WriteString(t.Value)
+			}
+		}
+	}
+	c.InputTokens = usage.EstimateTokens(prompt)
+	c.OutputTokens = usage.EstimateTokens(text.String())
+	c.Estimated = true
+	return c
+}
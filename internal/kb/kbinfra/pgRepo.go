@@ -59,16 +59,64 @@ func (lc *PostgresStore) GetKnowlegeBaseConfig() (*kb.KnowlegeBaseConfig, error)
 		return nil, errors.ErrUnexpected("KB_S3_DATA_SOURCE is not set")
 	}
 
-	return &kb.KnowlegeBaseConfig{
-		ID:              id,
-		NumberOfResults: numberOfResultsInt,
-		Region:          region,
-		S3DataSurce:     s3DataSource,
+	suggestionsModelId := os.Getenv("KB_SUGGESTIONS_MODEL_ID")
+	if suggestionsModelId == "" {
+		suggestionsModelId = modelId
+	}
+
+	judgeModelId := os.Getenv("KB_EVAL_JUDGE_MODEL_ID")
+	if judgeModelId == "" {
+		judgeModelId = modelId
+	}
+
+	guardrailVersion := os.Getenv("KB_GUARDRAIL_VERSION")
+	if guardrailVersion == "" {
+		guardrailVersion = "DRAFT"
+	}
+
+	kbConf := &kb.KnowlegeBaseConfig{
+		ID:               id,
+		NumberOfResults:  numberOfResultsInt,
+		Region:           region,
+		S3DataSurce:      s3DataSource,
+		GuardrailID:      os.Getenv("KB_GUARDRAIL_ID"),
+		GuardrailVersion: guardrailVersion,
 		Model: kb.ModelInformation{
-			ModelId: modelId,
-			Prompt:  modelPrompt,
-		}}, nil
+			ModelId:            modelId,
+			Prompt:             modelPrompt,
+			SuggestionsModelId: suggestionsModelId,
+			JudgeModelId:       judgeModelId,
+		}}
+
+	if err := lc.applyPromotedSettings(kbConf); err != nil {
+		return nil, err
+	}
+	return kbConf, nil
+}
+
+// applyPromotedSettings overrides the environment with the configuration promoted from an experiment
+func (lc *PostgresStore) applyPromotedSettings(kbConf *kb.KnowlegeBaseConfig) error {
+	query := `SELECT key, value FROM settings WHERE key IN ($1, $2, $3)`
+
+	var settings []kb.Setting
+	err := lc.db.SelectContext(context.Background(), &settings, query, kb.SettingModelID, kb.SettingPrompt, kb.SettingNumberOfResults)
+	if err != nil {
+		return errors.ErrDatabase(fmt.Sprintf("Failed to get promoted settings: %v", err))
+	}
 
+	for _, setting := range settings {
+		switch setting.Key {
+		case kb.SettingModelID:
+			kbConf.Model.ModelId = setting.Value
+		case kb.SettingPrompt:
+			kbConf.Model.Prompt = setting.Value
+		case kb.SettingNumberOfResults:
+			if n, err := strconv.Atoi(setting.Value); err == nil && n > 0 {
+				kbConf.NumberOfResults = n
+			}
+		}
+	}
+	return nil
 }
 
 func (lc *PostgresStore) SaveData(ctx context.Context, data kb.DataFile) (*kb.DataFile, error) {
@@ -174,3 +222,55 @@ func (lc *PostgresStore) GetDataById(ctx context.Context, id int) (*kb.DataFile,
 
 	return &file, nil
 }
+
+func (lc *PostgresStore) GetDataByS3Key(ctx context.Context, s3Key string) (*kb.DataFile, error) {
+	query := `
+        SELECT id, filename, s3_key, user_id ,user_email
+        FROM files 
+        WHERE s3_key = $1`
+
+	var file kb.DataFile
+	err := lc.db.QueryRowxContext(ctx, query, s3Key).StructScan(&file)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("file not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get file: %v", err))
+	}
+
+	return &file, nil
+}
+
+func (lc *PostgresStore) GetSetting(ctx context.Context, key string) (*kb.Setting, error) {
+	query := `
+        SELECT key, value, updated_by, updated_at
+        FROM settings
+        WHERE key = $1`
+
+	var setting kb.Setting
+	err := lc.db.QueryRowxContext(ctx, query, key).StructScan(&setting)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("setting not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get setting: %v", err))
+	}
+
+	return &setting, nil
+}
+
+func (lc *PostgresStore) SaveSetting(ctx context.Context, setting kb.Setting) (*kb.Setting, error) {
+	query := `
+        INSERT INTO settings (key, value, updated_by)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by
+        RETURNING key, value, updated_by, updated_at`
+
+	var saved kb.Setting
+	err := lc.db.QueryRowxContext(ctx, query, setting.Key, setting.Value, setting.UpdatedBy).StructScan(&saved)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to save setting: %v", err))
+	}
+
+	return &saved, nil
+}
@@ -1,8 +1,14 @@
 package kb
 
+import "time"
+
 type ModelInformation struct {
 	ModelId string `json:"modelId"`
 	Prompt  string `json:"prompt"`
+	// SuggestionsModelId writes the follow-up suggestions, defaults to ModelId
+	SuggestionsModelId string `json:"suggestionsModelId"`
+	// JudgeModelId grades answers in evaluation runs, defaults to ModelId
+	JudgeModelId string `json:"judgeModelId"`
 }
 
 type KnowlegeBaseConfig struct {
@@ -11,6 +17,9 @@ type KnowlegeBaseConfig struct {
 	NumberOfResults int              `json:"numberOfResults"`
 	Region          string           `json:"region"`
 	Model           ModelInformation `json:"model"`
+	// GuardrailID is an optional Bedrock Guardrail applied to every answer
+	GuardrailID      string `json:"guardrailId"`
+	GuardrailVersion string `json:"guardrailVersion"`
 }
 
 type DataFile struct {
@@ -20,3 +29,27 @@ type DataFile struct {
 	UserID    string `db:"user_id" json:"user_id"`
 	UserEmail string `db:"user_email" json:"user_email"`
 }
+
+// Citation is a knowledge base file an answer was grounded on
+type Citation struct {
+	Filename string `json:"filename"`
+	S3Key    string `json:"s3_key"`
+	URL      string `json:"url"`
+}
+
+// Setting keys
+const (
+	SettingSuggestionsPrompt = "suggestions_prompt"
+	// The model, prompt and number of results promoted from an experiment, they override the environment
+	SettingModelID         = "kb_model_id"
+	SettingPrompt          = "kb_prompt"
+	SettingNumberOfResults = "kb_number_of_results"
+)
+
+// Setting is a value admins can change at runtime
+type Setting struct {
+	Key       string    `db:"key" json:"key"`
+	Value     string    `db:"value" json:"value"`
+	UpdatedBy *string   `db:"updated_by" json:"updated_by"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
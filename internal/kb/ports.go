@@ -12,4 +12,8 @@ type Repository interface {
 	DeleteData(ctx context.Context, dataId int) (*DataFile, error)
 	GetData(ctx context.Context, page, pageSize int) (database.PaginatedRecord[DataFile], error)
 	GetDataById(ctx context.Context, id int) (*DataFile, error)
+	GetDataByS3Key(ctx context.Context, s3Key string) (*DataFile, error)
+
+	GetSetting(ctx context.Context, key string) (*Setting, error)
+	SaveSetting(ctx context.Context, setting Setting) (*Setting, error)
 }
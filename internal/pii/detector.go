@@ -0,0 +1,221 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Detector finds one kind of personal data
+type Detector interface {
+	// Name is the entity type, used in placeholders
+	Name() string
+	// Find returns the byte offsets [start, end] of every match
+	Find(text string) [][]int
+}
+
+// RegexDetector finds personal data with a regular expression. When the expression has a
+// capture group only the group is redacted, so context like a "DNI:" prefix can anchor it.
+// An optional validator rejects matches, for example on a failed checksum.
+type RegexDetector struct {
+	name  string
+	re    *regexp.Regexp
+	valid func(match string) bool
+}
+
+func NewRegexDetector(name string, pattern string, valid func(match string) bool) (RegexDetector, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return RegexDetector{}, fmt.Errorf("invalid %s pattern: %w", name, err)
+	}
+	return RegexDetector{name: strings.ToUpper(name), re: re, valid: valid}, nil
+}
+
+func (d RegexDetector) Name() string { return d.name }
+
+func (d RegexDetector) Find(text string) [][]int {
+	var locs [][]int
+	for _, m := range d.re.FindAllStringSubmatchIndex(text, -1) {
+		loc := m[:2]
+		if len(m) >= 4 && m[2] >= 0 {
+			loc = m[2:4]
+		}
+		if d.valid != nil && !d.valid(text[loc[0]:loc[1]]) {
+			continue
+		}
+		locs = append(locs, loc)
+	}
+	return locs
+}
+
+// Built-in detector names
+const (
+	DetectorEmail = "EMAIL"
+	DetectorCard  = "CARD"
+	DetectorPeRUC = "PE_RUC"
+	DetectorPeDNI = "PE_DNI"
+	DetectorClRUT = "CL_RUT"
+	DetectorEsDNI = "ES_DNI"
+	DetectorPhone = "PHONE"
+)
+
+// builtins are listed by priority, specific identifiers before the broad phone pattern
+var builtins = []RegexDetector{
+	mustRegexDetector(DetectorEmail, `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`, nil),
+	mustRegexDetector(DetectorCard, `\b\d(?:[ -]?\d){12,18}\b`, validLuhn),
+	mustRegexDetector(DetectorPeRUC, `\b(?:10|15|16|17|20)\d{9}\b`, validRUC),
+	mustRegexDetector(DetectorPeDNI, `(?i)\bdni\b[\s:#.nº°-]*(\d{8})\b`, nil),
+	mustRegexDetector(DetectorClRUT, `\b\d{1,2}\.?\d{3}\.?\d{3}-[\dkK]\b`, validRUT),
+	mustRegexDetector(DetectorEsDNI, `\b[XYZxyz]?-?\d{7,8}-?[A-Za-z]\b`, validSpanishID),
+	mustRegexDetector(DetectorPhone, `(?:\+\d{1,3}[\s.-]?)?(?:\(\d{1,4}\)[\s.-]?)?\d{1,4}(?:[\s.-]?\d{2,4}){1,4}`, validPhone),
+}
+
+func mustRegexDetector(name, pattern string, valid func(string) bool) RegexDetector {
+	d, err := NewRegexDetector(name, pattern, valid)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// NewDetectors returns the custom detectors followed by the enabled built-ins, all of them when
+// names is empty. Custom detectors are NAME=regex entries.
+func NewDetectors(names []string, custom []string) ([]Detector, error) {
+	var detectors []Detector
+	for _, c := range custom {
+		if c = strings.TrimSpace(c); c == "" {
+			continue
+		}
+		name, pattern, ok := strings.Cut(c, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("custom PII detector %q must be NAME=regex", c)
+		}
+		d, err := NewRegexDetector(strings.TrimSpace(name), pattern, nil)
+		if err != nil {
+			return nil, err
+		}
+		detectors = append(detectors, d)
+	}
+
+	enabled := make(map[string]bool)
+	for _, n := range names {
+		if n = strings.ToUpper(strings.TrimSpace(n)); n != "" {
+			enabled[n] = true
+		}
+	}
+	for _, d := range builtins {
+		if len(enabled) == 0 || enabled[d.Name()] {
+			delete(enabled, d.Name())
+			detectors = append(detectors, d)
+		}
+	}
+	for n := range enabled {
+		return nil, fmt.Errorf("unknown PII detector %q", n)
+	}
+	return detectors, nil
+}
+
+func digits(s string) []int {
+	var ds []int
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			ds = append(ds, int(r-'0'))
+		}
+	}
+	return ds
+}
+
+// validLuhn checks payment card numbers
+func validLuhn(s string) bool {
+	ds := digits(s)
+	if len(ds) < 13 || len(ds) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range ds {
+		d := ds[len(ds)-1-i]
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validRUC checks the modulo 11 digit of Peruvian tax IDs
+func validRUC(s string) bool {
+	ds := digits(s)
+	if len(ds) != 11 {
+		return false
+	}
+	weights := []int{5, 4, 3, 2, 7, 6, 5, 4, 3, 2}
+	sum := 0
+	for i, w := range weights {
+		sum += ds[i] * w
+	}
+	check := 11 - sum%11
+	if check >= 10 {
+		check -= 10
+	}
+	return check == ds[10]
+}
+
+// validRUT checks the modulo 11 verifier of Chilean national IDs
+func validRUT(s string) bool {
+	body, verifier, ok := strings.Cut(strings.ReplaceAll(s, ".", ""), "-")
+	if !ok {
+		return false
+	}
+	sum, factor := 0, 2
+	for i := len(body) - 1; i >= 0; i-- {
+		sum += int(body[i]-'0') * factor
+		if factor++; factor > 7 {
+			factor = 2
+		}
+	}
+	var expected string
+	switch rest := 11 - sum%11; rest {
+	case 11:
+		expected = "0"
+	case 10:
+		expected = "K"
+	default:
+		expected = string(rune('0' + rest))
+	}
+	return strings.ToUpper(verifier) == expected
+}
+
+// validSpanishID checks the control letter of Spanish DNI and NIE numbers
+func validSpanishID(s string) bool {
+	s = strings.ToUpper(strings.ReplaceAll(s, "-", ""))
+	switch s[0] {
+	case 'X':
+		s = "0" + s[1:]
+	case 'Y':
+		s = "1" + s[1:]
+	case 'Z':
+		s = "2" + s[1:]
+	}
+	if len(s) != 9 {
+		return false
+	}
+	n := 0
+	for _, d := range digits(s[:8]) {
+		n = n*10 + d
+	}
+	return s[8] == "TRWAGMYFPDXBNJZSQVHLCKE"[n%23]
+}
+
+// validPhone keeps numbers long enough to be phones, dates and amounts are shorter
+// unless written with an international prefix
+func validPhone(s string) bool {
+	n := len(digits(s))
+	if strings.HasPrefix(s, "+") {
+		return n >= 8 && n <= 15
+	}
+	return n >= 9 && n <= 15
+}
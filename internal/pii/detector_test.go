@@ -0,0 +1,96 @@
+package pii
+
+import "testing"
+
+func TestValidLuhn(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"4111 1111 1111 1111", true},
+		{"4242-4242-4242-4242", true},
+		{"5555555555554444", true},
+		{"4111111111111112", false},
+		{"0000000000", false},
+		{"41111111111111111111", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			if got := validLuhn(tt.number); got != tt.want {
+				t.Errorf("validLuhn(%q) = %v, want %v", tt.number, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidRUT(t *testing.T) {
+	tests := []struct {
+		rut  string
+		want bool
+	}{
+		{"12.345.678-5", true},
+		{"12345678-5", true},
+		{"11.111.111-1", true},
+		{"10.000.013-k", true},
+		{"10.000.013-K", true},
+		{"12.345.678-4", false},
+		{"12345678", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rut, func(t *testing.T) {
+			if got := validRUT(tt.rut); got != tt.want {
+				t.Errorf("validRUT(%q) = %v, want %v", tt.rut, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidSpanishID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"12345678Z", true},
+		{"12345678-z", true},
+		{"X1234567L", true},
+		{"Y-1234567-X", true},
+		{"12345678A", false},
+		{"1234567Z", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := validSpanishID(tt.id); got != tt.want {
+				t.Errorf("validSpanishID(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeruvianDNIDetector(t *testing.T) {
+	detectors, err := NewDetectors([]string{DetectorPeDNI}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"mi DNI: 12345678", "12345678"},
+		{"dni nº 87654321 por favor", "87654321"},
+		{"DNI#12345678", "12345678"},
+		{"12345678 sin la palabra", ""},
+		{"DNI: 1234567", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := ""
+			if locs := detectors[0].Find(tt.text); len(locs) > 0 {
+				got = tt.text[locs[0][0]:locs[0][1]]
+			}
+			if got != tt.want {
+				t.Errorf("Find(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
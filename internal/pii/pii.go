@@ -0,0 +1,121 @@
+package pii
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entity is a piece of personal data found in a text
+type Entity struct {
+	Type        string `json:"type"`
+	Value       string `json:"value"`
+	Placeholder string `json:"placeholder"`
+}
+
+// Redaction is a text with its personal data replaced by placeholders like [EMAIL_1]
+type Redaction struct {
+	Text     string
+	Entities []Entity
+}
+
+// Types returns the distinct entity types found, in order of appearance
+func (r Redaction) Types() []string {
+	var types []string
+	seen := make(map[string]bool)
+	for _, e := range r.Entities {
+		if !seen[e.Type] {
+			seen[e.Type] = true
+			types = append(types, e.Type)
+		}
+	}
+	return types
+}
+
+// Restore puts the original values back in place of the placeholders
+func (r Redaction) Restore() string {
+	pairs := make([]string, 0, 2*len(r.Entities))
+	for _, e := range r.Entities {
+		pairs = append(pairs, e.Placeholder, e.Value)
+	}
+	return strings.NewReplacer(pairs...).Replace(r.Text)
+}
+
+// Redact replaces the personal data the detectors find. When matches overlap the detector
+// listed first wins, and repeated values share a placeholder.
+func Redact(text string, detectors []Detector) Redaction {
+	type span struct {
+		start, end int
+		kind       string
+	}
+
+	var spans []span
+	for _, d := range detectors {
+		for _, loc := range d.Find(text) {
+			overlaps := false
+			for _, s := range spans {
+				if loc[0] < s.end && s.start < loc[1] {
+					overlaps = true
+					break
+				}
+			}
+			if !overlaps {
+				spans = append(spans, span{start: loc[0], end: loc[1], kind: d.Name()})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return Redaction{Text: text}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	var entities []Entity
+	placeholders := make(map[string]string)
+	counts := make(map[string]int)
+	last := 0
+	for _, s := range spans {
+		value := text[s.start:s.end]
+		key := s.kind + "\x00" + value
+		placeholder, ok := placeholders[key]
+		if !ok {
+			counts[s.kind]++
+			placeholder = fmt.Sprintf("[%s_%d]", s.kind, counts[s.kind])
+			placeholders[key] = placeholder
+			entities = append(entities, Entity{Type: s.kind, Value: value, Placeholder: placeholder})
+		}
+		b.WriteString(text[last:s.start])
+		b.WriteString(placeholder)
+		last = s.end
+	}
+	b.WriteString(text[last:])
+
+	return Redaction{Text: b.String(), Entities: entities}
+}
+
+// VaultEntry keeps the original values of a redacted message, encrypted, so admins can recover them
+type VaultEntry struct {
+	ID           int       `json:"id" db:"id"`
+	ChatUserID   *string   `json:"chat_user_id" db:"user_chat_id"`
+	RedactedText string    `json:"redacted_text" db:"redacted_text"`
+	EntityTypes  []string  `json:"entity_types" db:"entity_types"`
+	Secret       []byte    `json:"-" db:"secret"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// Reveal is an audit record of an admin recovering a vault entry
+type Reveal struct {
+	ID         int       `json:"id" db:"id"`
+	VaultID    int       `json:"vault_id" db:"vault_id"`
+	RevealedBy string    `json:"revealed_by" db:"revealed_by"`
+	Reason     string    `json:"reason" db:"reason"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Revealed is a vault entry with its original text
+type Revealed struct {
+	VaultEntry
+	Text     string   `json:"text"`
+	Entities []Entity `json:"entities"`
+}
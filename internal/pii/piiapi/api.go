@@ -0,0 +1,61 @@
+package piiapi
+
+import (
+	"strconv"
+
+	"github.com/Abraxas-365/opd/internal/pii/piisrv"
+	"github.com/Abraxas-365/opd/internal/user"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/Abraxas-365/toolkit/pkg/lucia"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the admin routes of the PII vault
+func SetupRoutes(app *fiber.App, service *piisrv.Service, authMiddleware *lucia.AuthMiddleware[*user.User]) {
+	app.Get("/pii/vault", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		page, err := strconv.Atoi(c.Query("page", "1"))
+		if err != nil || page < 1 {
+			return errors.ErrBadRequest("Invalid page number")
+		}
+
+		pageSize, err := strconv.Atoi(c.Query("pageSize", "10"))
+		if err != nil || pageSize < 1 {
+			return errors.ErrBadRequest("Invalid page size")
+		}
+
+		entries, err := service.GetVaultEntries(c.Context(), page, pageSize)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(entries)
+	})
+
+	app.Post("/pii/vault/:id/reveal", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		type Request struct {
+			Reason string `json:"reason"`
+		}
+
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return errors.ErrBadRequest("Vault entry id must be a number")
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		userID, err := lucia.GetSession(c).UserIDToString()
+		if err != nil {
+			return err
+		}
+
+		revealed, err := service.Reveal(c.Context(), id, userID, req.Reason)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(revealed)
+	})
+}
@@ -0,0 +1,126 @@
+package piiinfra
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Abraxas-365/opd/internal/pii"
+	"github.com/Abraxas-365/toolkit/pkg/database"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const vaultColumns = `id, user_chat_id, redacted_text, entity_types, secret, created_at`
+
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPIIStore creates a new PostgresStore for pii repository
+func NewPIIStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVaultEntry(row rowScanner) (*pii.VaultEntry, error) {
+	var e pii.VaultEntry
+	err := row.Scan(
+		&e.ID,
+		&e.ChatUserID,
+		&e.RedactedText,
+		pq.Array(&e.EntityTypes),
+		&e.Secret,
+		&e.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// CreateVaultEntry stores the encrypted originals of a redacted message
+func (s *PostgresStore) CreateVaultEntry(ctx context.Context, e pii.VaultEntry) (*pii.VaultEntry, error) {
+	query := `
+		INSERT INTO pii_vault (user_chat_id, redacted_text, entity_types, secret)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + vaultColumns
+
+	created, err := scanVaultEntry(s.db.QueryRowContext(ctx, query, e.ChatUserID, e.RedactedText, pq.Array(e.EntityTypes), e.Secret))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return nil, errors.ErrNotFound("Referenced chat user not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create vault entry: %v", err))
+	}
+	return created, nil
+}
+
+// GetVaultEntry retrieves a vault entry by ID
+func (s *PostgresStore) GetVaultEntry(ctx context.Context, id int) (*pii.VaultEntry, error) {
+	query := `SELECT ` + vaultColumns + ` FROM pii_vault WHERE id = $1`
+
+	e, err := scanVaultEntry(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound("Vault entry not found")
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get vault entry: %v", err))
+	}
+	return e, nil
+}
+
+// GetVaultEntries retrieves a paginated list of vault entries, newest first
+func (s *PostgresStore) GetVaultEntries(ctx context.Context, page, pageSize int) (database.PaginatedRecord[pii.VaultEntry], error) {
+	offset := (page - 1) * pageSize
+
+	query := `SELECT ` + vaultColumns + ` FROM pii_vault ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+
+	rows, err := s.db.QueryContext(ctx, query, pageSize, offset)
+	if err != nil {
+		return database.PaginatedRecord[pii.VaultEntry]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get vault entries: %v", err))
+	}
+	defer rows.Close()
+
+	entries := []pii.VaultEntry{}
+	for rows.Next() {
+		e, err := scanVaultEntry(rows)
+		if err != nil {
+			return database.PaginatedRecord[pii.VaultEntry]{}, errors.ErrDatabase(fmt.Sprintf("Failed to scan vault entry: %v", err))
+		}
+		entries = append(entries, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return database.PaginatedRecord[pii.VaultEntry]{}, errors.ErrDatabase(fmt.Sprintf("Error iterating vault entries: %v", err))
+	}
+
+	var total int
+	if err := s.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM pii_vault`); err != nil {
+		return database.PaginatedRecord[pii.VaultEntry]{}, errors.ErrDatabase(fmt.Sprintf("Failed to get total count: %v", err))
+	}
+
+	return database.PaginatedRecord[pii.VaultEntry]{
+		Data:       entries,
+		PageNumber: page,
+		PageSize:   pageSize,
+		Total:      total,
+	}, nil
+}
+
+// CreateReveal records that an admin recovered a vault entry
+func (s *PostgresStore) CreateReveal(ctx context.Context, r pii.Reveal) (*pii.Reveal, error) {
+	query := `
+		INSERT INTO pii_vault_reveals (vault_id, revealed_by, reason)
+		VALUES ($1, $2, $3)
+		RETURNING id, vault_id, revealed_by, reason, created_at`
+
+	var created pii.Reveal
+	if err := s.db.QueryRowxContext(ctx, query, r.VaultID, r.RevealedBy, r.Reason).StructScan(&created); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to record vault reveal: %v", err))
+	}
+	return &created, nil
+}
@@ -0,0 +1,130 @@
+package piisrv
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Abraxas-365/opd/internal/pii"
+	"github.com/Abraxas-365/opd/internal/user/usersrv"
+	"github.com/Abraxas-365/toolkit/pkg/database"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+type Service struct {
+	repo        pii.Repository
+	userService *usersrv.Service
+	detectors   []pii.Detector
+	// vault encrypts the originals of redacted messages, nil when no vault key is configured
+	vault cipher.AEAD
+}
+
+// New creates the redaction stage. vaultKey is a 32 byte AES key, without one originals are discarded.
+func New(repo pii.Repository, userService *usersrv.Service, detectors []pii.Detector, vaultKey []byte) (*Service, error) {
+	s := &Service{
+		repo:        repo,
+		userService: userService,
+		detectors:   detectors,
+	}
+	if len(vaultKey) == 0 {
+		return s, nil
+	}
+
+	block, err := aes.NewCipher(vaultKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PII vault key: %w", err)
+	}
+	if s.vault, err = cipher.NewGCM(block); err != nil {
+		return nil, fmt.Errorf("invalid PII vault key: %w", err)
+	}
+	return s, nil
+}
+
+// Redact replaces the personal data in text, used where the originals are never needed
+func (s *Service) Redact(text string) string {
+	return pii.Redact(text, s.detectors).Text
+}
+
+// Protect redacts a chat user's message and keeps the originals in the vault. It returns the
+// redacted text and the vault entry ID, nil when nothing was found or the vault is disabled.
+func (s *Service) Protect(ctx context.Context, chatUserID string, text string) (string, *int, error) {
+	redaction := pii.Redact(text, s.detectors)
+	if len(redaction.Entities) == 0 || s.vault == nil {
+		return redaction.Text, nil, nil
+	}
+
+	plaintext, err := json.Marshal(redaction.Entities)
+	if err != nil {
+		return "", nil, errors.ErrUnexpected("failed to encode redacted values: " + err.Error())
+	}
+	nonce := make([]byte, s.vault.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, errors.ErrUnexpected("failed to generate nonce: " + err.Error())
+	}
+
+	entry, err := s.repo.CreateVaultEntry(ctx, pii.VaultEntry{
+		ChatUserID:   &chatUserID,
+		RedactedText: redaction.Text,
+		EntityTypes:  redaction.Types(),
+		Secret:       s.vault.Seal(nonce, nonce, plaintext, nil),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return redaction.Text, &entry.ID, nil
+}
+
+func (s *Service) GetVaultEntries(ctx context.Context, page, pageSize int) (database.PaginatedRecord[pii.VaultEntry], error) {
+	return s.repo.GetVaultEntries(ctx, page, pageSize)
+}
+
+// Reveal decrypts a vault entry for an admin. Every reveal needs a reason and is recorded.
+func (s *Service) Reveal(ctx context.Context, id int, userID string, reason string) (*pii.Revealed, error) {
+	if s.vault == nil {
+		return nil, errors.ErrServiceUnavailable("PII vault is not configured")
+	}
+	if reason = strings.TrimSpace(reason); reason == "" {
+		return nil, errors.ErrBadRequest("reason is required")
+	}
+
+	u, err := s.userService.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAdmin {
+		return nil, errors.ErrForbidden("only admins can reveal redacted data")
+	}
+
+	entry, err := s.repo.GetVaultEntry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := s.vault.NonceSize()
+	if len(entry.Secret) < nonceSize {
+		return nil, errors.ErrUnexpected("vault entry is corrupted")
+	}
+	plaintext, err := s.vault.Open(nil, entry.Secret[:nonceSize], entry.Secret[nonceSize:], nil)
+	if err != nil {
+		return nil, errors.ErrUnexpected("failed to decrypt vault entry, the vault key may have changed")
+	}
+
+	var entities []pii.Entity
+	if err := json.Unmarshal(plaintext, &entities); err != nil {
+		return nil, errors.ErrUnexpected("failed to decode vault entry: " + err.Error())
+	}
+
+	if _, err := s.repo.CreateReveal(ctx, pii.Reveal{VaultID: id, RevealedBy: userID, Reason: reason}); err != nil {
+		return nil, err
+	}
+
+	return &pii.Revealed{
+		VaultEntry: *entry,
+		Text:       pii.Redaction{Text: entry.RedactedText, Entities: entities}.Restore(),
+		Entities:   entities,
+	}, nil
+}
@@ -0,0 +1,14 @@
+package pii
+
+import (
+	"context"
+
+	"github.com/Abraxas-365/toolkit/pkg/database"
+)
+
+type Repository interface {
+	CreateVaultEntry(ctx context.Context, e VaultEntry) (*VaultEntry, error)
+	GetVaultEntry(ctx context.Context, id int) (*VaultEntry, error)
+	GetVaultEntries(ctx context.Context, page, pageSize int) (database.PaginatedRecord[VaultEntry], error)
+	CreateReveal(ctx context.Context, r Reveal) (*Reveal, error)
+}
@@ -0,0 +1,9 @@
+package slack
+
+import "context"
+
+// Client posts messages through the Slack Web API
+type Client interface {
+	// PostMessage posts text to a channel, inside the thread threadTS when it is not empty
+	PostMessage(ctx context.Context, channel, threadTS, text string) error
+}
@@ -0,0 +1,45 @@
+package slack
+
+// EventEnvelope is the body Slack posts to the events endpoint
+type EventEnvelope struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge,omitempty"`
+	TeamID    string `json:"team_id"`
+	EventID   string `json:"event_id"`
+	Event     Event  `json:"event"`
+}
+
+type Event struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype,omitempty"`
+	User        string `json:"user"`
+	BotID       string `json:"bot_id,omitempty"`
+	Text        string `json:"text"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type,omitempty"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts,omitempty"`
+}
+
+// IsQuestion reports whether the event is a user message the bot should answer:
+// a mention of the app or a direct message
+func (e Event) IsQuestion() bool {
+	if e.BotID != "" || e.Subtype != "" || e.User == "" {
+		return false
+	}
+	switch e.Type {
+	case "app_mention":
+		return true
+	case "message":
+		return e.ChannelType == "im"
+	}
+	return false
+}
+
+// ReplyThread is the thread the answer should be posted in
+func (e Event) ReplyThread() string {
+	if e.ThreadTS != "" {
+		return e.ThreadTS
+	}
+	return e.TS
+}
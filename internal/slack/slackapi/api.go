@@ -0,0 +1,41 @@
+package slackapi
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Abraxas-365/opd/internal/slack"
+	"github.com/Abraxas-365/opd/internal/slack/slacksrv"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the Slack events endpoint
+func SetupRoutes(app *fiber.App, service *slacksrv.Service) {
+	app.Post("/webhooks/slack/events", func(c *fiber.Ctx) error {
+		body := c.Body()
+		if err := service.VerifySignature(body, c.Get("X-Slack-Request-Timestamp"), c.Get("X-Slack-Signature")); err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid signature"})
+		}
+
+		var envelope slack.EventEnvelope
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		// Slack sends this once when the events URL is configured
+		if envelope.Type == "url_verification" {
+			return c.JSON(fiber.Map{"challenge": envelope.Challenge})
+		}
+
+		// Retries are sent when we take longer than 3 seconds, the first delivery is already being answered
+		if c.Get("X-Slack-Retry-Num") != "" {
+			return c.SendStatus(fiber.StatusOK)
+		}
+
+		if envelope.Type == "event_callback" {
+			go service.HandleEvent(context.Background(), envelope)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	})
+}
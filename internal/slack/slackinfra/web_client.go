@@ -0,0 +1,80 @@
+package slackinfra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// WebClient posts messages through the Slack Web API
+type WebClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewWebClient creates a WebClient, baseURL can point to a local fake server in tests
+func NewWebClient(baseURL, token string) *WebClient {
+	return &WebClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// PostMessage calls chat.postMessage
+func (c *WebClient) PostMessage(ctx context.Context, channel, threadTS, text string) error {
+	type request struct {
+		Channel     string `json:"channel"`
+		ThreadTS    string `json:"thread_ts,omitempty"`
+		Text        string `json:"text"`
+		UnfurlLinks bool   `json:"unfurl_links"`
+	}
+	type response struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+
+	payload, err := json.Marshal(request{
+		Channel:  channel,
+		ThreadTS: threadTS,
+		Text:     text,
+	})
+	if err != nil {
+		return errors.ErrUnexpected("failed to encode Slack message: " + err.Error())
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat.postMessage", bytes.NewReader(payload))
+	if err != nil {
+		return errors.ErrUnexpected("failed to create Slack request: " + err.Error())
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return errors.ErrServiceUnavailable("failed to post Slack message: " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return errors.ErrServiceUnavailable(fmt.Sprintf("Slack API returned %d", resp.StatusCode))
+	}
+
+	// Slack reports most failures with a 200 and ok=false
+	var res response
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return errors.ErrServiceUnavailable("failed to decode Slack response: " + err.Error())
+	}
+	if !res.OK {
+		return errors.ErrServiceUnavailable("Slack API error: " + res.Error)
+	}
+
+	return nil
+}
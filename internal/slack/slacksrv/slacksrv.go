@@ -0,0 +1,142 @@
+package slacksrv
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/chatuser"
+	"github.com/Abraxas-365/opd/internal/chatuser/chatusersrv"
+	"github.com/Abraxas-365/opd/internal/kb"
+	kbsrv "github.com/Abraxas-365/opd/internal/kb/kbasesrv"
+	"github.com/Abraxas-365/opd/internal/slack"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// maxRequestAge rejects replayed requests, as recommended by Slack
+const maxRequestAge = 5 * time.Minute
+
+const fallbackAnswer = "Sorry, I could not answer your question right now. Please try again later."
+
+var mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+>`)
+
+type Service struct {
+	client          slack.Client
+	kbService       *kbsrv.Service
+	chatUserService *chatusersrv.Service
+	signingSecret   string
+}
+
+func New(client slack.Client,
+	kbService *kbsrv.Service,
+	chatUserService *chatusersrv.Service,
+	signingSecret string,
+) *Service {
+	return &Service{
+		client:          client,
+		kbService:       kbService,
+		chatUserService: chatUserService,
+		signingSecret:   signingSecret,
+	}
+}
+
+// VerifySignature checks the X-Slack-Signature header against the timestamp and raw request body
+func (s *Service) VerifySignature(body []byte, timestamp, signature string) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.ErrUnauthorized("invalid timestamp")
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return errors.ErrUnauthorized("request is too old")
+	}
+
+	received, err := hex.DecodeString(strings.TrimPrefix(signature, "v0="))
+	if err != nil {
+		return errors.ErrUnauthorized("invalid signature")
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.signingSecret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		return errors.ErrUnauthorized("invalid signature")
+	}
+	return nil
+}
+
+// HandleEvent answers app mentions and direct messages in their thread
+func (s *Service) HandleEvent(ctx context.Context, envelope slack.EventEnvelope) {
+	event := envelope.Event
+	if !event.IsQuestion() {
+		return
+	}
+
+	if err := s.answer(ctx, envelope.TeamID, event); err != nil {
+		log.Printf("slack: failed to answer event %s: %v", envelope.EventID, err)
+	}
+}
+
+// Deliver sends an agent reply as a direct message from the app, implementing handoff.Deliverer.
+// externalID is the team:user identity the Slack user was mapped with.
+func (s *Service) Deliver(ctx context.Context, externalID string, text string) error {
+	parts := strings.SplitN(externalID, ":", 2)
+	if len(parts) != 2 {
+		return errors.ErrBadRequest("invalid Slack identity " + externalID)
+	}
+	return s.client.PostMessage(ctx, parts[1], "", escape(text))
+}
+
+func (s *Service) answer(ctx context.Context, teamID string, event slack.Event) error {
+	question := strings.TrimSpace(mentionPattern.ReplaceAllString(event.Text, ""))
+	if question == "" {
+		return nil
+	}
+
+	u, err := s.chatUserService.GetOrCreateChannelChatUser(ctx, chatuser.ChannelSlack, teamID+":"+event.User)
+	if err != nil {
+		return err
+	}
+
+	text := fallbackAnswer
+	output, err := s.kbService.CompleteAnswerWithMetadata(ctx, question, nil, *u.ID, kbsrv.FromChannel(chatuser.ChannelSlack))
+	if err != nil {
+		log.Printf("slack: completion failed for chat user %s: %v", *u.ID, err)
+	} else {
+		citations, err := s.kbService.GetCitations(ctx, output)
+		if err != nil {
+			log.Printf("slack: failed to resolve citations: %v", err)
+		}
+		if output.Output != nil && output.Output.Text != nil {
+			text = formatAnswer(*output.Output.Text, citations)
+		}
+	}
+
+	return s.client.PostMessage(ctx, event.Channel, event.ReplyThread(), text)
+}
+
+// formatAnswer renders the answer in Slack mrkdwn with the cited files as links
+func formatAnswer(answer string, citations []kb.Citation) string {
+	var b strings.Builder
+	b.WriteString(escape(answer))
+	if len(citations) == 0 {
+		return b.String()
+	}
+
+	b.WriteString("\n\n*Sources*")
+	for _, c := range citations {
+		fmt.Fprintf(&b, "\n• <%s|%s>", c.URL, escape(c.Filename))
+	}
+	return b.String()
+}
+
+// escape encodes the characters Slack reserves for mrkdwn control sequences
+func escape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
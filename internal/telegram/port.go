@@ -0,0 +1,15 @@
+package telegram
+
+import "context"
+
+// Client talks to the Telegram Bot API
+type Client interface {
+	// SendMessage sends text to a chat as a reply to replyTo, with one inline button per row
+	SendMessage(ctx context.Context, chatID int64, text string, replyTo int64, buttons []InlineButton) error
+
+	// GetUpdates long-polls for updates starting at offset, waiting up to timeoutSeconds
+	GetUpdates(ctx context.Context, offset int64, timeoutSeconds int) ([]Update, error)
+
+	// DeleteWebhook removes the webhook so getUpdates can be used
+	DeleteWebhook(ctx context.Context) error
+}
@@ -0,0 +1,28 @@
+package telegramapi
+
+import (
+	"context"
+
+	"github.com/Abraxas-365/opd/internal/telegram"
+	"github.com/Abraxas-365/opd/internal/telegram/telegramsrv"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the Telegram webhook, register it with setWebhook and the same secret_token
+func SetupRoutes(app *fiber.App, service *telegramsrv.Service) {
+	app.Post("/webhooks/telegram", func(c *fiber.Ctx) error {
+		if err := service.VerifySecretToken(c.Get("X-Telegram-Bot-Api-Secret-Token")); err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid secret token"})
+		}
+
+		var update telegram.Update
+		if err := c.BodyParser(&update); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		// Telegram redelivers updates that are not acknowledged, so answer in the background
+		go service.HandleUpdate(context.Background(), update)
+
+		return c.SendStatus(fiber.StatusOK)
+	})
+}
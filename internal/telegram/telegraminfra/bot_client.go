@@ -0,0 +1,133 @@
+package telegraminfra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/telegram"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// BotClient calls the Telegram Bot API
+type BotClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewBotClient creates a BotClient, baseURL can point to a local fake server in tests
+func NewBotClient(baseURL, token string) *BotClient {
+	return &BotClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		// Long enough for getUpdates long polling
+		httpClient: &http.Client{Timeout: 90 * time.Second},
+	}
+}
+
+// SendMessage calls sendMessage
+func (c *BotClient) SendMessage(ctx context.Context, chatID int64, text string, replyTo int64, buttons []telegram.InlineButton) error {
+	type replyParameters struct {
+		MessageID                int64 `json:"message_id"`
+		AllowSendingWithoutReply bool  `json:"allow_sending_without_reply"`
+	}
+	type replyMarkup struct {
+		InlineKeyboard [][]telegram.InlineButton `json:"inline_keyboard"`
+	}
+	type request struct {
+		ChatID          int64            `json:"chat_id"`
+		Text            string           `json:"text"`
+		ReplyParameters *replyParameters `json:"reply_parameters,omitempty"`
+		ReplyMarkup     *replyMarkup     `json:"reply_markup,omitempty"`
+	}
+
+	req := request{
+		ChatID: chatID,
+		Text:   text,
+	}
+	if replyTo != 0 {
+		req.ReplyParameters = &replyParameters{MessageID: replyTo, AllowSendingWithoutReply: true}
+	}
+	if len(buttons) > 0 {
+		markup := &replyMarkup{}
+		for _, b := range buttons {
+			markup.InlineKeyboard = append(markup.InlineKeyboard, []telegram.InlineButton{b})
+		}
+		req.ReplyMarkup = markup
+	}
+
+	return c.call(ctx, "sendMessage", req, nil)
+}
+
+// GetUpdates calls getUpdates
+func (c *BotClient) GetUpdates(ctx context.Context, offset int64, timeoutSeconds int) ([]telegram.Update, error) {
+	type request struct {
+		Offset         int64    `json:"offset"`
+		Timeout        int      `json:"timeout"`
+		AllowedUpdates []string `json:"allowed_updates"`
+	}
+
+	var updates []telegram.Update
+	err := c.call(ctx, "getUpdates", request{
+		Offset:         offset,
+		Timeout:        timeoutSeconds,
+		AllowedUpdates: []string{"message"},
+	}, &updates)
+	if err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// DeleteWebhook calls deleteWebhook
+func (c *BotClient) DeleteWebhook(ctx context.Context) error {
+	return c.call(ctx, "deleteWebhook", struct{}{}, nil)
+}
+
+// call posts params to a Bot API method and decodes its result into result, when not nil
+func (c *BotClient) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	type response struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+
+	payload, err := json.Marshal(params)
+	if err != nil {
+		return errors.ErrUnexpected("failed to encode Telegram request: " + err.Error())
+	}
+
+	url := fmt.Sprintf("%s/bot%s/%s", c.baseURL, c.token, method)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return errors.ErrUnexpected("failed to create Telegram request: " + err.Error())
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		// Do not leak the bot token embedded in the URL
+		return errors.ErrServiceUnavailable(fmt.Sprintf("failed to call Telegram %s", method))
+	}
+	defer resp.Body.Close()
+
+	var res response
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return errors.ErrServiceUnavailable(fmt.Sprintf("failed to decode Telegram %s response: %v", method, err))
+	}
+	if !res.OK {
+		return errors.ErrServiceUnavailable(fmt.Sprintf("Telegram %s failed: %s", method, res.Description))
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(res.Result, result); err != nil {
+			return errors.ErrServiceUnavailable(fmt.Sprintf("failed to decode Telegram %s result: %v", method, err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package telegramsrv
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/chatuser"
+	"github.com/Abraxas-365/opd/internal/chatuser/chatusersrv"
+	kbsrv "github.com/Abraxas-365/opd/internal/kb/kbasesrv"
+	"github.com/Abraxas-365/opd/internal/telegram"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+const (
+	// maxMessageLength is the longest text sendMessage accepts
+	maxMessageLength = 4096
+	// maxButtonTextLength keeps citation buttons readable on phones
+	maxButtonTextLength = 60
+	// pollTimeout is how long a getUpdates call waits for new messages
+	pollTimeout = 50
+)
+
+const (
+	fallbackAnswer  = "Sorry, I could not answer your question right now. Please try again later."
+	welcomeMessage  = "Hi! Ask me anything about our knowledge base. Send /new to start a new conversation or /human to talk to a person."
+	newConversation = "Started a new conversation."
+)
+
+type Service struct {
+	client          telegram.Client
+	kbService       *kbsrv.Service
+	chatUserService *chatusersrv.Service
+	secretToken     string
+}
+
+func New(client telegram.Client,
+	kbService *kbsrv.Service,
+	chatUserService *chatusersrv.Service,
+	secretToken string,
+) *Service {
+	return &Service{
+		client:          client,
+		kbService:       kbService,
+		chatUserService: chatUserService,
+		secretToken:     secretToken,
+	}
+}
+
+// VerifySecretToken checks the X-Telegram-Bot-Api-Secret-Token header set when the webhook was registered
+func (s *Service) VerifySecretToken(token string) error {
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.secretToken)) != 1 {
+		return errors.ErrUnauthorized("invalid secret token")
+	}
+	return nil
+}
+
+// HandleUpdate answers a text message, each Telegram chat keeps its own conversation
+func (s *Service) HandleUpdate(ctx context.Context, update telegram.Update) {
+	m := update.Message
+	if m == nil || strings.TrimSpace(m.Text) == "" || (m.From != nil && m.From.IsBot) {
+		return
+	}
+
+	if err := s.answer(ctx, *m); err != nil {
+		log.Printf("telegram: failed to answer update %d: %v", update.UpdateID, err)
+	}
+}
+
+// Poll reads updates with getUpdates until ctx is done, for running without a public webhook
+func (s *Service) Poll(ctx context.Context) error {
+	if err := s.client.DeleteWebhook(ctx); err != nil {
+		return err
+	}
+
+	var offset int64
+	for {
+		updates, err := s.client.GetUpdates(ctx, offset, pollTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("telegram: getUpdates failed: %v", err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			s.HandleUpdate(ctx, update)
+			offset = update.UpdateID + 1
+		}
+	}
+}
+
+// Deliver sends an agent reply to a Telegram chat, implementing handoff.Deliverer
+func (s *Service) Deliver(ctx context.Context, chatID string, text string) error {
+	id, err := strconv.ParseInt(chatID, 10, 64)
+	if err != nil {
+		return errors.ErrBadRequest("invalid Telegram chat id " + chatID)
+	}
+	return s.client.SendMessage(ctx, id, truncate(text, maxMessageLength), 0, nil)
+}
+
+func (s *Service) answer(ctx context.Context, m telegram.Message) error {
+	u, err := s.chatUserService.GetOrCreateChannelChatUser(ctx, chatuser.ChannelTelegram, strconv.FormatInt(m.Chat.ID, 10))
+	if err != nil {
+		return err
+	}
+
+	switch command(m.Text) {
+	case "/start":
+		return s.client.SendMessage(ctx, m.Chat.ID, welcomeMessage, 0, nil)
+	case "/new":
+		if err := s.kbService.StartNewConversation(ctx, *u.ID); err != nil {
+			return err
+		}
+		return s.client.SendMessage(ctx, m.Chat.ID, newConversation, m.MessageID, nil)
+	}
+
+	output, err := s.kbService.CompleteAnswerWithMetadata(ctx, m.Text, nil, *u.ID, kbsrv.FromChannel(chatuser.ChannelTelegram))
+	if err != nil || output.Output == nil || output.Output.Text == nil {
+		log.Printf("telegram: completion failed for chat user %s: %v", *u.ID, err)
+		return s.client.SendMessage(ctx, m.Chat.ID, fallbackAnswer, m.MessageID, nil)
+	}
+
+	citations, err := s.kbService.GetCitations(ctx, output)
+	if err != nil {
+		log.Printf("telegram: failed to resolve citations: %v", err)
+	}
+
+	var buttons []telegram.InlineButton
+	for _, c := range citations {
+		buttons = append(buttons, telegram.InlineButton{
+			Text: "📄 " + truncate(c.Filename, maxButtonTextLength),
+			URL:  c.URL,
+		})
+	}
+
+	return s.client.SendMessage(ctx, m.Chat.ID, truncate(*output.Output.Text, maxMessageLength), m.MessageID, buttons)
+}
+
+// command returns the bot command a message starts with, without the bot name of /new@my_bot,
+// or "" when it is not a command
+func command(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+	return strings.SplitN(fields[0], "@", 2)[0]
+}
+
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
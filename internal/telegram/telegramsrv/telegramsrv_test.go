@@ -0,0 +1,28 @@
+package telegramsrv
+
+import "testing"
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"command", "/start", "/start"},
+		{"command with arguments", "/new please", "/new"},
+		{"command with bot name", "/new@my_bot", "/new"},
+		{"leading spaces", "   /help", "/help"},
+		{"question", "how do I reset my password?", ""},
+		{"slash later in text", "what is a/b testing", ""},
+		{"empty", "", ""},
+		{"blank", " \n\t ", ""},
+		{"bare slash", "/", "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := command(tt.text); got != tt.want {
+				t.Errorf("command(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
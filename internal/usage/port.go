@@ -0,0 +1,29 @@
+package usage
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// RecordCompletion stores a completion with its cost at the current price of its model
+	RecordCompletion(ctx context.Context, c Completion) (*Completion, error)
+	// GetCosts groups the completions of the range by day of the IANA timeZone, knowledge base, channel,
+	// chat user or model, days in order and the rest by cost, up to limit groups
+	GetCosts(ctx context.Context, groupBy string, startDate, endDate time.Time, timeZone string, limit int) ([]Cost, error)
+	// GetSpentSince adds up the cost of the completions since t
+	GetSpentSince(ctx context.Context, t time.Time) (float64, error)
+
+	GetPrices(ctx context.Context) ([]ModelPrice, error)
+	SavePrice(ctx context.Context, p ModelPrice) (*ModelPrice, error)
+	DeletePrice(ctx context.Context, modelID string) error
+
+	// CreateBudgetAlert stores an alert, or returns nil when the threshold already alerted that month
+	CreateBudgetAlert(ctx context.Context, a BudgetAlert) (*BudgetAlert, error)
+	GetBudgetAlerts(ctx context.Context, month time.Time) ([]BudgetAlert, error)
+}
+
+// Notifier tells an admin that the spend of the month crossed a budget threshold
+type Notifier interface {
+	NotifyBudgetAlert(ctx context.Context, address string, alert BudgetAlert) error
+}
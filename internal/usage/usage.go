@@ -0,0 +1,104 @@
+package usage
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Purposes of a completion
+const (
+	PurposeAnswer      = "answer"
+	PurposeSuggestions = "suggestions"
+	PurposeEvaluation  = "evaluation"
+	PurposeJudge       = "judge"
+)
+
+// ChannelEvaluation is the channel of the completions of evaluation runs, they have no chat user
+const ChannelEvaluation = "evaluation"
+
+// Dimensions the cost reports group completions by
+const (
+	GroupByDay           = "day"
+	GroupByKnowledgeBase = "kb"
+	GroupByChannel       = "channel"
+	GroupByChatUser      = "chat_user"
+	GroupByModel         = "model"
+)
+
+// Completion is a model call and the tokens it used. Estimated is set when the API did not return
+// the token counts and they were estimated from the text. Cost is in USD, nil when the model has no price.
+type Completion struct {
+	ID              int       `json:"id" db:"id"`
+	InteractionID   *int      `json:"interaction_id" db:"interaction_id"`
+	UserChatID      *string   `json:"user_chat_id" db:"user_chat_id"`
+	KnowledgeBaseID string    `json:"kb_id" db:"kb_id"`
+	Channel         string    `json:"channel" db:"channel"`
+	Purpose         string    `json:"purpose" db:"purpose"`
+	ModelID         string    `json:"model_id" db:"model_id"`
+	InputTokens     int       `json:"input_tokens" db:"input_tokens"`
+	OutputTokens    int       `json:"output_tokens" db:"output_tokens"`
+	Estimated       bool      `json:"estimated" db:"estimated"`
+	Cost            *float64  `json:"cost" db:"cost"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// ModelPrice is what a model costs in USD per million input and output tokens
+type ModelPrice struct {
+	ModelID          string    `json:"model_id" db:"model_id"`
+	InputPerMillion  float64   `json:"input_per_million" db:"input_per_million"`
+	OutputPerMillion float64   `json:"output_per_million" db:"output_per_million"`
+	UpdatedBy        *string   `json:"updated_by" db:"updated_by"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Cost is the token usage and cost of the completions of a group, like a day or a chat user.
+// Unpriced counts the completions of models without a price, left out of Cost.
+type Cost struct {
+	Key          string  `json:"key" db:"key"`
+	Completions  int     `json:"completions" db:"completions"`
+	InputTokens  int64   `json:"input_tokens" db:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens" db:"output_tokens"`
+	Estimated    int     `json:"estimated" db:"estimated"`
+	Unpriced     int     `json:"unpriced" db:"unpriced"`
+	Cost         float64 `json:"cost" db:"cost"`
+}
+
+// BudgetAlert records that the spend of a month crossed Percent of the monthly budget
+type BudgetAlert struct {
+	ID        int       `json:"id" db:"id"`
+	Month     time.Time `json:"month" db:"month"`
+	Percent   int       `json:"percent" db:"percent"`
+	Budget    float64   `json:"budget" db:"budget"`
+	Spent     float64   `json:"spent" db:"spent"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// BudgetStatus is the spend of the current month against the monthly budget, a zero Budget
+// means alerts are disabled
+type BudgetStatus struct {
+	Month   time.Time     `json:"month"`
+	Budget  float64       `json:"budget"`
+	Spent   float64       `json:"spent"`
+	Percent float64       `json:"percent"`
+	Alerts  []BudgetAlert `json:"alerts"`
+}
+
+// ModelName returns the model ID of a model ARN, arn:aws:bedrock:us-east-1::foundation-model/anthropic.claude-v2
+// is anthropic.claude-v2. Model IDs are returned as they are.
+func ModelName(modelID string) string {
+	if i := strings.LastIndex(modelID, "/"); i >= 0 {
+		return modelID[i+1:]
+	}
+	return modelID
+}
+
+// EstimateTokens approximates the tokens of a text at four characters per token,
+// for APIs that do not return the token counts
+func EstimateTokens(texts ...string) int {
+	var chars int
+	for _, text := range texts {
+		chars += utf8.RuneCountInString(text)
+	}
+	return (chars + 3) / 4
+}
@@ -0,0 +1,102 @@
+package usageapi
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/usage"
+	"github.com/Abraxas-365/opd/internal/usage/usagesrv"
+	"github.com/Abraxas-365/opd/internal/user"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/Abraxas-365/toolkit/pkg/lucia"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the admin routes of the token usage, model prices and budget
+func SetupRoutes(app *fiber.App, service *usagesrv.Service, authMiddleware *lucia.AuthMiddleware[*user.User]) {
+	app.Get("/usage/costs", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		startDate, err := time.Parse("2006-01-02", c.Query("start_date"))
+		if err != nil {
+			return errors.ErrBadRequest("Invalid start_date format. Use YYYY-MM-DD")
+		}
+		endDate, err := time.Parse("2006-01-02", c.Query("end_date"))
+		if err != nil {
+			return errors.ErrBadRequest("Invalid end_date format. Use YYYY-MM-DD")
+		}
+		if endDate.Before(startDate) {
+			return errors.ErrBadRequest("end_date cannot be before start_date")
+		}
+
+		var loc *time.Location
+		if tz := c.Query("tz"); tz != "" {
+			loc, err = time.LoadLocation(tz)
+			if err != nil || tz == "Local" {
+				return errors.ErrBadRequest("Invalid tz, use an IANA time zone like America/Lima")
+			}
+		}
+
+		limit, err := strconv.Atoi(c.Query("limit", "0"))
+		if err != nil {
+			return errors.ErrBadRequest("limit must be a number")
+		}
+
+		costs, err := service.GetCosts(c.Context(), startDate, endDate, loc, c.Query("group_by", usage.GroupByDay), limit)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{"data": costs})
+	})
+
+	app.Get("/usage/budget", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		status, err := service.GetBudgetStatus(c.Context())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(status)
+	})
+
+	app.Get("/usage/prices", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		prices, err := service.GetPrices(c.Context())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(prices)
+	})
+
+	app.Put("/usage/prices", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		var p usage.ModelPrice
+		if err := c.BodyParser(&p); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		userID, err := lucia.GetSession(c).UserIDToString()
+		if err != nil {
+			return err
+		}
+
+		saved, err := service.SavePrice(c.Context(), p, userID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(saved)
+	})
+
+	// Model IDs contain colons, clients escape them in the path
+	app.Delete("/usage/prices/:model", authMiddleware.RequireAuth(), func(c *fiber.Ctx) error {
+		modelID, err := url.PathUnescape(c.Params("model"))
+		if err != nil {
+			return errors.ErrBadRequest("Invalid model id")
+		}
+
+		if err := service.DeletePrice(c.Context(), modelID); err != nil {
+			return err
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+}
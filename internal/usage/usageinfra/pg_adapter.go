@@ -0,0 +1,174 @@
+package usageinfra
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/usage"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	completionColumns  = `id, interaction_id, user_chat_id, kb_id, channel, purpose, model_id, input_tokens, output_tokens, estimated, cost, created_at`
+	priceColumns       = `model_id, input_per_million, output_per_million, updated_by, updated_at`
+	budgetAlertColumns = `id, month, percent, budget, spent, created_at`
+)
+
+// costKeys are the expressions completions are grouped by in the cost reports,
+// days take the time zone as the last argument
+var costKeys = map[string]string{
+	usage.GroupByDay:           `to_char(date_trunc('day', created_at, $4::text), 'YYYY-MM-DD')`,
+	usage.GroupByKnowledgeBase: `kb_id`,
+	usage.GroupByChannel:       `channel`,
+	usage.GroupByChatUser:      `COALESCE(user_chat_id, '')`,
+	usage.GroupByModel:         `model_id`,
+}
+
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewUsageStore creates a new PostgresStore for usage repository
+func NewUsageStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// RecordCompletion stores a completion, the cost is computed here so later price changes
+// do not rewrite what was already spent
+func (s *PostgresStore) RecordCompletion(ctx context.Context, c usage.Completion) (*usage.Completion, error) {
+	query := `
+		INSERT INTO completions (interaction_id, user_chat_id, kb_id, channel, purpose, model_id, input_tokens, output_tokens, estimated, cost)
+		SELECT $1::integer, $2::text, $3::text, $4::text, $5::text, $6::text, $7::integer, $8::integer, $9::boolean,
+		       ($7 * p.input_per_million + $8 * p.output_per_million) / 1000000
+		FROM (SELECT 1) AS one
+		LEFT JOIN model_prices p ON p.model_id = $6
+		RETURNING ` + completionColumns
+
+	var created usage.Completion
+	err := s.db.QueryRowxContext(ctx, query,
+		c.InteractionID, c.UserChatID, c.KnowledgeBaseID, c.Channel, c.Purpose, c.ModelID,
+		c.InputTokens, c.OutputTokens, c.Estimated).StructScan(&created)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to record completion: %v", err))
+	}
+	return &created, nil
+}
+
+func (s *PostgresStore) GetCosts(ctx context.Context, groupBy string, startDate, endDate time.Time, timeZone string, limit int) ([]usage.Cost, error) {
+	key, ok := costKeys[groupBy]
+	if !ok {
+		return nil, errors.ErrBadRequest("unknown cost grouping " + groupBy)
+	}
+
+	order := `cost DESC, key`
+	args := []interface{}{startDate, endDate, limit}
+	if groupBy == usage.GroupByDay {
+		order = `key`
+		args = append(args, timeZone)
+	}
+
+	query := `
+		SELECT ` + key + ` AS key,
+		       COUNT(*) AS completions,
+		       COALESCE(SUM(input_tokens), 0) AS input_tokens,
+		       COALESCE(SUM(output_tokens), 0) AS output_tokens,
+		       COUNT(*) FILTER (WHERE estimated) AS estimated,
+		       COUNT(*) FILTER (WHERE cost IS NULL) AS unpriced,
+		       COALESCE(SUM(cost), 0) AS cost
+		FROM completions
+		WHERE created_at BETWEEN $1 AND $2
+		GROUP BY 1
+		ORDER BY ` + order + `
+		LIMIT $3`
+
+	costs := []usage.Cost{}
+	if err := s.db.SelectContext(ctx, &costs, query, args...); err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get costs: %v", err))
+	}
+	return costs, nil
+}
+
+func (s *PostgresStore) GetSpentSince(ctx context.Context, t time.Time) (float64, error) {
+	var spent float64
+	err := s.db.GetContext(ctx, &spent, `SELECT COALESCE(SUM(cost), 0) FROM completions WHERE created_at >= $1`, t)
+	if err != nil {
+		return 0, errors.ErrDatabase(fmt.Sprintf("Failed to get spend: %v", err))
+	}
+	return spent, nil
+}
+
+func (s *PostgresStore) GetPrices(ctx context.Context) ([]usage.ModelPrice, error) {
+	prices := []usage.ModelPrice{}
+	err := s.db.SelectContext(ctx, &prices, `SELECT `+priceColumns+` FROM model_prices ORDER BY model_id`)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get model prices: %v", err))
+	}
+	return prices, nil
+}
+
+// SavePrice creates or replaces the price of a model, completions recorded before keep their cost
+func (s *PostgresStore) SavePrice(ctx context.Context, p usage.ModelPrice) (*usage.ModelPrice, error) {
+	query := `
+		INSERT INTO model_prices (model_id, input_per_million, output_per_million, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (model_id) DO UPDATE SET
+			input_per_million = EXCLUDED.input_per_million,
+			output_per_million = EXCLUDED.output_per_million,
+			updated_by = EXCLUDED.updated_by
+		RETURNING ` + priceColumns
+
+	var saved usage.ModelPrice
+	err := s.db.QueryRowxContext(ctx, query, p.ModelID, p.InputPerMillion, p.OutputPerMillion, p.UpdatedBy).StructScan(&saved)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to save model price: %v", err))
+	}
+	return &saved, nil
+}
+
+func (s *PostgresStore) DeletePrice(ctx context.Context, modelID string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM model_prices WHERE model_id = $1`, modelID)
+	if err != nil {
+		return errors.ErrDatabase(fmt.Sprintf("Failed to delete model price: %v", err))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.ErrDatabase(fmt.Sprintf("Failed to get affected rows: %v", err))
+	}
+	if rows == 0 {
+		return errors.ErrNotFound("model price not found")
+	}
+	return nil
+}
+
+// CreateBudgetAlert stores an alert unless the threshold already alerted that month, so instances
+// recording completions at the same time alert only once
+func (s *PostgresStore) CreateBudgetAlert(ctx context.Context, a usage.BudgetAlert) (*usage.BudgetAlert, error) {
+	query := `
+		INSERT INTO budget_alerts (month, percent, budget, spent)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (month, percent) DO NOTHING
+		RETURNING ` + budgetAlertColumns
+
+	var created usage.BudgetAlert
+	err := s.db.QueryRowxContext(ctx, query, a.Month, a.Percent, a.Budget, a.Spent).StructScan(&created)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to create budget alert: %v", err))
+	}
+	return &created, nil
+}
+
+func (s *PostgresStore) GetBudgetAlerts(ctx context.Context, month time.Time) ([]usage.BudgetAlert, error) {
+	alerts := []usage.BudgetAlert{}
+	err := s.db.SelectContext(ctx, &alerts, `SELECT `+budgetAlertColumns+` FROM budget_alerts WHERE month = $1 ORDER BY percent`, month)
+	if err != nil {
+		return nil, errors.ErrDatabase(fmt.Sprintf("Failed to get budget alerts: %v", err))
+	}
+	return alerts, nil
+}
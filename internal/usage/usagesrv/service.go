@@ -0,0 +1,199 @@
+package usagesrv
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Abraxas-365/opd/internal/usage"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+const (
+	// DefaultCostGroups is how many groups a cost report returns when no limit is given
+	DefaultCostGroups = 50
+	// maxCostGroups bounds the limit of the cost reports
+	maxCostGroups = 1000
+)
+
+// DefaultAlertPercents are the shares of the monthly budget that alert when no others are configured
+var DefaultAlertPercents = []int{80, 100}
+
+type Service struct {
+	repo          usage.Repository
+	notifier      usage.Notifier
+	budget        float64
+	alertPercents []int
+	alertEmails   []string
+	timeZone      *time.Location
+}
+
+// New creates the usage service. A zero budget disables the budget alerts, months and days
+// of the reports are in timeZone.
+func New(repo usage.Repository, budget float64, alertPercents []int, alertEmails []string, timeZone *time.Location) *Service {
+	if len(alertPercents) == 0 {
+		alertPercents = DefaultAlertPercents
+	}
+	alertPercents = append([]int(nil), alertPercents...)
+	sort.Ints(alertPercents)
+	if timeZone == nil {
+		timeZone = time.UTC
+	}
+	return &Service{
+		repo:          repo,
+		budget:        budget,
+		alertPercents: alertPercents,
+		alertEmails:   alertEmails,
+		timeZone:      timeZone,
+	}
+}
+
+// RegisterNotifier sets how the alert emails are sent, without one alerts are only logged and listed
+func (s *Service) RegisterNotifier(notifier usage.Notifier) {
+	s.notifier = notifier
+}
+
+// Record stores a completion and alerts when it takes the spend of the month past a budget threshold.
+// Model ARNs are stored as model IDs so they match the price table.
+func (s *Service) Record(ctx context.Context, c usage.Completion) (*usage.Completion, error) {
+	c.ModelID = usage.ModelName(c.ModelID)
+
+	recorded, err := s.repo.RecordCompletion(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if recorded.Cost != nil && *recorded.Cost > 0 {
+		if err := s.checkBudget(ctx); err != nil {
+			log.Printf("usage: failed to check budget: %v", err)
+		}
+	}
+	return recorded, nil
+}
+
+// checkBudget alerts once a month for every threshold the spend of the month crossed
+func (s *Service) checkBudget(ctx context.Context) error {
+	if s.budget <= 0 {
+		return nil
+	}
+
+	month := s.monthStart(time.Now())
+	spent, err := s.repo.GetSpentSince(ctx, month)
+	if err != nil {
+		return err
+	}
+
+	for _, percent := range s.alertPercents {
+		if spent < s.budget*float64(percent)/100 {
+			break
+		}
+		alert, err := s.repo.CreateBudgetAlert(ctx, usage.BudgetAlert{
+			Month:   month,
+			Percent: percent,
+			Budget:  s.budget,
+			Spent:   spent,
+		})
+		if err != nil {
+			return err
+		}
+		if alert == nil {
+			continue
+		}
+
+		log.Printf("usage: spend of %s is %.2f USD, %d%% of the %.2f USD budget", month.Format("2006-01"), spent, percent, s.budget)
+		s.notify(ctx, *alert)
+	}
+	return nil
+}
+
+func (s *Service) notify(ctx context.Context, alert usage.BudgetAlert) {
+	if s.notifier == nil {
+		return
+	}
+	for _, address := range s.alertEmails {
+		if err := s.notifier.NotifyBudgetAlert(ctx, address, alert); err != nil {
+			log.Printf("usage: failed to notify %s of budget alert: %v", address, err)
+		}
+	}
+}
+
+// GetBudgetStatus returns the spend of the current month against the budget and the alerts it raised
+func (s *Service) GetBudgetStatus(ctx context.Context) (*usage.BudgetStatus, error) {
+	month := s.monthStart(time.Now())
+	spent, err := s.repo.GetSpentSince(ctx, month)
+	if err != nil {
+		return nil, err
+	}
+	alerts, err := s.repo.GetBudgetAlerts(ctx, month)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &usage.BudgetStatus{
+		Month:  month,
+		Budget: s.budget,
+		Spent:  spent,
+		Alerts: alerts,
+	}
+	if s.budget > 0 {
+		status.Percent = spent / s.budget * 100
+	}
+	return status, nil
+}
+
+// GetCosts reports the token usage and cost of the completions between the calendar days of startDate and
+// endDate in loc, the configured time zone when nil, grouped by day, knowledge base, channel, chat user or model.
+// Days are all returned in order, the other groups are the limit most expensive.
+func (s *Service) GetCosts(ctx context.Context, startDate, endDate time.Time, loc *time.Location, groupBy string, limit int) ([]usage.Cost, error) {
+	switch groupBy {
+	case usage.GroupByDay, usage.GroupByKnowledgeBase, usage.GroupByChannel, usage.GroupByChatUser, usage.GroupByModel:
+	default:
+		return nil, errors.ErrBadRequest("group_by must be day, kb, channel, chat_user or model")
+	}
+	if limit == 0 {
+		limit = DefaultCostGroups
+	}
+	if limit < 0 || limit > maxCostGroups {
+		return nil, errors.ErrBadRequest("limit must be between 1 and 1000")
+	}
+
+	if loc == nil {
+		loc = s.timeZone
+	}
+	start := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, loc)
+	if groupBy == usage.GroupByDay {
+		// Every day of the range, a day longer for daylight saving time
+		limit = int(end.Sub(start).Hours()/24) + 2
+	}
+
+	return s.repo.GetCosts(ctx, groupBy, start, end, loc.String(), limit)
+}
+
+func (s *Service) GetPrices(ctx context.Context) ([]usage.ModelPrice, error) {
+	return s.repo.GetPrices(ctx)
+}
+
+// SavePrice sets the price of a model, it applies to the completions recorded from now on
+func (s *Service) SavePrice(ctx context.Context, p usage.ModelPrice, userID string) (*usage.ModelPrice, error) {
+	p.ModelID = usage.ModelName(strings.TrimSpace(p.ModelID))
+	if p.ModelID == "" {
+		return nil, errors.ErrBadRequest("model_id is required")
+	}
+	if p.InputPerMillion < 0 || p.OutputPerMillion < 0 {
+		return nil, errors.ErrBadRequest("prices cannot be negative")
+	}
+	p.UpdatedBy = &userID
+
+	return s.repo.SavePrice(ctx, p)
+}
+
+func (s *Service) DeletePrice(ctx context.Context, modelID string) error {
+	return s.repo.DeletePrice(ctx, usage.ModelName(modelID))
+}
+
+func (s *Service) monthStart(t time.Time) time.Time {
+	t = t.In(s.timeZone)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, s.timeZone)
+}
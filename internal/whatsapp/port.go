@@ -0,0 +1,16 @@
+package whatsapp
+
+import "context"
+
+// Client sends messages through the WhatsApp Cloud API
+type Client interface {
+	// SendText sends body to the phone number to, replying to the message replyTo when it is not empty
+	SendText(ctx context.Context, to, body, replyTo string) error
+}
+
+type Repository interface {
+	// MarkMessageReceived stores the ID of an inbound message, it returns false when the message was already received
+	MarkMessageReceived(ctx context.Context, messageID string) (bool, error)
+	// UnmarkMessageReceived forgets an inbound message that was not answered, so a new delivery answers it
+	UnmarkMessageReceived(ctx context.Context, messageID string) error
+}
@@ -0,0 +1,66 @@
+package whatsapp
+
+// WebhookPayload is the body WhatsApp Cloud API posts to the webhook
+type WebhookPayload struct {
+	Object string  `json:"object"`
+	Entry  []Entry `json:"entry"`
+}
+
+type Entry struct {
+	ID      string   `json:"id"`
+	Changes []Change `json:"changes"`
+}
+
+type Change struct {
+	Field string `json:"field"`
+	Value Value  `json:"value"`
+}
+
+type Value struct {
+	MessagingProduct string    `json:"messaging_product"`
+	Metadata         Metadata  `json:"metadata"`
+	Contacts         []Contact `json:"contacts"`
+	Messages         []Message `json:"messages"`
+}
+
+type Metadata struct {
+	DisplayPhoneNumber string `json:"display_phone_number"`
+	PhoneNumberID      string `json:"phone_number_id"`
+}
+
+type Contact struct {
+	WaID    string `json:"wa_id"`
+	Profile struct {
+		Name string `json:"name"`
+	} `json:"profile"`
+}
+
+type Message struct {
+	ID        string `json:"id"`
+	From      string `json:"from"`
+	Timestamp string `json:"timestamp"`
+	Type      string `json:"type"`
+	Text      *Text  `json:"text,omitempty"`
+}
+
+type Text struct {
+	Body string `json:"body"`
+}
+
+// Messages returns every incoming text message in the payload
+func (p WebhookPayload) Messages() []Message {
+	var messages []Message
+	for _, entry := range p.Entry {
+		for _, change := range entry.Changes {
+			if change.Field != "messages" {
+				continue
+			}
+			for _, m := range change.Value.Messages {
+				if m.Type == "text" && m.Text != nil {
+					messages = append(messages, m)
+				}
+			}
+		}
+	}
+	return messages
+}
@@ -0,0 +1,45 @@
+package whatsappapi
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Abraxas-365/opd/internal/whatsapp"
+	"github.com/Abraxas-365/opd/internal/whatsapp/whatsappsrv"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRoutes sets up the WhatsApp Cloud API webhook
+func SetupRoutes(app *fiber.App, service *whatsappsrv.Service) {
+	// Webhook verification, called by Meta when the webhook is registered
+	app.Get("/webhooks/whatsapp", func(c *fiber.Ctx) error {
+		challenge, err := service.VerifySubscription(
+			c.Query("hub.mode"),
+			c.Query("hub.verify_token"),
+			c.Query("hub.challenge"),
+		)
+		if err != nil {
+			return c.Status(fiber.StatusForbidden).SendString("Invalid verify token")
+		}
+
+		return c.SendString(challenge)
+	})
+
+	// Incoming messages
+	app.Post("/webhooks/whatsapp", func(c *fiber.Ctx) error {
+		body := c.Body()
+		if err := service.VerifySignature(body, c.Get("X-Hub-Signature-256")); err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid signature"})
+		}
+
+		var payload whatsapp.WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		// Meta retries deliveries that are not acknowledged quickly, so answer in the background
+		go service.HandleWebhook(context.Background(), payload)
+
+		return c.SendStatus(fiber.StatusOK)
+	})
+}
@@ -0,0 +1,88 @@
+package whatsappinfra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// GraphClient sends WhatsApp messages through the Graph API
+type GraphClient struct {
+	baseURL       string
+	phoneNumberID string
+	token         string
+	httpClient    *http.Client
+}
+
+// NewGraphClient creates a GraphClient, baseURL can point to a local fake server in tests
+func NewGraphClient(baseURL, phoneNumberID, token string) *GraphClient {
+	return &GraphClient{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		phoneNumberID: phoneNumberID,
+		token:         token,
+		httpClient:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// SendText sends a text message to a WhatsApp user
+func (c *GraphClient) SendText(ctx context.Context, to, body, replyTo string) error {
+	type text struct {
+		PreviewURL bool   `json:"preview_url"`
+		Body       string `json:"body"`
+	}
+	type replyContext struct {
+		MessageID string `json:"message_id"`
+	}
+	type request struct {
+		MessagingProduct string        `json:"messaging_product"`
+		RecipientType    string        `json:"recipient_type"`
+		To               string        `json:"to"`
+		Type             string        `json:"type"`
+		Text             text          `json:"text"`
+		Context          *replyContext `json:"context,omitempty"`
+	}
+
+	req := request{
+		MessagingProduct: "whatsapp",
+		RecipientType:    "individual",
+		To:               to,
+		Type:             "text",
+		Text:             text{Body: body},
+	}
+	if replyTo != "" {
+		req.Context = &replyContext{MessageID: replyTo}
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return errors.ErrUnexpected("failed to encode WhatsApp message: " + err.Error())
+	}
+
+	url := fmt.Sprintf("%s/%s/messages", c.baseURL, c.phoneNumberID)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return errors.ErrUnexpected("failed to create WhatsApp request: " + err.Error())
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return errors.ErrServiceUnavailable("failed to send WhatsApp message: " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return errors.ErrServiceUnavailable(fmt.Sprintf("WhatsApp API returned %d: %s", resp.StatusCode, respBody))
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package whatsappinfra
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendText(t *testing.T) {
+	tests := []struct {
+		name        string
+		replyTo     string
+		status      int
+		wantContext bool
+		wantErr     bool
+	}{
+		{"message", "", http.StatusOK, false, false},
+		{"reply", "wamid.123", http.StatusOK, true, false},
+		{"API error", "", http.StatusBadRequest, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/v21.0/12345/messages" {
+					t.Errorf("request %s %s, want POST /v21.0/12345/messages", r.Method, r.URL.Path)
+				}
+				if auth := r.Header.Get("Authorization"); auth != "Bearer token" {
+					t.Errorf("Authorization = %q, want %q", auth, "Bearer token")
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("invalid request body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := NewGraphClient(server.URL+"/v21.0/", "12345", "token")
+			err := client.SendText(context.Background(), "51999888777", "hello", tt.replyTo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendText error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if got["to"] != "51999888777" || got["type"] != "text" || got["messaging_product"] != "whatsapp" {
+				t.Errorf("unexpected message %v", got)
+			}
+			if text, _ := got["text"].(map[string]interface{}); text["body"] != "hello" {
+				t.Errorf("text = %v, want body hello", got["text"])
+			}
+			replyContext, hasContext := got["context"].(map[string]interface{})
+			if hasContext != tt.wantContext {
+				t.Fatalf("context = %v, want context %v", got["context"], tt.wantContext)
+			}
+			if hasContext && replyContext["message_id"] != tt.replyTo {
+				t.Errorf("context message_id = %v, want %q", replyContext["message_id"], tt.replyTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package whatsappinfra
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewWhatsAppStore creates a new PostgresStore for whatsapp repository
+func NewWhatsAppStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// MarkMessageReceived relies on the primary key so concurrent deliveries of a message are handled once
+func (s *PostgresStore) MarkMessageReceived(ctx context.Context, messageID string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `INSERT INTO whatsapp_messages (id) VALUES ($1) ON CONFLICT (id) DO NOTHING`, messageID)
+	if err != nil {
+		return false, errors.ErrDatabase(fmt.Sprintf("Failed to mark message received: %v", err))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.ErrDatabase(fmt.Sprintf("Failed to get affected rows: %v", err))
+	}
+	return rows > 0, nil
+}
+
+func (s *PostgresStore) UnmarkMessageReceived(ctx context.Context, messageID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM whatsapp_messages WHERE id = $1`, messageID); err != nil {
+		return errors.ErrDatabase(fmt.Sprintf("Failed to unmark message received: %v", err))
+	}
+	return nil
+}
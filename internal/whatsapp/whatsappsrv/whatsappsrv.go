@@ -0,0 +1,122 @@
+package whatsappsrv
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+
+	"github.com/Abraxas-365/opd/internal/chatuser"
+	"github.com/Abraxas-365/opd/internal/chatuser/chatusersrv"
+	kbsrv "github.com/Abraxas-365/opd/internal/kb/kbasesrv"
+	"github.com/Abraxas-365/opd/internal/whatsapp"
+	"github.com/Abraxas-365/toolkit/pkg/errors"
+)
+
+// maxMessageLength is the longest text body the Cloud API accepts
+const maxMessageLength = 4096
+
+const fallbackAnswer = "Sorry, we could not answer your question right now. Please try again later."
+
+type Service struct {
+	client          whatsapp.Client
+	repo            whatsapp.Repository
+	kbService       *kbsrv.Service
+	chatUserService *chatusersrv.Service
+	verifyToken     string
+	appSecret       string
+}
+
+func New(client whatsapp.Client,
+	repo whatsapp.Repository,
+	kbService *kbsrv.Service,
+	chatUserService *chatusersrv.Service,
+	verifyToken string,
+	appSecret string,
+) *Service {
+	return &Service{
+		client:          client,
+		repo:            repo,
+		kbService:       kbService,
+		chatUserService: chatUserService,
+		verifyToken:     verifyToken,
+		appSecret:       appSecret,
+	}
+}
+
+// VerifySubscription answers the webhook verification request Meta sends when the webhook is registered
+func (s *Service) VerifySubscription(mode, token, challenge string) (string, error) {
+	if mode != "subscribe" || !hmac.Equal([]byte(token), []byte(s.verifyToken)) {
+		return "", errors.ErrForbidden("invalid verify token")
+	}
+	return challenge, nil
+}
+
+// VerifySignature checks the X-Hub-Signature-256 header against the raw request body
+func (s *Service) VerifySignature(body []byte, signature string) error {
+	signature = strings.TrimPrefix(signature, "sha256=")
+	received, err := hex.DecodeString(signature)
+	if err != nil {
+		return errors.ErrUnauthorized("invalid signature")
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.appSecret))
+	mac.Write(body)
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		return errors.ErrUnauthorized("invalid signature")
+	}
+	return nil
+}
+
+// HandleWebhook answers every text message in the payload. Messages Meta delivers again are skipped
+// unless answering them failed.
+func (s *Service) HandleWebhook(ctx context.Context, payload whatsapp.WebhookPayload) {
+	for _, m := range payload.Messages() {
+		received, err := s.repo.MarkMessageReceived(ctx, m.ID)
+		if err != nil {
+			log.Printf("whatsapp: failed to mark message %s received: %v", m.ID, err)
+			continue
+		}
+		if !received {
+			continue
+		}
+		if err := s.answer(ctx, m); err != nil {
+			log.Printf("whatsapp: failed to answer message %s: %v", m.ID, err)
+			if err := s.repo.UnmarkMessageReceived(ctx, m.ID); err != nil {
+				log.Printf("whatsapp: failed to unmark message %s: %v", m.ID, err)
+			}
+		}
+	}
+}
+
+// Deliver sends an agent reply to a WhatsApp user, implementing handoff.Deliverer
+func (s *Service) Deliver(ctx context.Context, phoneNumber string, text string) error {
+	return s.client.SendText(ctx, phoneNumber, truncate(text, maxMessageLength), "")
+}
+
+func (s *Service) answer(ctx context.Context, m whatsapp.Message) error {
+	u, err := s.chatUserService.GetOrCreateChannelChatUser(ctx, chatuser.ChannelWhatsApp, m.From)
+	if err != nil {
+		return err
+	}
+
+	answer := fallbackAnswer
+	output, err := s.kbService.CompleteAnswerWithMetadata(ctx, m.Text.Body, nil, *u.ID, kbsrv.FromChannel(chatuser.ChannelWhatsApp))
+	if err != nil {
+		log.Printf("whatsapp: completion failed for chat user %s: %v", *u.ID, err)
+	} else if output.Output != nil && output.Output.Text != nil {
+		answer = *output.Output.Text
+	}
+
+	return s.client.SendText(ctx, m.From, truncate(answer, maxMessageLength), m.ID)
+}
+
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
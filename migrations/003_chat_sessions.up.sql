@@ -0,0 +1,13 @@
+-- Active Bedrock session per chat user
+CREATE TABLE chat_sessions (
+    user_chat_id TEXT PRIMARY KEY REFERENCES chatUser(id) ON DELETE CASCADE,
+    session_id TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_chat_sessions_timestamp
+    BEFORE UPDATE ON chat_sessions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
@@ -0,0 +1,10 @@
+-- External identities (phone numbers, Slack users, ...) linked to chat users
+CREATE TABLE chat_user_channels (
+    channel TEXT NOT NULL,
+    external_id TEXT NOT NULL,
+    user_chat_id TEXT NOT NULL REFERENCES chatUser(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel, external_id)
+);
+
+CREATE INDEX chat_user_channels_user_chat_id_idx ON chat_user_channels (user_chat_id);
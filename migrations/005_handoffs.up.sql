@@ -0,0 +1,45 @@
+-- Conversations escalated to a human agent
+CREATE TABLE handoffs (
+    id SERIAL PRIMARY KEY,
+    user_chat_id TEXT NOT NULL REFERENCES chatUser(id) ON DELETE CASCADE,
+    channel TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    question TEXT NOT NULL,
+    claimed_by TEXT REFERENCES "user"(id) ON DELETE SET NULL,
+    claimed_at TIMESTAMP WITH TIME ZONE,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A chat user has at most one handoff that is not closed
+CREATE UNIQUE INDEX handoffs_active_user_chat_id_idx ON handoffs (user_chat_id) WHERE status <> 'closed';
+CREATE INDEX handoffs_status_idx ON handoffs (status, created_at);
+
+CREATE TRIGGER update_handoffs_timestamp
+    BEFORE UPDATE ON handoffs
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+CREATE TABLE handoff_messages (
+    id SERIAL PRIMARY KEY,
+    handoff_id INTEGER NOT NULL REFERENCES handoffs(id) ON DELETE CASCADE,
+    author TEXT NOT NULL,
+    agent_id TEXT REFERENCES "user"(id) ON DELETE SET NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX handoff_messages_handoff_id_idx ON handoff_messages (handoff_id, id);
+
+-- Escalations, claims, replies and closes, for analytics
+CREATE TABLE handoff_events (
+    id SERIAL PRIMARY KEY,
+    handoff_id INTEGER NOT NULL REFERENCES handoffs(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX handoff_events_created_at_idx ON handoff_events (created_at);
@@ -0,0 +1,22 @@
+-- Curated answers returned instead of a generated one when a question matches
+CREATE TABLE faqs (
+    id SERIAL PRIMARY KEY,
+    question TEXT NOT NULL,
+    variants TEXT[] NOT NULL DEFAULT '{}',
+    answer TEXT NOT NULL,
+    citation_title TEXT NOT NULL DEFAULT '',
+    citation_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_faqs_timestamp
+    BEFORE UPDATE ON faqs
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+-- The FAQ that answered an interaction, NULL when the model answered
+ALTER TABLE interactions
+ADD COLUMN faq_id INTEGER REFERENCES faqs(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_interactions_faq_id ON interactions (faq_id);
@@ -0,0 +1,17 @@
+-- Runtime settings admins can change without a deploy
+CREATE TABLE settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_by TEXT REFERENCES "user"(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_settings_timestamp
+    BEFORE UPDATE ON settings
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+-- Questions the chat user picked from the suggested follow-ups
+ALTER TABLE interactions
+ADD COLUMN from_suggestion BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+-- Question asked and how well the knowledge base answered it, NULL for older interactions
+ALTER TABLE interactions
+ADD COLUMN question TEXT,
+ADD COLUMN answer_status TEXT CHECK (answer_status IN ('answered', 'partial', 'not_found'));
+
+CREATE INDEX idx_interactions_answer_status ON interactions (answer_status, created_at);
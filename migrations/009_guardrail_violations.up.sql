@@ -0,0 +1,12 @@
+-- Messages and answers stopped by a guardrail rule
+CREATE TABLE guardrail_violations (
+    id SERIAL PRIMARY KEY,
+    user_chat_id TEXT REFERENCES chatUser(id) ON DELETE SET NULL,
+    direction TEXT NOT NULL CHECK (direction IN ('input', 'output')),
+    rule TEXT NOT NULL,
+    detail TEXT NOT NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_guardrail_violations_created_at ON guardrail_violations (created_at);
@@ -0,0 +1,21 @@
+-- Original values of redacted messages, encrypted with PII_VAULT_KEY
+CREATE TABLE pii_vault (
+    id SERIAL PRIMARY KEY,
+    user_chat_id TEXT REFERENCES chatUser(id) ON DELETE CASCADE,
+    redacted_text TEXT NOT NULL,
+    entity_types TEXT[] NOT NULL,
+    secret BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Audit of admins recovering vault entries
+CREATE TABLE pii_vault_reveals (
+    id SERIAL PRIMARY KEY,
+    vault_id INTEGER NOT NULL REFERENCES pii_vault(id) ON DELETE CASCADE,
+    revealed_by TEXT NOT NULL REFERENCES "user"(id),
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE interactions
+ADD COLUMN pii_vault_id INTEGER REFERENCES pii_vault(id) ON DELETE SET NULL;
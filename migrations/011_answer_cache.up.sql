@@ -0,0 +1,21 @@
+-- Answers reused for repeated questions, per knowledge base and prompt version
+CREATE TABLE answer_cache (
+    id SERIAL PRIMARY KEY,
+    kb_id TEXT NOT NULL,
+    prompt_version TEXT NOT NULL,
+    question TEXT NOT NULL,
+    normalized_question TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    refs JSONB NOT NULL DEFAULT '[]',
+    hits INTEGER NOT NULL DEFAULT 0,
+    last_hit_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_answer_cache_lookup ON answer_cache (kb_id, prompt_version, expires_at);
+CREATE INDEX idx_answer_cache_normalized ON answer_cache (kb_id, prompt_version, normalized_question);
+
+-- Answers served from the cache instead of a model call
+ALTER TABLE interactions
+ADD COLUMN cached BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,63 @@
+-- Questions with their expected answer and source files, to measure answer quality offline
+CREATE TABLE eval_datasets (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_eval_datasets_timestamp
+    BEFORE UPDATE ON eval_datasets
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+CREATE TABLE eval_cases (
+    id SERIAL PRIMARY KEY,
+    dataset_id INTEGER NOT NULL REFERENCES eval_datasets(id) ON DELETE CASCADE,
+    question TEXT NOT NULL,
+    expected_answer TEXT NOT NULL DEFAULT '',
+    expected_sources TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX idx_eval_cases_dataset_id ON eval_cases (dataset_id);
+
+-- A dataset answered with one model, prompt and number of results
+CREATE TABLE eval_runs (
+    id SERIAL PRIMARY KEY,
+    dataset_id INTEGER NOT NULL REFERENCES eval_datasets(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'running',
+    grader TEXT NOT NULL,
+    model_id TEXT NOT NULL,
+    prompt TEXT NOT NULL,
+    number_of_results INTEGER NOT NULL,
+    case_count INTEGER NOT NULL DEFAULT 0,
+    retrieval_hit_rate DOUBLE PRECISION,
+    answer_score DOUBLE PRECISION,
+    error TEXT,
+    created_by TEXT,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_eval_runs_dataset_id ON eval_runs (dataset_id);
+
+-- Results keep a copy of the case, datasets can be edited after a run
+CREATE TABLE eval_results (
+    id SERIAL PRIMARY KEY,
+    run_id INTEGER NOT NULL REFERENCES eval_runs(id) ON DELETE CASCADE,
+    question TEXT NOT NULL,
+    expected_answer TEXT NOT NULL,
+    expected_sources TEXT[] NOT NULL DEFAULT '{}',
+    answer TEXT NOT NULL DEFAULT '',
+    retrieved_sources TEXT[] NOT NULL DEFAULT '{}',
+    cited_sources TEXT[] NOT NULL DEFAULT '{}',
+    retrieval_hit BOOLEAN,
+    similarity DOUBLE PRECISION NOT NULL DEFAULT 0,
+    judge_score DOUBLE PRECISION,
+    judge_reasoning TEXT NOT NULL DEFAULT '',
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX idx_eval_results_run_id ON eval_results (run_id);
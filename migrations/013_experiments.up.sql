@@ -0,0 +1,45 @@
+-- A/B experiments splitting chat users across knowledge base configurations
+CREATE TABLE experiments (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'running',
+    winner_variant_id INTEGER,
+    created_by TEXT,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    stopped_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_experiments_timestamp
+    BEFORE UPDATE ON experiments
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+-- Only one experiment splits the traffic at a time
+CREATE UNIQUE INDEX idx_experiments_running ON experiments (status) WHERE status = 'running';
+
+-- Empty fields keep the knowledge base configuration
+CREATE TABLE experiment_variants (
+    id SERIAL PRIMARY KEY,
+    experiment_id INTEGER NOT NULL REFERENCES experiments(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    model_id TEXT NOT NULL DEFAULT '',
+    prompt TEXT NOT NULL DEFAULT '',
+    number_of_results INTEGER NOT NULL DEFAULT 0,
+    weight INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX idx_experiment_variants_experiment_id ON experiment_variants (experiment_id);
+
+ALTER TABLE experiments
+ADD CONSTRAINT fk_experiments_winner FOREIGN KEY (winner_variant_id) REFERENCES experiment_variants(id) ON DELETE SET NULL;
+
+-- The variant that answered, how long the answer took and whether the chat user found it helpful
+ALTER TABLE interactions
+ADD COLUMN variant_id INTEGER REFERENCES experiment_variants(id) ON DELETE SET NULL,
+ADD COLUMN latency_ms INTEGER,
+ADD COLUMN helpful BOOLEAN;
+
+CREATE INDEX idx_interactions_variant_id ON interactions (variant_id);
+CREATE INDEX idx_interactions_user_chat_id ON interactions (user_chat_id, id);
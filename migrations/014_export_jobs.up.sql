@@ -0,0 +1,22 @@
+-- Database exports built in the background, streamed from Postgres to S3
+CREATE TABLE export_jobs (
+    id SERIAL PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'running',
+    requested_by TEXT REFERENCES "user"(id) ON DELETE SET NULL,
+    start_date TIMESTAMP WITH TIME ZONE,
+    end_date TIMESTAMP WITH TIME ZONE,
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    exported_rows INTEGER NOT NULL DEFAULT 0,
+    s3_key TEXT,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TRIGGER update_export_jobs_timestamp
+    BEFORE UPDATE ON export_jobs
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+CREATE INDEX idx_export_jobs_requested_by ON export_jobs (requested_by, id);
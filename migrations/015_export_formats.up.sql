@@ -0,0 +1,3 @@
+-- Format of the export file: csv, ndjson, xlsx or parquet
+ALTER TABLE export_jobs
+ADD COLUMN format TEXT NOT NULL DEFAULT 'csv';
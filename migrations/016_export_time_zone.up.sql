@@ -0,0 +1,3 @@
+-- IANA time zone the date range of the export was given in
+ALTER TABLE export_jobs
+ADD COLUMN time_zone TEXT NOT NULL DEFAULT 'UTC';
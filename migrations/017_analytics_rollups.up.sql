@@ -0,0 +1,41 @@
+-- Interactions per hour, so reports read a row per hour instead of scanning the interactions table
+CREATE TABLE analytics_hourly (
+    bucket TIMESTAMP WITH TIME ZONE PRIMARY KEY,
+    interactions INTEGER NOT NULL,
+    answered INTEGER NOT NULL,
+    partial INTEGER NOT NULL,
+    not_found INTEGER NOT NULL,
+    faq_matched INTEGER NOT NULL,
+    suggestion_clicks INTEGER NOT NULL
+);
+
+-- Citations of every cited file per hour, s3_key without the s3://bucket/ prefix like files.s3_key
+CREATE TABLE analytics_file_hourly (
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    s3_key TEXT NOT NULL,
+    citations INTEGER NOT NULL,
+    interactions INTEGER NOT NULL,
+    PRIMARY KEY (bucket, s3_key)
+);
+
+CREATE INDEX idx_analytics_file_hourly_s3_key ON analytics_file_hourly (s3_key, bucket);
+
+-- Interactions of every chat user per hour. Distinct chat users, in total or per demographic value,
+-- cannot be added up across hours so they are counted from this table
+CREATE TABLE analytics_chat_user_hourly (
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    user_chat_id TEXT NOT NULL REFERENCES chatUser(id) ON DELETE CASCADE,
+    interactions INTEGER NOT NULL,
+    PRIMARY KEY (bucket, user_chat_id)
+);
+
+CREATE INDEX idx_analytics_chat_user_hourly_user ON analytics_chat_user_hourly (user_chat_id, bucket);
+
+-- The rollups hold every hour before rolled_up_to, interactions since then are read live
+CREATE TABLE analytics_rollup_state (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    rolled_up_to TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+INSERT INTO analytics_rollup_state (rolled_up_to)
+SELECT date_trunc('hour', COALESCE(MIN(created_at), CURRENT_TIMESTAMP), 'UTC') FROM interactions;
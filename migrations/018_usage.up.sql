@@ -0,0 +1,55 @@
+-- Bedrock on-demand prices in USD per million tokens, model_id is the model ID without the ARN prefix
+CREATE TABLE model_prices (
+    model_id TEXT PRIMARY KEY,
+    input_per_million NUMERIC(12, 6) NOT NULL CHECK (input_per_million >= 0),
+    output_per_million NUMERIC(12, 6) NOT NULL CHECK (output_per_million >= 0),
+    updated_by TEXT REFERENCES "user"(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_model_prices_timestamp
+    BEFORE UPDATE ON model_prices
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+-- us-east-1 prices at the time of writing, check them against the Bedrock pricing page
+INSERT INTO model_prices (model_id, input_per_million, output_per_million) VALUES
+    ('anthropic.claude-3-haiku-20240307-v1:0', 0.25, 1.25),
+    ('anthropic.claude-3-sonnet-20240229-v1:0', 3, 15),
+    ('anthropic.claude-3-5-sonnet-20240620-v1:0', 3, 15),
+    ('anthropic.claude-3-opus-20240229-v1:0', 15, 75),
+    ('anthropic.claude-instant-v1', 0.8, 2.4),
+    ('anthropic.claude-v2', 8, 24),
+    ('anthropic.claude-v2:1', 8, 24);
+
+-- Token counts of every model completion. Counts are estimated from the text when the API does not
+-- return them, cost is computed with the price of the model when the completion is recorded and is
+-- NULL when the model has no price.
+CREATE TABLE completions (
+    id SERIAL PRIMARY KEY,
+    interaction_id INTEGER REFERENCES interactions(id) ON DELETE SET NULL,
+    user_chat_id TEXT REFERENCES chatUser(id) ON DELETE SET NULL,
+    kb_id TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    purpose TEXT NOT NULL,
+    model_id TEXT NOT NULL,
+    input_tokens INTEGER NOT NULL,
+    output_tokens INTEGER NOT NULL,
+    estimated BOOLEAN NOT NULL,
+    cost NUMERIC(14, 8),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_completions_created_at ON completions (created_at);
+
+-- Monthly budget thresholds crossed, a threshold alerts once a month
+CREATE TABLE budget_alerts (
+    id SERIAL PRIMARY KEY,
+    month TIMESTAMP WITH TIME ZONE NOT NULL,
+    percent INTEGER NOT NULL,
+    budget NUMERIC(14, 2) NOT NULL,
+    spent NUMERIC(14, 8) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (month, percent)
+);
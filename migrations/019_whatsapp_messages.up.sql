@@ -0,0 +1,5 @@
+-- Inbound WhatsApp messages already handled, Meta may deliver a message more than once
+CREATE TABLE whatsapp_messages (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
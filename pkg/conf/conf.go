@@ -1,13 +1,27 @@
 package conf
 
 import (
+	"encoding/base64"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Conf struct {
 	GoogleConf
 	CorsConf
+	WhatsAppConf
+	SlackConf
+	TelegramConf
+	EmailConf
+	GuardrailConf
+	PIIConf
+	AnswerCacheConf
+	AnalyticsConf
+	UsageConf
+	HandoffTriggers    []string
+	FAQMatchThreshold  float64
 	RedirectAfterLogin string
 	DatabaseURL        string
 	Port               string
@@ -23,13 +37,114 @@ type CorsConf struct {
 	AllowOrigins string
 }
 
-func Load() Conf {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3000"
-	}
-	port = ":" + port
+// WhatsAppConf configures the WhatsApp Cloud API channel, it is disabled when WhatsAppToken is empty
+type WhatsAppConf struct {
+	WhatsAppAPIURL        string
+	WhatsAppToken         string
+	WhatsAppPhoneNumberID string
+	WhatsAppVerifyToken   string
+	WhatsAppAppSecret     string
+}
+
+func (c WhatsAppConf) WhatsAppEnabled() bool {
+	return c.WhatsAppToken != ""
+}
+
+// SlackConf configures the Slack app channel, it is disabled when SlackBotToken is empty
+type SlackConf struct {
+	SlackAPIURL        string
+	SlackBotToken      string
+	SlackSigningSecret string
+}
+
+func (c SlackConf) SlackEnabled() bool {
+	return c.SlackBotToken != ""
+}
+
+// TelegramConf configures the Telegram bot channel, it is disabled when TelegramBotToken is empty.
+// TelegramMode is "webhook" (default) or "polling" for running locally without a public URL.
+type TelegramConf struct {
+	TelegramAPIURL      string
+	TelegramBotToken    string
+	TelegramMode        string
+	TelegramSecretToken string
+}
+
+func (c TelegramConf) TelegramEnabled() bool {
+	return c.TelegramBotToken != ""
+}
+
+func (c TelegramConf) TelegramPolling() bool {
+	return c.TelegramMode == "polling"
+}
+
+// EmailConf configures the email channel, it is disabled when EmailFrom is empty.
+// Questions arrive through the webhook when EmailWebhookToken is set and through
+// IMAP polling when IMAPAddr is set. Leave SMTPUsername empty for a local SMTP sink.
+type EmailConf struct {
+	EmailFrom         string
+	EmailWebhookToken string
+	SMTPHost          string
+	SMTPPort          string
+	SMTPUsername      string
+	SMTPPassword      string
+	IMAPAddr          string
+	IMAPUsername      string
+	IMAPPassword      string
+	IMAPFolder        string
+	IMAPPollInterval  time.Duration
+}
 
+func (c EmailConf) EmailEnabled() bool {
+	return c.EmailFrom != ""
+}
+
+// GuardrailConf configures the chat guardrail rules. A zero GuardrailMaxInputLength disables the
+// length check, GuardrailBlockedTopics and GuardrailDenyPatterns are checked on questions and answers.
+type GuardrailConf struct {
+	GuardrailMaxInputLength int
+	GuardrailBlockedTopics  []string
+	GuardrailDenyPatterns   []string
+}
+
+// PIIConf configures the personal data redaction. Empty PIIDetectors enables every built-in
+// detector, PIICustomPatterns are NAME=regex entries. Without PIIVaultKey originals are discarded.
+type PIIConf struct {
+	PIIDetectors      []string
+	PIICustomPatterns []string
+	PIIVaultKey       []byte
+}
+
+// AnswerCacheConf configures the answer cache, a zero AnswerCacheTTL disables it. Cached answers
+// are dropped when an ingestion job completes, checked every AnswerCacheIngestionPoll.
+type AnswerCacheConf struct {
+	AnswerCacheTTL           time.Duration
+	AnswerCacheThreshold     float64
+	AnswerCacheIngestionPoll time.Duration
+}
+
+// AnalyticsConf configures the analytics reports. Demographic groups with fewer chat users than
+// AnalyticsMinGroupSize are suppressed, AnalyticsAgeBuckets are the default age bucket boundaries.
+// AnalyticsTimeZone is the zone reports are bucketed in when a request does not give one.
+// The rollups the reports read are brought up to date every AnalyticsRollupInterval.
+type AnalyticsConf struct {
+	AnalyticsMinGroupSize   int
+	AnalyticsAgeBuckets     []int
+	AnalyticsTimeZone       *time.Location
+	AnalyticsRollupInterval time.Duration
+}
+
+// UsageConf configures the model spend budget. A zero UsageMonthlyBudget, in USD, disables the alerts.
+// An alert is raised once a month for each of UsageBudgetAlertPercents of the budget the spend crosses,
+// and emailed to UsageBudgetAlertEmails when the email channel is enabled.
+type UsageConf struct {
+	UsageMonthlyBudget       float64
+	UsageBudgetAlertPercents []int
+	UsageBudgetAlertEmails   []string
+}
+
+// LoadDatabaseURL reads DATABASE_URL, for commands that only need the database
+func LoadDatabaseURL() string {
 	uri := os.Getenv("DATABASE_URL")
 	if uri == "" {
 		panic("DATABASE_URL is not set")
@@ -37,6 +152,57 @@ func Load() Conf {
 	if !strings.Contains(uri, "sslmode") {
 		uri += "?sslmode=disable"
 	}
+	return uri
+}
+
+// LoadTimeZone reads ANALYTICS_TIME_ZONE, the zone of reports and budget months, nil when it is not set
+func LoadTimeZone() *time.Location {
+	zone := os.Getenv("ANALYTICS_TIME_ZONE")
+	if zone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		panic("ANALYTICS_TIME_ZONE must be an IANA time zone, like America/Lima")
+	}
+	return loc
+}
+
+// LoadUsageConf reads the spend budget, zero values use the usage defaults
+func LoadUsageConf() UsageConf {
+	var usageConf UsageConf
+	if budget := os.Getenv("USAGE_MONTHLY_BUDGET"); budget != "" {
+		b, err := strconv.ParseFloat(budget, 64)
+		if err != nil || b < 0 {
+			panic("USAGE_MONTHLY_BUDGET must be a positive number")
+		}
+		usageConf.UsageMonthlyBudget = b
+	}
+	if percents := os.Getenv("USAGE_BUDGET_ALERT_PERCENTS"); percents != "" {
+		for _, p := range strings.Split(percents, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil || n < 1 {
+				panic("USAGE_BUDGET_ALERT_PERCENTS must be comma separated percentages, like 50,80,100")
+			}
+			usageConf.UsageBudgetAlertPercents = append(usageConf.UsageBudgetAlertPercents, n)
+		}
+	}
+	if emails := os.Getenv("USAGE_BUDGET_ALERT_EMAILS"); emails != "" {
+		for _, e := range strings.Split(emails, ",") {
+			usageConf.UsageBudgetAlertEmails = append(usageConf.UsageBudgetAlertEmails, strings.TrimSpace(e))
+		}
+	}
+	return usageConf
+}
+
+func Load() Conf {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "3000"
+	}
+	port = ":" + port
+
+	uri := LoadDatabaseURL()
 
 	googleClientID := os.Getenv("GOOGLE_CLIENT_ID")
 	if googleClientID == "" {
@@ -60,6 +226,194 @@ func Load() Conf {
 		allowOrigins = "http://localhost:3001, http://localhost:3000"
 	}
 
+	whatsAppConf := WhatsAppConf{
+		WhatsAppAPIURL:        os.Getenv("WHATSAPP_API_URL"),
+		WhatsAppToken:         os.Getenv("WHATSAPP_TOKEN"),
+		WhatsAppPhoneNumberID: os.Getenv("WHATSAPP_PHONE_NUMBER_ID"),
+		WhatsAppVerifyToken:   os.Getenv("WHATSAPP_VERIFY_TOKEN"),
+		WhatsAppAppSecret:     os.Getenv("WHATSAPP_APP_SECRET"),
+	}
+	if whatsAppConf.WhatsAppAPIURL == "" {
+		whatsAppConf.WhatsAppAPIURL = "https://graph.facebook.com/v21.0"
+	}
+	if whatsAppConf.WhatsAppEnabled() {
+		if whatsAppConf.WhatsAppPhoneNumberID == "" {
+			panic("WHATSAPP_PHONE_NUMBER_ID is not set")
+		}
+		if whatsAppConf.WhatsAppVerifyToken == "" {
+			panic("WHATSAPP_VERIFY_TOKEN is not set")
+		}
+		if whatsAppConf.WhatsAppAppSecret == "" {
+			panic("WHATSAPP_APP_SECRET is not set")
+		}
+	}
+
+	slackConf := SlackConf{
+		SlackAPIURL:        os.Getenv("SLACK_API_URL"),
+		SlackBotToken:      os.Getenv("SLACK_BOT_TOKEN"),
+		SlackSigningSecret: os.Getenv("SLACK_SIGNING_SECRET"),
+	}
+	if slackConf.SlackAPIURL == "" {
+		slackConf.SlackAPIURL = "https://slack.com/api"
+	}
+	if slackConf.SlackEnabled() && slackConf.SlackSigningSecret == "" {
+		panic("SLACK_SIGNING_SECRET is not set")
+	}
+
+	telegramConf := TelegramConf{
+		TelegramAPIURL:      os.Getenv("TELEGRAM_API_URL"),
+		TelegramBotToken:    os.Getenv("TELEGRAM_BOT_TOKEN"),
+		TelegramMode:        os.Getenv("TELEGRAM_MODE"),
+		TelegramSecretToken: os.Getenv("TELEGRAM_SECRET_TOKEN"),
+	}
+	if telegramConf.TelegramAPIURL == "" {
+		telegramConf.TelegramAPIURL = "https://api.telegram.org"
+	}
+	if telegramConf.TelegramMode == "" {
+		telegramConf.TelegramMode = "webhook"
+	}
+	if telegramConf.TelegramMode != "webhook" && telegramConf.TelegramMode != "polling" {
+		panic("TELEGRAM_MODE must be webhook or polling")
+	}
+	if telegramConf.TelegramEnabled() && !telegramConf.TelegramPolling() && telegramConf.TelegramSecretToken == "" {
+		panic("TELEGRAM_SECRET_TOKEN is not set")
+	}
+
+	emailConf := EmailConf{
+		EmailFrom:         os.Getenv("EMAIL_FROM"),
+		EmailWebhookToken: os.Getenv("EMAIL_WEBHOOK_TOKEN"),
+		SMTPHost:          os.Getenv("SMTP_HOST"),
+		SMTPPort:          os.Getenv("SMTP_PORT"),
+		SMTPUsername:      os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:      os.Getenv("SMTP_PASSWORD"),
+		IMAPAddr:          os.Getenv("IMAP_ADDR"),
+		IMAPUsername:      os.Getenv("IMAP_USERNAME"),
+		IMAPPassword:      os.Getenv("IMAP_PASSWORD"),
+		IMAPFolder:        os.Getenv("IMAP_FOLDER"),
+		IMAPPollInterval:  time.Minute,
+	}
+	if emailConf.SMTPPort == "" {
+		emailConf.SMTPPort = "587"
+	}
+	if interval := os.Getenv("IMAP_POLL_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			panic("IMAP_POLL_INTERVAL is not a valid duration")
+		}
+		emailConf.IMAPPollInterval = d
+	}
+	if emailConf.EmailEnabled() && emailConf.SMTPHost == "" {
+		panic("SMTP_HOST is not set")
+	}
+
+	// Comma separated phrases that escalate to a human, empty uses the defaults
+	var handoffTriggers []string
+	if triggers := os.Getenv("HANDOFF_TRIGGERS"); triggers != "" {
+		handoffTriggers = strings.Split(triggers, ",")
+	}
+
+	// Similarity from 0 to 1 a message needs to get a curated FAQ answer, 0 uses the default
+	var faqMatchThreshold float64
+	if threshold := os.Getenv("FAQ_MATCH_THRESHOLD"); threshold != "" {
+		t, err := strconv.ParseFloat(threshold, 64)
+		if err != nil || t <= 0 || t > 1 {
+			panic("FAQ_MATCH_THRESHOLD must be a number between 0 and 1")
+		}
+		faqMatchThreshold = t
+	}
+
+	// Comma separated topics and newline separated regular expressions, since patterns may contain commas
+	guardrailConf := GuardrailConf{
+		GuardrailMaxInputLength: 2000,
+	}
+	if maxLength := os.Getenv("GUARDRAIL_MAX_INPUT_LENGTH"); maxLength != "" {
+		n, err := strconv.Atoi(maxLength)
+		if err != nil || n < 0 {
+			panic("GUARDRAIL_MAX_INPUT_LENGTH must be a positive number")
+		}
+		guardrailConf.GuardrailMaxInputLength = n
+	}
+	if topics := os.Getenv("GUARDRAIL_BLOCKED_TOPICS"); topics != "" {
+		guardrailConf.GuardrailBlockedTopics = strings.Split(topics, ",")
+	}
+	if patterns := os.Getenv("GUARDRAIL_DENY_PATTERNS"); patterns != "" {
+		guardrailConf.GuardrailDenyPatterns = strings.Split(patterns, "\n")
+	}
+
+	// Comma separated detector names, newline separated custom patterns and a base64 AES-256 key
+	var piiConf PIIConf
+	if detectors := os.Getenv("PII_DETECTORS"); detectors != "" {
+		piiConf.PIIDetectors = strings.Split(detectors, ",")
+	}
+	if patterns := os.Getenv("PII_CUSTOM_PATTERNS"); patterns != "" {
+		piiConf.PIICustomPatterns = strings.Split(patterns, "\n")
+	}
+	if key := os.Getenv("PII_VAULT_KEY"); key != "" {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(decoded) != 32 {
+			panic("PII_VAULT_KEY must be 32 bytes encoded in base64")
+		}
+		piiConf.PIIVaultKey = decoded
+	}
+
+	answerCacheConf := AnswerCacheConf{
+		AnswerCacheTTL:           24 * time.Hour,
+		AnswerCacheIngestionPoll: time.Minute,
+	}
+	if ttl := os.Getenv("ANSWER_CACHE_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d < 0 {
+			panic("ANSWER_CACHE_TTL is not a valid duration")
+		}
+		answerCacheConf.AnswerCacheTTL = d
+	}
+	// Similarity from 0 to 1 a question needs to reuse a cached answer, 0 uses the default
+	if threshold := os.Getenv("ANSWER_CACHE_THRESHOLD"); threshold != "" {
+		t, err := strconv.ParseFloat(threshold, 64)
+		if err != nil || t <= 0 || t > 1 {
+			panic("ANSWER_CACHE_THRESHOLD must be a number between 0 and 1")
+		}
+		answerCacheConf.AnswerCacheThreshold = t
+	}
+	if interval := os.Getenv("ANSWER_CACHE_INGESTION_POLL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			panic("ANSWER_CACHE_INGESTION_POLL is not a valid duration")
+		}
+		answerCacheConf.AnswerCacheIngestionPoll = d
+	}
+
+	// Zero values use the analytics defaults
+	analyticsConf := AnalyticsConf{
+		AnalyticsRollupInterval: 5 * time.Minute,
+	}
+	if size := os.Getenv("ANALYTICS_MIN_GROUP_SIZE"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 1 {
+			panic("ANALYTICS_MIN_GROUP_SIZE must be a positive number")
+		}
+		analyticsConf.AnalyticsMinGroupSize = n
+	}
+	if buckets := os.Getenv("ANALYTICS_AGE_BUCKETS"); buckets != "" {
+		for _, b := range strings.Split(buckets, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(b))
+			if err != nil || n < 1 {
+				panic("ANALYTICS_AGE_BUCKETS must be comma separated ages, like 18,25,35")
+			}
+			analyticsConf.AnalyticsAgeBuckets = append(analyticsConf.AnalyticsAgeBuckets, n)
+		}
+	}
+	analyticsConf.AnalyticsTimeZone = LoadTimeZone()
+	if interval := os.Getenv("ANALYTICS_ROLLUP_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			panic("ANALYTICS_ROLLUP_INTERVAL is not a valid duration")
+		}
+		analyticsConf.AnalyticsRollupInterval = d
+	}
+
+	usageConf := LoadUsageConf()
+
 	return Conf{
 		GoogleConf: GoogleConf{
 			GoogleClientID:     googleClientID,
@@ -69,6 +423,17 @@ func Load() Conf {
 		CorsConf: CorsConf{
 			AllowOrigins: allowOrigins,
 		},
+		WhatsAppConf:       whatsAppConf,
+		SlackConf:          slackConf,
+		TelegramConf:       telegramConf,
+		EmailConf:          emailConf,
+		GuardrailConf:      guardrailConf,
+		PIIConf:            piiConf,
+		AnswerCacheConf:    answerCacheConf,
+		AnalyticsConf:      analyticsConf,
+		UsageConf:          usageConf,
+		HandoffTriggers:    handoffTriggers,
+		FAQMatchThreshold:  faqMatchThreshold,
 		RedirectAfterLogin: redirectAfterLogin,
 		DatabaseURL:        uri,
 		Port:               port,
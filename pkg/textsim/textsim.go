@@ -0,0 +1,118 @@
+// Package textsim compares short texts, like chat questions, without a model call
+package textsim
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Similarity scores how alike two texts are, from 0 to 1, by the overlap of the
+// character trigrams of their normalized text. Trigrams tolerate typos and word order changes.
+func Similarity(a, b string) float64 {
+	ta, tb := trigrams(Normalize(a)), trigrams(Normalize(b))
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ta)+len(tb))
+}
+
+// Normalize lowercases text and reduces it to words separated by single spaces
+func Normalize(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// trigrams returns the set of three rune sequences of every word padded with spaces
+func trigrams(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(text) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// minTypoWordLength is the shortest word a misspelling is tolerated in, short words like
+// "no", "not" or "can" change the meaning of a question with a single letter
+const minTypoWordLength = 4
+
+// SameWords reports whether two texts use the same words, in any order, apart from misspellings
+// of a letter in longer words. Similar texts that add, drop or swap a word, like "can" and
+// "can't" or "before" and "after", do not.
+func SameWords(a, b string) bool {
+	wa, wb := words(a), words(b)
+	return typosOf(wa, wb) && typosOf(wb, wa)
+}
+
+// typosOf reports whether every word of a is in b or misspells one of its words
+func typosOf(a, b map[string]bool) bool {
+	for w := range a {
+		if b[w] {
+			continue
+		}
+		found := false
+		for other := range b {
+			if misspells(w, other) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func words(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(Normalize(text)) {
+		set[w] = true
+	}
+	return set
+}
+
+// misspells reports whether two long words are one edit or transposition of adjacent letters apart
+func misspells(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < minTypoWordLength || len(rb) < minTypoWordLength {
+		return false
+	}
+	return editDistance(ra, rb) <= 1
+}
+
+// editDistance counts the insertions, deletions, substitutions and adjacent transpositions from a to b
+func editDistance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}